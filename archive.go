@@ -0,0 +1,170 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var manPageRegex = regexp.MustCompile(`\.([1-8])(\.gz)?$`)
+
+// archiveFile is a regular file contained in a release archive
+type archiveFile struct {
+	name       string
+	executable bool
+}
+
+// listArchive lists regular files in a zip or tar.gz archive, the name is used to detect the format of the archive.
+// It returns nil if the format is not supported
+func listArchive(name string, data []byte) ([]archiveFile, error) {
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return listZip(data)
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return listTarGz(data)
+	default:
+		return nil, nil
+	}
+}
+
+func listZip(data []byte) ([]archiveFile, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the zip archive")
+	}
+
+	var files []archiveFile
+	for _, f := range r.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+
+		files = append(files, archiveFile{name: f.Name, executable: f.Mode()&0111 != 0})
+	}
+
+	return stripTopDir(files), nil
+}
+
+func listTarGz(data []byte) ([]archiveFile, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the tar.gz archive")
+	}
+
+	defer gr.Close()
+
+	var files []archiveFile
+	tr := tar.NewReader(gr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read the tar.gz archive")
+		}
+
+		if !h.FileInfo().Mode().IsRegular() {
+			continue
+		}
+
+		files = append(files, archiveFile{name: strings.TrimPrefix(h.Name, "./"), executable: h.FileInfo().Mode()&0111 != 0})
+	}
+
+	return stripTopDir(files), nil
+}
+
+// stripTopDir strips a directory if all the files are in it, since Homebrew changes into the directory on install
+func stripTopDir(files []archiveFile) []archiveFile {
+	if len(files) == 0 {
+		return files
+	}
+
+	i := strings.Index(files[0].name, "/")
+	if i < 0 {
+		return files
+	}
+
+	top := files[0].name[:i+1]
+	for _, f := range files {
+		if !strings.HasPrefix(f.name, top) {
+			return files
+		}
+	}
+
+	stripped := make([]archiveFile, len(files))
+	for i, f := range files {
+		stripped[i] = archiveFile{name: strings.TrimPrefix(f.name, top), executable: f.executable}
+	}
+
+	return stripped
+}
+
+// installLines generates lines of the install block to install binaries, shell completions and man pages in the files
+func installLines(app string, files []archiveFile) []string {
+	var bins, completions, mans []string
+
+	sorted := make([]archiveFile, len(files))
+	copy(sorted, files)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+
+	for _, f := range sorted {
+		dir, base := path.Split(f.name)
+
+		switch {
+		case hasDir(dir, "completions", "completion", "autocomplete"):
+			if l := completionLine(app, dir, base, f.name); len(l) != 0 {
+				completions = append(completions, l)
+			}
+		case hasDir(dir, "man", "manpages"):
+			if ms := manPageRegex.FindStringSubmatch(base); ms != nil {
+				mans = append(mans, fmt.Sprintf("man%s.install '%s'", ms[1], f.name))
+			}
+		case f.executable || base == app:
+			bins = append(bins, fmt.Sprintf("bin.install '%s'", f.name))
+		}
+	}
+
+	if len(bins) == 0 {
+		bins = []string{fmt.Sprintf("bin.install '%s'", app)}
+	}
+
+	return append(append(bins, completions...), mans...)
+}
+
+// completionLine returns a line to install a completion file, or an empty string if the shell is unknown
+func completionLine(app, dir, base, name string) string {
+	switch {
+	case strings.HasSuffix(base, ".bash") || hasDir(dir, "bash"):
+		return fmt.Sprintf("bash_completion.install '%s' => '%s'", name, app)
+	case strings.HasSuffix(base, ".zsh") || strings.HasPrefix(base, "_") || hasDir(dir, "zsh"):
+		return fmt.Sprintf("zsh_completion.install '%s' => '_%s'", name, app)
+	case strings.HasSuffix(base, ".fish") || hasDir(dir, "fish"):
+		return fmt.Sprintf("fish_completion.install '%s' => '%s.fish'", name, app)
+	default:
+		return ""
+	}
+}
+
+// hasDir reports whether the dir contains one of the names as its component
+func hasDir(dir string, names ...string) bool {
+	for _, c := range strings.Split(strings.Trim(dir, "/"), "/") {
+		for _, n := range names {
+			if c == n {
+				return true
+			}
+		}
+	}
+
+	return false
+}
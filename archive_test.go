@@ -0,0 +1,104 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+)
+
+func TestListArchive_Zip(t *testing.T) {
+	data := testZip(t, map[string]bool{
+		"testApp_v0.0.1_darwin_amd64/testApp":   true,
+		"testApp_v0.0.1_darwin_amd64/README.md": false,
+	})
+
+	got, err := listArchive("testApp_v0.0.1_darwin_amd64.zip", data)
+	if err != nil {
+		t.Fatalf("#listArchive returns unexpected error: %s", err)
+	}
+
+	want := []archiveFile{{name: "README.md"}, {name: "testApp", executable: true}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("#listArchive returned %+v, want %+v", got, want)
+	}
+}
+
+func TestListArchive_TarGz(t *testing.T) {
+	var b bytes.Buffer
+	gw := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gw)
+
+	for _, h := range []*tar.Header{
+		{Name: "./bin/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "./bin/testApp", Typeflag: tar.TypeReg, Mode: 0755},
+		{Name: "./completions/_testApp", Typeflag: tar.TypeReg, Mode: 0644},
+	} {
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatalf("Error creating a tar.gz archive: %v", err)
+		}
+	}
+
+	tw.Close()
+	gw.Close()
+
+	got, err := listArchive("testApp_v0.0.1_darwin_amd64.tar.gz", b.Bytes())
+	if err != nil {
+		t.Fatalf("#listArchive returns unexpected error: %s", err)
+	}
+
+	want := []archiveFile{{name: "bin/testApp", executable: true}, {name: "completions/_testApp"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("#listArchive returned %+v, want %+v", got, want)
+	}
+}
+
+func TestListArchive_Unsupported(t *testing.T) {
+	got, err := listArchive("testApp_v0.0.1_darwin_amd64", []byte("test"))
+	if err != nil || got != nil {
+		t.Errorf("#listArchive returned %+v, %v, want nil", got, err)
+	}
+
+	if _, err := listArchive("testApp_v0.0.1_darwin_amd64.zip", []byte("test")); err == nil {
+		t.Errorf("#listArchive did not return error for a broken zip archive")
+	}
+}
+
+func TestInstallLines(t *testing.T) {
+	cases := []struct {
+		files []archiveFile
+		want  []string
+	}{
+		{
+			files: nil,
+			want:  []string{"bin.install 'testApp'"},
+		},
+		{
+			files: []archiveFile{
+				{name: "README.md"},
+				{name: "testApp"},
+				{name: "man/man1/testApp.1.gz"},
+				{name: "completions/testApp.fish"},
+				{name: "completions/testApp.zsh"},
+				{name: "completions/bash/testApp"},
+				{name: "completions/README.md"},
+				{name: "helper", executable: true},
+			},
+			want: []string{
+				"bin.install 'helper'",
+				"bin.install 'testApp'",
+				"bash_completion.install 'completions/bash/testApp' => 'testApp'",
+				"fish_completion.install 'completions/testApp.fish' => 'testApp.fish'",
+				"zsh_completion.install 'completions/testApp.zsh' => '_testApp'",
+				"man1.install 'man/man1/testApp.1.gz'",
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		if got := installLines("testApp", tc.files); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("#%d #installLines returned %+v, want %+v", i, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/google/go-github/github"
+	"github.com/iancoleman/strcase"
+)
+
+var pkgRegex = regexp.MustCompile(`pkg\s['"]([^'"]+\.pkg)['"]`)
+
+// caskTemplate is a template of a cask file created by ghbr
+var caskTemplate = template.Must(template.New("cask").Parse(`cask "{{.Token}}" do
+  version "{{.Version}}"
+  sha256 "{{.Hash}}"
+
+  url "{{.URL}}"
+  name "{{.Name}}"
+{{- if .Desc}}
+  desc "{{.Desc}}"
+{{- end}}
+  homepage "{{.Homepage}}"
+
+  {{.Artifact}}
+end
+`))
+
+// cask contains values to render a cask file
+type cask struct {
+	Token, Version, Hash, URL, Name, Desc, Homepage, Artifact string
+}
+
+// render renders the cask with the custom template at the path, or caskTemplate if the path is empty
+func (c *cask) render(path string) (string, error) {
+	t, err := parseTemplate(caskTemplate, path)
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+
+	if err := t.Execute(&b, c); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// caskToken returns a cask token of the application, which is a lower-cased and hyphenated name
+func caskToken(app string) string {
+	return strcase.ToKebab(app)
+}
+
+// caskPath returns a path of the cask file of the application
+func caskPath(app string) string {
+	return fmt.Sprintf("Casks/%s.rb", caskToken(app))
+}
+
+// caskArtifact returns an artifact stanza to install the app downloaded from the url
+func caskArtifact(app, url string) string {
+	if strings.HasSuffix(url, ".pkg") {
+		return fmt.Sprintf(`pkg "%s"`, path.Base(url))
+	}
+
+	return fmt.Sprintf(`app "%s.app"`, app)
+}
+
+// findCaskAssetURL returns a URL of a released macOS app, looking for .dmg, .pkg and zipped .app in this order
+func findCaskAssetURL(release *github.RepositoryRelease) (string, error) {
+	matchers := []func(name string) bool{
+		func(name string) bool { return strings.HasSuffix(name, ".dmg") },
+		func(name string) bool { return strings.HasSuffix(name, ".pkg") },
+		func(name string) bool {
+			if !strings.HasSuffix(name, ".zip") {
+				return false
+			}
+
+			for _, s := range []string{"darwin", "mac", "osx", ".app"} {
+				if strings.Contains(name, s) {
+					return true
+				}
+			}
+
+			return false
+		},
+	}
+
+	for _, match := range matchers {
+		for _, a := range release.Assets {
+			if match(strings.ToLower(*a.Name)) {
+				return *a.BrowserDownloadURL, nil
+			}
+		}
+	}
+
+	return "", &HandledError{
+		Message: `No released asset which is a .dmg, .pkg or zipped .app file.\n` +
+			`You need to upload one of them, or name a zip file with "darwin", "mac", "osx" or ".app" to specify the asset is a macOS app.`,
+	}
+}
+
+// bumpsUpCask updates version, url and sha256 of the cask, and pkg if the cask installs a .pkg file
+func bumpsUpCask(content string, release *LatestRelease) (string, error) {
+	c, err := bumpsUpFormula(content, release)
+	if err != nil {
+		return "", err
+	}
+
+	if pkgRegex.MatchString(c) && strings.HasSuffix(release.url, ".pkg") {
+		return findAndReplace(pkgRegex, c, path.Base(release.url))
+	}
+
+	return c, nil
+}
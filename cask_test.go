@@ -0,0 +1,116 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func TestCask_Render(t *testing.T) {
+	c := cask{
+		Token:    "test-app",
+		Version:  "0.0.1",
+		Hash:     "0001123456789012345678901234567890123456789012345678901234567890",
+		URL:      "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp.dmg",
+		Name:     "testApp",
+		Desc:     "This is a test",
+		Homepage: "https://github.com/shuheiktgw/testApp",
+		Artifact: `app "testApp.app"`,
+	}
+
+	got, err := c.render("")
+	if err != nil {
+		t.Fatalf("#render returns unexpected error: %s", err)
+	}
+
+	want := `cask "test-app" do
+  version "0.0.1"
+  sha256 "0001123456789012345678901234567890123456789012345678901234567890"
+
+  url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp.dmg"
+  name "testApp"
+  desc "This is a test"
+  homepage "https://github.com/shuheiktgw/testApp"
+
+  app "testApp.app"
+end
+`
+
+	if got != want {
+		t.Errorf("#render returned %s, want %s", got, want)
+	}
+}
+
+func TestCaskPath(t *testing.T) {
+	if got, want := caskPath("testApp"), "Casks/test-app.rb"; got != want {
+		t.Errorf("#caskPath returned %s, want %s", got, want)
+	}
+}
+
+func TestFindCaskAssetURL(t *testing.T) {
+	asset := func(name string) github.ReleaseAsset {
+		return github.ReleaseAsset{Name: github.String(name), BrowserDownloadURL: github.String("https://example.com/" + name)}
+	}
+
+	cases := []struct {
+		assets []github.ReleaseAsset
+		want   string
+	}{
+		{assets: []github.ReleaseAsset{asset("testApp.pkg"), asset("testApp.dmg")}, want: "https://example.com/testApp.dmg"},
+		{assets: []github.ReleaseAsset{asset("testApp_linux.zip"), asset("testApp.pkg")}, want: "https://example.com/testApp.pkg"},
+		{assets: []github.ReleaseAsset{asset("testApp_linux.zip"), asset("testApp_macOS.zip")}, want: "https://example.com/testApp_macOS.zip"},
+	}
+
+	for i, tc := range cases {
+		got, err := findCaskAssetURL(&github.RepositoryRelease{Assets: tc.assets})
+		if err != nil {
+			t.Fatalf("#%d #findCaskAssetURL returns unexpected error: %s", i, err)
+		}
+
+		if got != tc.want {
+			t.Errorf("#%d #findCaskAssetURL returned %s, want %s", i, got, tc.want)
+		}
+	}
+
+	_, err := findCaskAssetURL(&github.RepositoryRelease{Assets: []github.ReleaseAsset{asset("testApp_linux.zip")}})
+	if _, ok := err.(*HandledError); !ok {
+		t.Errorf("#findCaskAssetURL returns invalid error: %s", err)
+	}
+}
+
+func TestBumpsUpCask(t *testing.T) {
+	content := `cask "test-app" do
+  version "0.0.1"
+  sha256 "0001123456789012345678901234567890123456789012345678901234567890"
+
+  url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp-0.0.1.pkg"
+
+  pkg "testApp-0.0.1.pkg"
+end
+`
+
+	release := &LatestRelease{
+		version: "0.0.2",
+		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp-0.0.2.pkg",
+		hash:    "0002123456789012345678901234567890123456789012345678901234567890",
+	}
+
+	got, err := bumpsUpCask(content, release)
+	if err != nil {
+		t.Fatalf("#bumpsUpCask returns unexpected error: %s", err)
+	}
+
+	want := `cask "test-app" do
+  version "0.0.2"
+  sha256 "0002123456789012345678901234567890123456789012345678901234567890"
+
+  url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp-0.0.2.pkg"
+
+  pkg "testApp-0.0.2.pkg"
+end
+`
+
+	if got != want {
+		t.Errorf("#bumpsUpCask returned %s, want %s", got, want)
+	}
+}
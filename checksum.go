@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+)
+
+// Statuses of a checksum verified by Verify
+const (
+	ChecksumOK       = "ok"
+	ChecksumMismatch = "mismatch"
+	ChecksumFailed   = "failed"
+)
+
+// checksum is a pair of url and sha256 in a formula, which is either top level, per platform or of a resource
+type checksum struct {
+	url, sha256 string
+
+	// start and end are offsets of the sha256 value in the formula
+	start, end int
+}
+
+// findChecksums returns pairs of url and the first sha256 following it before the next url.
+// Urls without sha256 like the one of a head are omitted
+func findChecksums(content string) []checksum {
+	urls := urlRegex.FindAllStringSubmatchIndex(content, -1)
+
+	var checksums []checksum
+	for i, u := range urls {
+		end := len(content)
+		if i+1 < len(urls) {
+			end = urls[i+1][0]
+		}
+
+		loc := shaRegex.FindStringSubmatchIndex(content[u[1]:end])
+		if loc == nil {
+			continue
+		}
+
+		checksums = append(checksums, checksum{
+			url:    content[u[2]:u[3]],
+			sha256: content[u[1]+loc[2] : u[1]+loc[3]],
+			start:  u[1] + loc[2],
+			end:    u[1] + loc[3],
+		})
+	}
+
+	return checksums
+}
+
+// verifyResult is a result of verifying a checksum
+type verifyResult struct {
+	checksum
+	actual, status string
+	err            error
+}
+
+// Verify downloads every url the formula references and compares its checksum with sha256 in the formula.
+// If fix is true, it opens a Pull Request to fix mismatched checksums, otherwise it returns a HandledError if any of them does not match
+func (g *Ghbr) Verify(ctx context.Context, tap Tap, owner, app, branch string, fix, merge bool) error {
+	formulaOwner, repo := tap.owner(owner), tap.name(app)
+
+	path := tap.Path
+	if len(path) == 0 {
+		var err error
+		if path, err = g.formulaPath(ctx, formulaOwner, repo, branch, app); err != nil {
+			return err
+		}
+	}
+
+	// Get the formula file
+	g.report().Step("check_current", "Checking the current formula")
+	rc, err := g.tapForge().GetFile(ctx, formulaOwner, repo, branch, path)
+	if err != nil {
+		return err
+	}
+
+	content, err := decodeContent(rc)
+	if err != nil {
+		return err
+	}
+
+	var results []verifyResult
+	for _, c := range findChecksums(content) {
+		g.report().Step("verify_checksum", "Verifying %s", c.url)
+		results = append(results, g.verifyChecksum(ctx, c))
+	}
+
+	g.report().Message("\n\n")
+
+	tw := tabwriter.NewWriter(g.outStream, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tURL\tDETAIL")
+
+	var mismatched, failed []verifyResult
+	for _, r := range results {
+		var detail string
+		switch r.status {
+		case ChecksumMismatch:
+			detail = fmt.Sprintf("formula has %s, but the actual one is %s", r.sha256, r.actual)
+			mismatched = append(mismatched, r)
+		case ChecksumFailed:
+			detail = r.err.Error()
+			failed = append(failed, r)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.status, r.url, orDash(detail))
+	}
+
+	tw.Flush()
+	fmt.Fprintf(g.outStream, "\nverified: %d, mismatched: %d, failed: %d\n\n", len(results), len(mismatched), len(failed))
+
+	if len(failed) != 0 {
+		return &HandledError{Message: fmt.Sprintf("failed to verify %d of %d checksums", len(failed), len(results))}
+	}
+
+	if len(mismatched) == 0 {
+		return nil
+	}
+
+	if !fix {
+		return &HandledError{Message: fmt.Sprintf("%d of %d checksums do not match, run `ghbr verify` with `--fix` option to fix them", len(mismatched), len(results))}
+	}
+
+	// Replace mismatched checksums from the last one so that offsets of the rest are kept
+	fixed := content
+	for i := len(mismatched) - 1; i >= 0; i-- {
+		r := mismatched[i]
+		fixed = fixed[:r.start] + r.actual + fixed[r.end:]
+	}
+
+	version := "unknown"
+	if ms := versionRegex.FindStringSubmatch(content); ms != nil {
+		version = ms[1]
+	}
+
+	message := fmt.Sprintf("Fixes checksums of %s", version)
+	updates := []fileUpdate{{label: "the formula file", path: path, sha: rc.GetSHA(), content: fixed}}
+
+	pr, err := g.pullRequest(ctx, formulaOwner, repo, branch, fmt.Sprintf("fixes_checksums_of_%s", version), message, message, updates, merge)
+	if err != nil {
+		return err
+	}
+
+	g.report().Message("\n\n")
+
+	if merge {
+		g.report().Message("Yay! Now checksums of your formula are fixed!\n\n")
+		return nil
+	}
+
+	g.report().Message("Yay! Now checksums of your formula are ready to fix!\n\n")
+	g.report().Message("Access %s and merge the Pull Request\n\n", pr.GetHTMLURL())
+
+	return nil
+}
+
+// verifyChecksum downloads the url and compares its checksum with the one in the formula
+func (g *Ghbr) verifyChecksum(ctx context.Context, c checksum) verifyResult {
+	body, err := g.downloadFile(ctx, c.url)
+	if err != nil {
+		return verifyResult{checksum: c, status: ChecksumFailed, err: err}
+	}
+
+	defer body.Close()
+
+	actual, err := calculateSha256(body)
+	if err != nil {
+		return verifyResult{checksum: c, status: ChecksumFailed, err: err}
+	}
+
+	if actual != c.sha256 {
+		return verifyResult{checksum: c, actual: actual, status: ChecksumMismatch}
+	}
+
+	return verifyResult{checksum: c, actual: actual, status: ChecksumOK}
+}
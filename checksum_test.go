@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindChecksums(t *testing.T) {
+	content := `class TestApp < Formula
+  version "v0.0.1"
+  url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip"
+  sha256 "0001123456789012345678901234567890123456789012345678901234567890"
+  head "https://github.com/shuheiktgw/testApp.git"
+
+  resource "plugin" do
+    url "https://github.com/shuheiktgw/plugin/releases/download/v1.0.0/plugin.tar.gz"
+    sha256 "0002123456789012345678901234567890123456789012345678901234567890"
+  end
+
+  resource "docs" do
+    url "https://example.com/docs.git", branch: "master"
+  end
+end
+`
+
+	got := findChecksums(content)
+
+	want := []checksum{
+		{url: "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip", sha256: "0001123456789012345678901234567890123456789012345678901234567890"},
+		{url: "https://github.com/shuheiktgw/plugin/releases/download/v1.0.0/plugin.tar.gz", sha256: "0002123456789012345678901234567890123456789012345678901234567890"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("#findChecksums returned %+v, want %+v", got, want)
+	}
+
+	for i, c := range got {
+		if c.url != want[i].url || c.sha256 != want[i].sha256 {
+			t.Errorf("#findChecksums returned %+v, want %+v", c, want[i])
+		}
+
+		if content[c.start:c.end] != c.sha256 {
+			t.Errorf("#findChecksums returned offsets pointing to %q, want %q", content[c.start:c.end], c.sha256)
+		}
+	}
+}
+
+func TestGhbr_Verify(t *testing.T) {
+	// sha256 of "test"
+	hash := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	wrong := "0001123456789012345678901234567890123456789012345678901234567890"
+
+	cases := []struct {
+		fix     bool
+		message string
+	}{
+		{fix: false, message: "1 of 2 checksums do not match, run `ghbr verify` with `--fix` option to fix them"},
+		{fix: true},
+	}
+
+	for i, tc := range cases {
+		client, mux, serverURL, tearDown := setup()
+
+		outStream := new(bytes.Buffer)
+		ghbr := Ghbr{Forge: client, outStream: outStream}
+
+		formula := `version "v0.0.1"
+url "%s/testApp_v0.0.1_darwin_amd64.zip"
+sha256 "%s"
+resource "plugin" do
+  url "%s/plugin.tar.gz"
+  sha256 "%s"
+end
+`
+		content := fmt.Sprintf(formula, serverURL, hash, serverURL, wrong)
+		fixed := fmt.Sprintf(formula, serverURL, hash, serverURL, hash)
+
+		// Mock GetFile and UpdateFile requests
+		var updated string
+		mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/contents/testApp.rb", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPut {
+				var b bytes.Buffer
+				b.ReadFrom(r.Body)
+				updated = b.String()
+				fmt.Fprint(w, `{}`)
+				return
+			}
+
+			fmt.Fprintf(w, `{"path":"testApp.rb","sha":"formulaV0.0.1","encoding":"base64","content":"%s"}`, base64.StdEncoding.EncodeToString([]byte(content)))
+		})
+
+		// Mock downloadFile requests
+		for _, p := range []string{"/testApp_v0.0.1_darwin_amd64.zip", "/plugin.tar.gz"} {
+			mux.HandleFunc(p, func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "test")
+			})
+		}
+
+		// Mock CreateBranch request
+		mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/git/refs/heads/master", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"object":{"sha":"abcdefg"}}`)
+		})
+
+		mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/git/refs", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+			testBody(t, r, `{"ref":"refs/heads/fixes_checksums_of_v0.0.1","sha":"abcdefg"}`+"\n")
+			fmt.Fprint(w, `{"object":{"sha":"abcdefg"}}`)
+		})
+
+		// Mock CreatePullRequest request
+		mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/pulls", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+			testBody(t, r, `{"title":"Fixes checksums of v0.0.1","head":"fixes_checksums_of_v0.0.1","base":"master","body":"Fixes checksums of v0.0.1"}`+"\n")
+			fmt.Fprint(w, `{"number":1, "html_url":"https://github.com/shuheiktgw/homebrew-testApp/pull/1"}`)
+		})
+
+		err := ghbr.Verify(context.Background(), Tap{Path: "testApp.rb"}, TestOwner, "testApp", "master", tc.fix, false)
+
+		if !tc.fix {
+			if e, ok := err.(*HandledError); !ok || e.Message != tc.message {
+				t.Errorf("#%d Verify returned %v, want %q", i, err, tc.message)
+			}
+		} else {
+			if err != nil {
+				t.Fatalf("#%d Verify returned unexpected error: %s", i, err)
+			}
+
+			if want := base64.StdEncoding.EncodeToString([]byte(fixed)); !strings.Contains(updated, want) {
+				t.Errorf("#%d Verify updated the formula with %s, want %s", i, updated, fixed)
+			}
+		}
+
+		got := outStream.String()
+		for _, want := range [][]string{
+			{ChecksumOK, serverURL + "/testApp_v0.0.1_darwin_amd64.zip", "-"},
+			{ChecksumMismatch, serverURL + "/plugin.tar.gz", "formula", "has", wrong + ",", "but", "the", "actual", "one", "is", hash},
+		} {
+			var found bool
+			for _, l := range strings.Split(got, "\n") {
+				if reflect.DeepEqual(strings.Fields(l), want) {
+					found = true
+				}
+			}
+
+			if !found {
+				t.Errorf("#%d Verify outputed %s, want a line of %v", i, got, want)
+			}
+		}
+
+		tearDown()
+	}
+}
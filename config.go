@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/tcnksm/go-gitconfig"
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigFileName is a name of the project configuration file placed at the root of an application
+const ConfigFileName = ".ghbr.yml"
+
+// projectConfig is a project configuration read from ConfigFileName
+type projectConfig struct {
+	// Owner is an owner of the application repository
+	Owner string `yaml:"owner"`
+
+	// Repository is a name of the application repository
+	Repository string `yaml:"repository"`
+
+	// Org is an organization hosting the tap
+	Org string `yaml:"org"`
+
+	// Tap is a tap repository in owner/name or name form
+	Tap string `yaml:"tap"`
+
+	// FormulaPath is a path of the formula file in the tap
+	FormulaPath string `yaml:"formula-path"`
+
+	// Branch is a base branch of the tap
+	Branch string `yaml:"branch"`
+
+	// Asset is a glob pattern matching the name of the released asset
+	Asset string `yaml:"asset"`
+
+	// ReleaseURL is an index or a bucket the application is released to instead of the forge
+	ReleaseURL string `yaml:"release-url"`
+
+	// Merge merges Pull Requests to update the tap, nil means it is not configured
+	Merge *bool `yaml:"merge"`
+
+	// Templates are paths to custom templates of a formula and a cask
+	Templates struct {
+		Formula string `yaml:"formula"`
+		Cask    string `yaml:"cask"`
+	} `yaml:"templates"`
+
+	// Dependencies are runtime dependencies of the formula, nil means they are not managed by ghbr
+	Dependencies []string `yaml:"dependencies"`
+
+	// Resources are resource blocks of the formula managed by ghbr
+	Resources []Resource `yaml:"resources"`
+}
+
+// lookup returns a value of the key, or an empty string if the key is not configured
+func (c *projectConfig) lookup(key string) string {
+	switch key {
+	case "owner":
+		return c.Owner
+	case "repository":
+		return c.Repository
+	case "org":
+		return c.Org
+	case "tap":
+		return c.Tap
+	case "formula-path":
+		return c.FormulaPath
+	case "branch":
+		return c.Branch
+	case "asset":
+		return c.Asset
+	case "release-url":
+		return c.ReleaseURL
+	case "merge":
+		if c.Merge != nil {
+			return strconv.FormatBool(*c.Merge)
+		}
+	case "templates.formula":
+		return c.Templates.Formula
+	case "templates.cask":
+		return c.Templates.Cask
+	}
+
+	return ""
+}
+
+// Resource is an auxiliary download of a formula, which tracks the latest release of another repository
+type Resource struct {
+	// Name is a name of the resource block
+	Name string `yaml:"name"`
+
+	// Repository is a repository releasing the resource, in owner/repo form
+	Repository string `yaml:"repository"`
+
+	// Asset is a glob pattern matching the name of the released asset
+	Asset string `yaml:"asset"`
+}
+
+// validate validates the resource declared in the project configuration
+func (r *Resource) validate() error {
+	if len(r.Name) == 0 {
+		return errors.New("missing name of a resource")
+	}
+
+	if ss := strings.Split(r.Repository, "/"); len(ss) != 2 || len(ss[0]) == 0 || len(ss[1]) == 0 {
+		return errors.Errorf("invalid repository of resource %s: %q, it must be in owner/repo form", r.Name, r.Repository)
+	}
+
+	if _, err := path.Match(r.Asset, ""); err != nil || len(r.Asset) == 0 {
+		return errors.Errorf("invalid asset pattern of resource %s: %q", r.Name, r.Asset)
+	}
+
+	return nil
+}
+
+// loadProjectConfig loads the project configuration from the path,
+// it returns an empty configuration if the file does not exist
+func loadProjectConfig(path string) (*projectConfig, error) {
+	b, err := ioutil.ReadFile(path)
+
+	if os.IsNotExist(err) {
+		return &projectConfig{}, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+
+	var c projectConfig
+	if err := yaml.UnmarshalStrict(b, &c); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", path)
+	}
+
+	for _, r := range c.Resources {
+		if err := r.validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", path)
+		}
+	}
+
+	return &c, nil
+}
+
+// Sources of a resolved setting other than a flag and an environment variable
+const (
+	SourceGitConfig   = ".git/config"
+	SourceGitDefaults = "git defaults"
+	SourceDefault     = "default"
+)
+
+// setting is an option resolved from, in order of precedence, a flag, an environment variable,
+// ConfigFileName, `ghbr` section of .git/config and defaults derived from git
+type setting struct {
+	// key is a key in ConfigFileName, which is also looked up as ghbr.<key> in .git/config
+	key string
+
+	// flag and env are names of the flag and the environment variable, they are empty if the setting cannot be set via them
+	flag, env string
+
+	// gitDefault returns a default value derived from git, it can be nil
+	gitDefault func() string
+
+	// def is a default value used if the setting is set nowhere
+	def string
+
+	// secret is masked when it is shown
+	secret bool
+}
+
+// settings are options shared by commands. Token is never read from ConfigFileName since the file is usually committed
+var settings = []setting{
+	{key: "token", flag: "token", env: EnvGitHubToken, gitDefault: func() string { t, _ := gitconfig.GithubToken(); return t }, secret: true},
+	{key: "owner", flag: "owner", env: "GHBR_OWNER", gitDefault: defaultOwner},
+	{key: "repository", flag: "repository", env: "GHBR_REPOSITORY", gitDefault: defaultRepo},
+	{key: "org", flag: "org", env: "GHBR_ORG"},
+	{key: "tap", flag: "tap", env: "GHBR_TAP"},
+	{key: "formula-path", flag: "formula-path", env: "GHBR_FORMULA_PATH"},
+	{key: "branch", flag: "branch", env: "GHBR_BRANCH", def: "master"},
+	{key: "asset", flag: "asset", env: "GHBR_ASSET"},
+	{key: "release-url", flag: "release-url", env: "GHBR_RELEASE_URL"},
+	{key: "merge", flag: "merge", env: "GHBR_MERGE", def: "false"},
+	{key: "templates.formula", env: "GHBR_TEMPLATES_FORMULA"},
+	{key: "templates.cask", env: "GHBR_TEMPLATES_CASK"},
+}
+
+// resolvedSetting is a value of a setting along with where it came from
+type resolvedSetting struct {
+	setting
+	value, source string
+}
+
+// resolve resolves the setting with flags of the cmd and the project configuration
+func (s setting) resolve(cmd *cobra.Command, c *projectConfig) resolvedSetting {
+	if len(s.flag) != 0 {
+		if f := cmd.Flags().Lookup(s.flag); f != nil && f.Changed {
+			return resolvedSetting{setting: s, value: f.Value.String(), source: "--" + s.flag}
+		}
+	}
+
+	if v := os.Getenv(s.env); len(s.env) != 0 && len(v) != 0 {
+		return resolvedSetting{setting: s, value: v, source: "$" + s.env}
+	}
+
+	if v := c.lookup(s.key); len(v) != 0 {
+		return resolvedSetting{setting: s, value: v, source: ConfigFileName}
+	}
+
+	if v, err := gitconfig.Local("ghbr." + s.key); err == nil && len(v) != 0 {
+		return resolvedSetting{setting: s, value: v, source: SourceGitConfig}
+	}
+
+	if s.gitDefault != nil {
+		if v := s.gitDefault(); len(v) != 0 {
+			return resolvedSetting{setting: s, value: v, source: SourceGitDefaults}
+		}
+	}
+
+	return resolvedSetting{setting: s, value: s.def, source: SourceDefault}
+}
+
+// applySettings resolves the settings and sets them to the flags of the cmd,
+// settings which the cmd does not have a flag for are set to the dests by their keys
+func applySettings(cmd *cobra.Command, c *projectConfig, dests map[string]*string) error {
+	for _, s := range settings {
+		if f := cmd.Flags().Lookup(s.flag); len(s.flag) != 0 && f != nil {
+			r := s.resolve(cmd, c)
+			if f.Changed || r.source == SourceDefault {
+				continue
+			}
+
+			if err := cmd.Flags().Set(s.flag, r.value); err != nil {
+				return errors.Wrapf(err, "invalid %s from %s", s.key, r.source)
+			}
+
+			continue
+		}
+
+		if dest, ok := dests[s.key]; ok {
+			*dest = s.resolve(cmd, c).value
+		}
+	}
+
+	return nil
+}
+
+// NewConfigCmd returns a command to inspect the configuration of ghbr
+func NewConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration of ghbr",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "show",
+		Short: "Print resolved settings and where each came from",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := loadProjectConfig(ConfigFileName)
+			if err != nil {
+				return cmdError{error: err, exitCode: ExitCodeParseFlagsError}
+			}
+
+			showSettings(cmd.OutOrStdout(), cmd, c)
+
+			return nil
+		},
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	})
+
+	return cmd
+}
+
+// showSettings prints resolved settings in a table, secret values are masked
+func showSettings(w io.Writer, cmd *cobra.Command, c *projectConfig) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+
+	for _, s := range settings {
+		r := s.resolve(cmd, c)
+
+		v := r.value
+		if s.secret && len(v) != 0 {
+			v = "********"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.key, orDash(v), r.source)
+	}
+
+	tw.Flush()
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestLoadProjectConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghbr")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	merge := true
+
+	cases := []struct {
+		content string
+		want    *projectConfig
+	}{
+		{content: "", want: &projectConfig{}},
+		{content: "dependencies:\n- git\n- gnupg\n", want: &projectConfig{Dependencies: []string{"git", "gnupg"}}},
+		{content: "dependencies: []\n", want: &projectConfig{Dependencies: []string{}}},
+		{
+			content: "owner: shuheiktgw\norg: ghbr-org\ntap: homebrew-tools\nbranch: main\nasset: '*_darwin_arm64.tar.gz'\nmerge: true\n",
+			want:    &projectConfig{Owner: "shuheiktgw", Org: "ghbr-org", Tap: "homebrew-tools", Branch: "main", Asset: "*_darwin_arm64.tar.gz", Merge: &merge},
+		},
+		{
+			content: "resources:\n- name: plugin\n  repository: shuheiktgw/testPlugin\n  asset: '*.tar.gz'\n",
+			want:    &projectConfig{Resources: []Resource{{Name: "plugin", Repository: "shuheiktgw/testPlugin", Asset: "*.tar.gz"}}},
+		},
+	}
+
+	for i, tc := range cases {
+		path := filepath.Join(dir, ConfigFileName)
+		if err := ioutil.WriteFile(path, []byte(tc.content), 0644); err != nil {
+			t.Fatalf("failed to write a config file: %s", err)
+		}
+
+		got, err := loadProjectConfig(path)
+		if err != nil {
+			t.Fatalf("#%d #loadProjectConfig returns unexpected error: %s", i, err)
+		}
+
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("#%d #loadProjectConfig returned %+v, want %+v", i, got, tc.want)
+		}
+	}
+}
+
+func TestLoadProjectConfig_NotExist(t *testing.T) {
+	got, err := loadProjectConfig(filepath.Join(os.TempDir(), "unknown", ConfigFileName))
+	if err != nil {
+		t.Fatalf("#loadProjectConfig returns unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(got, &projectConfig{}) {
+		t.Errorf("#loadProjectConfig returned %+v, want empty config", got)
+	}
+}
+
+func TestLoadProjectConfig_Invalid(t *testing.T) {
+	f, err := ioutil.TempFile("", ConfigFileName)
+	if err != nil {
+		t.Fatalf("failed to create a temp file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+
+	cases := []string{
+		"unknown: true\n",
+		"resources:\n- name: plugin\n  repository: testPlugin\n  asset: '*.tar.gz'\n",
+		"resources:\n- name: plugin\n  repository: shuheiktgw/\n  asset: '*.tar.gz'\n",
+		"resources:\n- name: plugin\n  repository: /testPlugin\n  asset: '*.tar.gz'\n",
+		"resources:\n- repository: shuheiktgw/testPlugin\n  asset: '*.tar.gz'\n",
+		"resources:\n- name: plugin\n  repository: shuheiktgw/testPlugin\n  asset: '[.tar.gz'\n",
+	}
+
+	for i, c := range cases {
+		if err := ioutil.WriteFile(f.Name(), []byte(c), 0644); err != nil {
+			t.Fatalf("failed to write a config file: %s", err)
+		}
+
+		if _, err := loadProjectConfig(f.Name()); err == nil {
+			t.Errorf("#%d #loadProjectConfig did not return error for %q", i, c)
+		}
+	}
+}
+
+func TestSetting_Resolve(t *testing.T) {
+	s := setting{key: "branch", flag: "branch", env: "GHBR_TEST_BRANCH", def: "master"}
+
+	cases := []struct {
+		args          []string
+		env           string
+		config        *projectConfig
+		value, source string
+	}{
+		{args: []string{"--branch", "flag"}, env: "env", config: &projectConfig{Branch: "config"}, value: "flag", source: "--branch"},
+		{env: "env", config: &projectConfig{Branch: "config"}, value: "env", source: "$GHBR_TEST_BRANCH"},
+		{config: &projectConfig{Branch: "config"}, value: "config", source: ConfigFileName},
+		{config: &projectConfig{}, value: "master", source: SourceDefault},
+	}
+
+	for i, tc := range cases {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("branch", "master", "")
+		if err := cmd.ParseFlags(tc.args); err != nil {
+			t.Fatalf("#%d failed to parse flags: %s", i, err)
+		}
+
+		os.Setenv(s.env, tc.env)
+
+		got := s.resolve(cmd, tc.config)
+		if got.value != tc.value || got.source != tc.source {
+			t.Errorf("#%d #resolve returned %s from %s, want %s from %s", i, got.value, got.source, tc.value, tc.source)
+		}
+	}
+
+	os.Unsetenv(s.env)
+}
+
+func TestApplySettings(t *testing.T) {
+	os.Setenv("GHBR_MERGE", "true")
+	defer os.Unsetenv("GHBR_MERGE")
+
+	cmd := NewReleaseCmd(nil)
+	if err := cmd.ParseFlags([]string{"-b", "develop"}); err != nil {
+		t.Fatalf("failed to parse flags: %s", err)
+	}
+
+	c := &projectConfig{Org: "ghbr-org", Tap: "homebrew-tools", Branch: "main"}
+	c.Templates.Formula = "formula.rb.tmpl"
+
+	var template string
+	if err := applySettings(cmd, c, map[string]*string{"templates.formula": &template}); err != nil {
+		t.Fatalf("#applySettings returns unexpected error: %s", err)
+	}
+
+	if releaseOpts.branch != "develop" || releaseOpts.org != "ghbr-org" || !releaseOpts.merge || releaseOpts.tap != "homebrew-tools" {
+		t.Errorf("#applySettings set branch %s, org %s, merge %t and tap %s, want develop, ghbr-org, true and homebrew-tools", releaseOpts.branch, releaseOpts.org, releaseOpts.merge, releaseOpts.tap)
+	}
+
+	if template != "formula.rb.tmpl" {
+		t.Errorf("#applySettings set template %s, want formula.rb.tmpl", template)
+	}
+}
+
+func TestConfigShow(t *testing.T) {
+	os.Setenv(EnvGitHubToken, "secretToken")
+	defer os.Unsetenv(EnvGitHubToken)
+
+	os.Setenv("GHBR_ORG", "ghbr-org")
+	defer os.Unsetenv("GHBR_ORG")
+
+	cmd := NewConfigCmd()
+
+	buf := new(bytes.Buffer)
+	cmd.SetOutput(buf)
+	cmd.SetArgs([]string{"show"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error occured: %s", err)
+	}
+
+	got := buf.String()
+	if strings.Contains(got, "secretToken") {
+		t.Errorf("#show printed the token: %s", got)
+	}
+
+	for _, want := range [][]string{{"token", "********", "$GITHUB_TOKEN"}, {"org", "ghbr-org", "$GHBR_ORG"}, {"branch", "master", SourceDefault}} {
+		var found bool
+		for _, l := range strings.Split(got, "\n") {
+			if reflect.DeepEqual(strings.Fields(l), want) {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Errorf("#show printed %s, want a line of %v", got, want)
+		}
+	}
+}
@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
+
 	"github.com/spf13/cobra"
 )
 
 type createOptions struct {
-	token, org, owner, repo, font string
-	private                       bool
+	token, org, owner, repo, font  string
+	caveats, tap, formulaPath      string
+	asset, output                  string
+	formulaTemplate, caskTemplate  string
+	private, fromSource, cask      bool
+	noInspect, noLivecheck, noHead bool
+	dependencies                   []string
+	resources                      []Resource
 }
 
 var createOpts createOptions
@@ -17,7 +25,10 @@ func NewCreateCmd(generator GhbrGenerator) *cobra.Command {
 		Aliases: []string{"init"},
 		Short:   "Create a GitHub repository to host a Homebrew formula",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runCreate(generator)
+			ctx, cancel := commandContext(globalOpts.timeout)
+			defer cancel()
+
+			return canceledError(ctx, runCreate(ctx, generator))
 		},
 		SilenceErrors: true,
 		SilenceUsage:  true,
@@ -30,26 +41,88 @@ func NewCreateCmd(generator GhbrGenerator) *cobra.Command {
 
 func setCreatePreRunE(cmd *cobra.Command) {
 	setCreateFlags(cmd)
-	err := validateCreateFlags()
 
+	// Validate flags after they are parsed
 	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
-		if err != nil {
+		if err := loadCreateConfig(cmd); err != nil {
 			return cmdError{error: err, exitCode: ExitCodeParseFlagsError}
 		}
 
-		return err
+		if err := validateCreateFlags(); err != nil {
+			return cmdError{error: err, exitCode: ExitCodeParseFlagsError}
+		}
+
+		return nil
 	}
 }
 
-func runCreate(generator GhbrGenerator) error {
+func runCreate(ctx context.Context, generator GhbrGenerator) error {
+	caveats, err := renderCaveats(createOpts.caveats, createOpts.repo, createOpts.font)
+	if err != nil {
+		return err
+	}
+
 	g := generator(createOpts.token)
+	g.setOutput(createOpts.output)
+	tap := parseTap(createOpts.org, createOpts.tap, createOpts.formulaPath)
+
+	if createOpts.cask {
+		lr, err := g.GetLatestCaskRelease(ctx, createOpts.owner, createOpts.repo, createOpts.asset)
+		if err != nil {
+			return err
+		}
+
+		return g.CreateCask(ctx, tap, createOpts.owner, createOpts.repo, createOpts.private, createOpts.caskTemplate, lr)
+	}
+
+	var lr *LatestRelease
+
+	if createOpts.fromSource {
+		lr, err = g.GetLatestSourceRelease(ctx, createOpts.owner, createOpts.repo)
+	} else {
+		lr, err = g.GetLatestRelease(ctx, createOpts.owner, createOpts.repo, createOpts.asset, !createOpts.noInspect)
+	}
 
-	lr, err := g.GetLatestRelease(createOpts.owner, createOpts.repo)
 	if err != nil {
 		return err
 	}
 
-	return g.CreateFormula(createOpts.org, createOpts.owner, createOpts.repo, createOpts.font, createOpts.private, lr)
+	opts := FormulaOptions{
+		Caveats:   caveats,
+		Livecheck: !createOpts.noLivecheck,
+		Head:      !createOpts.noHead,
+
+		Dependencies: createOpts.dependencies,
+		Resources:    createOpts.resources,
+		Template:     createOpts.formulaTemplate,
+	}
+
+	return g.CreateFormula(ctx, tap, createOpts.owner, createOpts.repo, createOpts.private, opts, lr)
+}
+
+// loadCreateConfig sets options which are not set via flags from environment variables and the project configuration
+func loadCreateConfig(cmd *cobra.Command) error {
+	c, err := loadProjectConfig(ConfigFileName)
+	if err != nil {
+		return err
+	}
+
+	dests := map[string]*string{
+		"templates.formula": &createOpts.formulaTemplate,
+		"templates.cask":    &createOpts.caskTemplate,
+	}
+
+	if err := applySettings(cmd, c, dests); err != nil {
+		return err
+	}
+
+	if !cmd.Flags().Changed("depends-on") {
+		createOpts.dependencies = c.Dependencies
+	}
+
+	createOpts.resources = c.Resources
+
+	return nil
 }
 
 func setCreateFlags(cmd *cobra.Command) {
@@ -59,17 +132,50 @@ func setCreateFlags(cmd *cobra.Command) {
 	// Set org flag
 	cmd.Flags().StringVarP(&createOpts.org, "org", "g", "", "GitHub organization you want to host a formula on")
 
+	// Set tap flag
+	cmd.Flags().StringVar(&createOpts.tap, "tap", "", "Tap repository to add a formula to in owner/name or name form, created if it does not exist (default homebrew-<repository>)")
+
+	// Set formula-path flag
+	setFormulaPathFlag(cmd, &createOpts.formulaPath)
+
 	// Set owner flag
 	setOwnerFlag(cmd, &createOpts.owner)
 
 	// Set repository flag
 	setRepositoryFlag(cmd, &createOpts.repo)
 
+	// Set asset flag
+	cmd.Flags().StringVar(&createOpts.asset, "asset", "", "Glob pattern of the released asset a formula points to, Darwin AMD64 one is used by default")
+
+	// Caveats
+	cmd.Flags().StringVar(&createOpts.caveats, "caveats", "none", "caveats of a formula, one of none, figure, file:<path> or text:<string>")
+
 	// Ascii Font
-	cmd.Flags().StringVarP(&createOpts.font, "font", "f", "isometric3", "caveats Ascii Font from go-figure")
+	cmd.Flags().StringVarP(&createOpts.font, "font", "f", "isometric3", "caveats Ascii Font from go-figure, used with --caveats figure")
 
 	// Repository private setting
 	cmd.Flags().BoolVarP(&createOpts.private, "private", "p", false, "If true, GHBR creates a private repository on GitHub")
+
+	// Build from source setting
+	cmd.Flags().BoolVar(&createOpts.fromSource, "from-source", false, "If true, GHBR creates a formula which builds the source tarball of the release")
+
+	// Inspect setting
+	cmd.Flags().BoolVar(&createOpts.noInspect, "no-inspect", false, "If true, GHBR does not inspect the release archive and only installs the binary named after the repository")
+
+	// Livecheck setting
+	cmd.Flags().BoolVar(&createOpts.noLivecheck, "no-livecheck", false, "If true, GHBR does not add a livecheck block to a formula")
+
+	// Head setting
+	cmd.Flags().BoolVar(&createOpts.noHead, "no-head", false, "If true, GHBR does not add a head stanza pointing to the default branch to a formula")
+
+	// Dependencies setting
+	cmd.Flags().StringSliceVar(&createOpts.dependencies, "depends-on", nil, "Runtime dependency of a formula, can be specified multiple times")
+
+	// Cask setting
+	cmd.Flags().BoolVar(&createOpts.cask, "cask", false, "If true, GHBR creates a cask for a macOS app released as .dmg, .pkg or zipped .app")
+
+	// Output setting
+	cmd.Flags().StringVar(&createOpts.output, "output", OutputText, "Output format, one of text, json for the result in a JSON or jsonl for an event per step in JSON lines")
 }
 
 func validateCreateFlags() error {
@@ -88,5 +194,40 @@ func validateCreateFlags() error {
 		return err
 	}
 
+	// Tap
+	if err := validateTap(createOpts.tap); err != nil {
+		return err
+	}
+
+	// Organization, GitHub Apps cannot create a tap under a user since they do not act as one
+	if err := validateAppOrg(createOpts.org, createOpts.tap); err != nil {
+		return err
+	}
+
+	// Caveats
+	if err := validateCaveats(createOpts.caveats); err != nil {
+		return err
+	}
+
+	// Font
+	if err := validateFont(createOpts.font); err != nil {
+		return err
+	}
+
+	// Release URL
+	if err := validateReleaseURL(globalOpts.releaseURL); err != nil {
+		return err
+	}
+
+	// Cask
+	if err := validateCask(createOpts.cask, createOpts.fromSource); err != nil {
+		return err
+	}
+
+	// Output
+	if err := validateOutput(createOpts.output); err != nil {
+		return err
+	}
+
 	return nil
 }
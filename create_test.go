@@ -25,8 +25,21 @@ func TestCreate(t *testing.T) {
 	})
 
 	// Mock downloadFile request
+	archive := testZip(t, map[string]bool{"testApp": true, "completions/testApp.bash": false, "man/testApp.1": false})
 	mux.HandleFunc(assetPath, func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintf(w, "test")
+		w.Write(archive)
+	})
+
+	// Mock GetRepository request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s", TestOwner, "testApp"), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"name":"testApp","default_branch":"main"}`)
+	})
+
+	// Mock GetFile request to detect the language of the head
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/contents/go.mod", TestOwner, "testApp"), func(w http.ResponseWriter, r *http.Request) {
+		testFormValues(t, r, values{"ref": "main"})
+		fmt.Fprint(w, `{"path":"go.mod","encoding":"base64","content":""}`)
 	})
 
 	// Mock CreateRepository request
@@ -44,6 +57,31 @@ func TestCreate(t *testing.T) {
 	// Mock CreateFile request for formula file
 	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/contents/%s", TestOwner, "homebrew-testApp", "testApp.rb"), func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, http.MethodPut)
+
+		content := testFileContent(t, r)
+		for _, want := range []string{
+			"  head do\n" +
+				"    url 'https://github.com/shuheiktgw/testApp.git', branch: 'main'\n" +
+				"    depends_on 'go' => :build\n" +
+				"  end\n",
+			// The asset is not on GitHub.com but on the test server, so the strategy is left to Homebrew
+			"  livecheck do\n" +
+				"    url :stable\n" +
+				"  end\n",
+			"  def install\n" +
+				"    if build.head?\n" +
+				"      system 'go', 'build', *std_go_args(ldflags: '-s -w')\n" +
+				"    else\n" +
+				"      bin.install 'testApp'\n" +
+				"      bash_completion.install 'completions/testApp.bash' => 'testApp'\n" +
+				"      man1.install 'man/testApp.1'\n" +
+				"    end\n" +
+				"  end\n",
+		} {
+			if !strings.Contains(content, want) {
+				t.Errorf("formula file does not contain %q: %s", want, content)
+			}
+		}
 	})
 
 	err := cmd.Execute()
@@ -54,6 +92,9 @@ func TestCreate(t *testing.T) {
 	expectedOutput := "[ghbr] ===> Checking the latest release\n" +
 		"[ghbr] ===> Downloading Darwin AMD64 release\n" +
 		"[ghbr] ===> Calculating a checksum of the release\n" +
+		"[ghbr] ===> Inspecting the release archive\n" +
+		"[ghbr] ===> Checking the default branch of the repository\n" +
+		fmt.Sprintf("[ghbr] ===> testApp.rb:7: warning: `url` %s is not https\n", assetURL) +
 		"[ghbr] ===> Creating a repository\n" +
 		"[ghbr] ===> Adding README.md to the repository\n" +
 		"[ghbr] ===> Adding testApp.rb to the repository\n" +
@@ -65,3 +106,45 @@ func TestCreate(t *testing.T) {
 		t.Errorf("#create outputed %+v, want %+v", got, expectedOutput)
 	}
 }
+
+func TestCreate_InvalidFlags(t *testing.T) {
+	cases := []string{
+		"ghbr create -t test -o shuheiktgw -r testApp --caveats figure -f unknown",
+		"ghbr create -t test -o shuheiktgw -r testApp --caveats unknown",
+		"ghbr create -t test -o shuheiktgw -r testApp --tap shuheiktgw/homebrew-tools/Formula",
+		"ghbr create -t test -o shuheiktgw -r testApp --tap /homebrew-tools",
+		"ghbr create -t test -o shuheiktgw -r testApp --output yaml",
+		"ghbr create -t test -o shuheiktgw -r testApp --cask --from-source",
+	}
+
+	for _, arg := range cases {
+		generator, _, _, _, tearDown := ghbrMockGenerator()
+
+		cmd := NewCreateCmd(generator)
+		args := strings.Split(arg, " ")
+		cmd.SetArgs(args[1:])
+
+		err := cmd.Execute()
+		if e, ok := err.(cmdError); !ok || e.exitCode != ExitCodeParseFlagsError {
+			t.Errorf("%s returns unexpected error: %v", arg, err)
+		}
+
+		tearDown()
+	}
+}
+
+func TestCreate_GitHubAppWithoutOrg(t *testing.T) {
+	defer func(app *GitHubApp) { globalOpts.app = app }(globalOpts.app)
+	globalOpts.app = &GitHubApp{ID: 42}
+
+	generator, _, _, _, tearDown := ghbrMockGenerator()
+	defer tearDown()
+
+	cmd := NewCreateCmd(generator)
+	cmd.SetArgs(strings.Split("-o shuheiktgw -r testApp --tap homebrew-tools", " "))
+
+	err := cmd.Execute()
+	if e, ok := err.(cmdError); !ok || e.exitCode != ExitCodeParseFlagsError || !strings.Contains(err.Error(), "GitHub App 42 cannot create a tap under a user") {
+		t.Errorf("create without --org returned %v, want an error requiring the organization", err)
+	}
+}
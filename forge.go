@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// Forges hosting applications and taps, selected via --forge
+const (
+	ForgeGitHub = "github"
+	ForgeGitLab = "gitlab"
+	ForgeGitea  = "gitea"
+)
+
+// Forge is a service hosting repositories, releases and Pull Requests which ghbr reads releases from and writes taps to.
+// Data is exchanged in the types of go-github, so that forges other than GitHub convert their own into them.
+// Errors caused by API responses are expected to report the status codes GitHub would respond with, see statusCode
+type Forge interface {
+	// GetLatestRelease returns the latest release of the repository
+	GetLatestRelease(ctx context.Context, owner, repo string) (*github.RepositoryRelease, error)
+
+	// CreateBranch creates a new branch from the heads of the origin
+	CreateBranch(ctx context.Context, owner, repo, origin, new string) error
+
+	// DeleteLatestRef deletes the branch
+	DeleteLatestRef(ctx context.Context, owner, repo, branch string) error
+
+	// CreatePullRequest opens a Pull Request, which is a Merge Request on GitLab
+	CreatePullRequest(ctx context.Context, owner, repo, title, head, base, body string) (*github.PullRequest, error)
+
+	// ListPullRequests lists open Pull Requests to the base
+	ListPullRequests(ctx context.Context, owner, repo, base string) ([]*github.PullRequest, error)
+
+	// GetPullRequest gets the Pull Request with the number
+	GetPullRequest(ctx context.Context, owner, repo string, number int) (*github.PullRequest, error)
+
+	// MergePullRequest merges the Pull Request with the number
+	MergePullRequest(ctx context.Context, owner, repo string, number int) error
+
+	// ClosePullRequest closes the Pull Request with the number
+	ClosePullRequest(ctx context.Context, owner, repo string, number int) error
+
+	// GetFile gets the file on the branch, its SHA is passed to UpdateFile and DeleteFile to update it
+	GetFile(ctx context.Context, owner, repo, branch, path string) (*github.RepositoryContent, error)
+
+	// GetDirectory lists files in the directory on the branch
+	GetDirectory(ctx context.Context, owner, repo, branch, path string) ([]*github.RepositoryContent, error)
+
+	// CreateFile commits a new file to the branch
+	CreateFile(ctx context.Context, owner, repo, branch, path, message string, content []byte) (*github.RepositoryContentResponse, error)
+
+	// UpdateFile commits the content of the file whose SHA is sha to the branch
+	UpdateFile(ctx context.Context, owner, repo, branch, path, sha, message string, content []byte) error
+
+	// DeleteFile deletes the file whose SHA is sha from the branch
+	DeleteFile(ctx context.Context, owner, repo, branch, path, sha, message string) error
+
+	// CreateRepository creates a new repository under the org, or the authenticated user if org is empty
+	CreateRepository(ctx context.Context, org, name, description, homepage string, private bool) (*github.Repository, error)
+
+	// GetRepository gets the repository
+	GetRepository(ctx context.Context, owner, name string) (*github.Repository, error)
+
+	// GetLogin returns the login name of the authenticated user, which is empty if it is not a user like a GitHub App
+	GetLogin(ctx context.Context) (string, error)
+
+	// DeleteRepository deletes the repository
+	DeleteRepository(ctx context.Context, owner, name string) error
+
+	// RepositoryURL returns the URL of the repository for humans, which is also used as the homepage of formulae
+	RepositoryURL(owner, repo string) string
+}
+
+// NewForge creates a client of the forge at the forgeURL, which is GitHub unless it is one of the other forges.
+// The forgeURL is the URL of a self-hosted instance for humans like https://gitlab.example.com, or empty for GitHub.com and GitLab.com.
+// GitHub is authenticated as the app instead of the token unless it is nil. API requests are logged through the logger unless it is nil
+func NewForge(forge, forgeURL, token string, app *GitHubApp, logger *Logger) Forge {
+	switch forge {
+	case ForgeGitLab:
+		if len(forgeURL) == 0 {
+			return NewGitLabClient(defaultGitLabURL, token, logger)
+		}
+
+		return NewGitLabClient(strings.TrimSuffix(forgeURL, "/")+"/api/v4", token, logger)
+	case ForgeGitea:
+		return NewGiteaClient(forgeURL, token, logger)
+	default:
+		if app != nil {
+			return NewGitHubAppClient(forgeURL, app, logger)
+		}
+
+		if len(forgeURL) == 0 {
+			return NewGitHubClient(token, logger)
+		}
+
+		return NewGitHubEnterpriseClient(forgeURL, token, logger)
+	}
+}
+
+// forgeTokenEnv returns the environment variable holding the token of the forge
+func forgeTokenEnv(forge string) string {
+	switch forge {
+	case ForgeGitLab:
+		return EnvGitLabToken
+	case ForgeGitea:
+		return EnvGiteaToken
+	default:
+		return EnvGitHubToken
+	}
+}
+
+// forgeHost returns the host of the forge at the forgeURL, which is GitHub.com or GitLab.com if it is empty
+func forgeHost(forge, forgeURL string) string {
+	if len(forgeURL) == 0 {
+		if forge == ForgeGitLab {
+			return "gitlab.com"
+		}
+
+		return "github.com"
+	}
+
+	u, err := url.Parse(forgeURL)
+	if err != nil {
+		return forgeURL
+	}
+
+	return strings.ToLower(u.Host)
+}
+
+// validateForge validates the forge and its URL passed via --<flag> and --<flag>-url, where the flag is forge or tap-forge
+func validateForge(flag, forge, forgeURL string) error {
+	switch forge {
+	case ForgeGitHub, ForgeGitLab:
+		if len(forgeURL) == 0 {
+			return nil
+		}
+	case ForgeGitea:
+		if len(forgeURL) == 0 {
+			return fmt.Errorf("missing the URL of your Gitea instance\n\n"+
+				"Please set it like https://codeberg.org via `--%s-url` option\n", flag)
+		}
+	default:
+		return fmt.Errorf("invalid forge: %s\n\n"+
+			"Please set one of `github`, `gitlab` or `gitea` via `--%s` option\n", forge, flag)
+	}
+
+	if u, err := url.Parse(forgeURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return fmt.Errorf("invalid forge URL: %s\n\n"+
+			"Please set the URL of your %s instance like https://%s.example.com via `--%s-url` option\n", forgeURL, forge, forge, flag)
+	}
+
+	return nil
+}
+
+// statusCode returns the HTTP status code of the API response causing the err, or 0 if the err is not caused by an API response
+func statusCode(err error) int {
+	switch e := errors.Cause(err).(type) {
+	case *github.ErrorResponse:
+		if e.Response != nil {
+			return e.Response.StatusCode
+		}
+	case *APIError:
+		return e.StatusCode
+	case *localError:
+		return e.status
+	}
+
+	return 0
+}
+
+// isNotFound reports whether the error is caused by the API returning 404 Not Found
+func isNotFound(err error) bool {
+	return statusCode(err) == http.StatusNotFound
+}
+
+// isUnprocessable reports whether the error is caused by the API returning 422 Unprocessable Entity,
+// which GitHub does for a branch which already exists or does not exist
+func isUnprocessable(err error) bool {
+	return statusCode(err) == http.StatusUnprocessableEntity
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/common-nighthawk/go-figure"
+	"github.com/pkg/errors"
+)
+
+// runtimeDependencyRegex matches a top level depends_on line without any option like `=> :build`
+var runtimeDependencyRegex = regexp.MustCompile(`(?m)^  depends_on\s['"]([^'"]+)['"][ \t]*\n`)
+
+// dependencyRegex matches any top level depends_on line
+var dependencyRegex = regexp.MustCompile(`(?m)^  depends_on\s.*\n`)
+
+var installRegex = regexp.MustCompile(`(?m)^  def install\b`)
+
+// formulaTemplate is a template of a formula file created by ghbr
+var formulaTemplate = template.Must(template.New("formula").Parse(`require 'formula'
+
+class {{.ClassName}} < Formula
+  homepage '{{.Homepage}}'
+  version '{{.Version}}'
+
+  url '{{.URL}}'
+  sha256 '{{.Hash}}'
+{{- with .Head}}
+{{- if .DependsOn}}
+
+  head do
+    url '{{.URL}}', branch: '{{.Branch}}'
+{{- range .DependsOn}}
+    {{.}}
+{{- end}}
+  end
+{{- else}}
+  head '{{.URL}}', branch: '{{.Branch}}'
+{{- end}}
+{{- end}}
+{{- if .Livecheck}}
+
+  livecheck do
+    url :stable
+{{- if .LivecheckStrategy}}
+    strategy :{{.LivecheckStrategy}}
+{{- end}}
+  end
+{{- end}}
+{{- if .DependsOn}}
+{{range .DependsOn}}
+  {{.}}
+{{- end}}
+{{- end}}
+{{- range .Resources}}
+{{template "resource" .}}
+{{- end}}
+
+  def install
+{{- if .HeadInstall}}
+    if build.head?
+{{- range .HeadInstall}}
+      {{.}}
+{{- end}}
+    else
+{{- range .Install}}
+      {{.}}
+{{- end}}
+    end
+{{- else}}
+{{- range .Install}}
+    {{.}}
+{{- end}}
+{{- end}}
+  end
+{{- if .Caveats}}
+
+  def caveats
+    <<-'{{.CaveatsTerminator}}'
+{{.Caveats}}
+{{.CaveatsTerminator}}
+  end
+{{- end}}
+end
+
+`))
+
+// resourceTemplate is a template of a resource block in a formula file
+var resourceTemplate = template.Must(formulaTemplate.New("resource").Parse(`
+  resource '{{.Name}}' do
+    url '{{.URL}}'
+    sha256 '{{.Hash}}'
+  end`))
+
+// formula contains values to render a formula file
+type formula struct {
+	ClassName, OriginalRepo, Homepage, Version, URL, Hash, Caveats string
+	DependsOn, Install                                             []string
+	Livecheck                                                      bool
+
+	// LivecheckStrategy is the strategy of livecheck, which is inferred by Homebrew from the url if it is empty
+	LivecheckStrategy string
+
+	// Head is omitted from the formula if it is nil
+	Head *formulaHead
+
+	Resources []formulaResource
+}
+
+// livecheckStrategy returns the livecheck strategy for the stable url, the latest release of GitHub is checked only if
+// the url is on GitHub.com, Homebrew infers the strategy from the url otherwise
+func livecheckStrategy(url string) string {
+	if strings.HasPrefix(url, defaultGitHubURL+"/") {
+		return "github_latest"
+	}
+
+	return ""
+}
+
+// formulaResource contains values to render a resource block
+type formulaResource struct {
+	Name, URL, Hash string
+}
+
+// formulaHead contains values to render a head stanza, DependsOn and Install are
+// set only if the head needs to be built differently from the stable release
+type formulaHead struct {
+	URL, Branch        string
+	DependsOn, Install []string
+}
+
+// HeadInstall returns lines of the install block for the head, or nil if the head is installed in the same way as the stable release
+func (f *formula) HeadInstall() []string {
+	if f.Head == nil {
+		return nil
+	}
+
+	return f.Head.Install
+}
+
+// CaveatsTerminator returns the terminator of the caveats heredoc, which is EOF unless a line of the caveats
+// would end the heredoc early, in which case a number is appended until none does
+func (f *formula) CaveatsTerminator() string {
+	lines := make(map[string]bool)
+	for _, l := range strings.Split(f.Caveats, "\n") {
+		lines[strings.TrimSpace(l)] = true
+	}
+
+	terminator := "EOF"
+	for i := 1; lines[terminator]; i++ {
+		terminator = fmt.Sprintf("EOF%d", i)
+	}
+
+	return terminator
+}
+
+// render renders the formula with the custom template at the path, or formulaTemplate if the path is empty
+func (f *formula) render(path string) (string, error) {
+	t, err := parseTemplate(formulaTemplate, path)
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+
+	if err := t.Execute(&b, f); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// parseTemplate parses the custom template at the path on top of the base template, so that it can refer to
+// templates associated with the base like `{{template "resource" .}}`. It returns the base if the path is empty
+func parseTemplate(base *template.Template, path string) (*template.Template, error) {
+	if len(path) == 0 {
+		return base, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read template file")
+	}
+
+	t, err := base.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	t, err = t.New(filepath.Base(path)).Parse(string(b))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse template file %s", path)
+	}
+
+	return t, nil
+}
+
+// renderCaveats renders caveats of a formula from the caveats option, which is one of
+// none, figure, file:<path> or text:<string>. It returns an empty string for none
+func renderCaveats(option, app, font string) (string, error) {
+	switch {
+	case option == "none":
+		return "", nil
+	case option == "figure":
+		return figure.NewFigure(app, font, true).String(), nil
+	case strings.HasPrefix(option, "file:"):
+		b, err := ioutil.ReadFile(strings.TrimPrefix(option, "file:"))
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read caveats file")
+		}
+
+		return strings.TrimRight(string(b), "\n"), nil
+	case strings.HasPrefix(option, "text:"):
+		return strings.TrimPrefix(option, "text:"), nil
+	default:
+		return "", errors.Errorf("invalid caveats option: %s", option)
+	}
+}
+
+// sourceLanguage describes how to build an application written in a specific language from its source
+type sourceLanguage struct {
+	name string
+
+	// manifest is a file which exists at the root of a repository written in the language
+	manifest string
+
+	dependsOn, install []string
+}
+
+// sourceLanguages are languages ghbr can build from source, in order of detection
+var sourceLanguages = []sourceLanguage{
+	{
+		name:      "go",
+		manifest:  "go.mod",
+		dependsOn: []string{`depends_on 'go' => :build`},
+		install:   []string{`system 'go', 'build', *std_go_args(ldflags: '-s -w')`},
+	},
+	{
+		name:      "rust",
+		manifest:  "Cargo.toml",
+		dependsOn: []string{`depends_on 'rust' => :build`},
+		install:   []string{`system 'cargo', 'install', *std_cargo_args`},
+	},
+	{
+		name:      "node",
+		manifest:  "package.json",
+		dependsOn: []string{`depends_on 'node'`},
+		install: []string{
+			`system 'npm', 'install', *std_npm_args`,
+			`bin.install_symlink Dir["#{libexec}/bin/*"]`,
+		},
+	},
+}
+
+// findSourceLanguage returns the sourceLanguage with the given name
+func findSourceLanguage(name string) (*sourceLanguage, bool) {
+	for _, l := range sourceLanguages {
+		if l.name == name {
+			return &l, true
+		}
+	}
+
+	return nil, false
+}
+
+// dependsOn returns a depends_on line of the runtime dependency
+func dependsOn(dependency string) string {
+	return fmt.Sprintf("depends_on '%s'", dependency)
+}
+
+// reconcileDependencies adds and removes top level runtime dependencies of the formula so that they match the dependencies.
+// Dependencies with options like `=> :build` and runtime dependencies of the language the formula is built with are left untouched
+func reconcileDependencies(content string, dependencies []string) (string, error) {
+	want := make(map[string]bool)
+	for _, d := range dependencies {
+		want[d] = true
+	}
+
+	for _, l := range sourceLanguages {
+		if !strings.Contains(content, l.install[0]) {
+			continue
+		}
+
+		for _, d := range l.dependsOn {
+			if ms := runtimeDependencyRegex.FindStringSubmatch("  " + d + "\n"); ms != nil {
+				want[ms[1]] = true
+			}
+		}
+	}
+
+	// Remove dependencies which are no longer declared
+	have := make(map[string]bool)
+	c := runtimeDependencyRegex.ReplaceAllStringFunc(content, func(line string) string {
+		name := runtimeDependencyRegex.FindStringSubmatch(line)[1]
+
+		if !want[name] || have[name] {
+			return ""
+		}
+
+		have[name] = true
+		return line
+	})
+
+	loc := installRegex.FindStringIndex(c)
+	if loc == nil {
+		return "", &HandledError{Message: "formula file is likely not to contain proper `def install` block"}
+	}
+
+	// Squash blank lines left by the removal
+	for strings.Contains(c[:loc[0]], "\n\n\n") {
+		c = strings.Replace(c[:loc[0]], "\n\n\n", "\n\n", 1) + c[loc[0]:]
+		loc = installRegex.FindStringIndex(c)
+	}
+
+	// Add dependencies which are newly declared
+	var lines string
+	for _, d := range dependencies {
+		if !have[d] {
+			lines += "  " + dependsOn(d) + "\n"
+			have[d] = true
+		}
+	}
+
+	if len(lines) == 0 {
+		return c, nil
+	}
+
+	if all := dependencyRegex.FindAllStringIndex(c[:loc[0]], -1); all != nil {
+		last := all[len(all)-1][1]
+		return c[:last] + lines + c[last:], nil
+	}
+
+	return c[:loc[0]] + lines + "\n" + c[loc[0]:], nil
+}
+
+// resourceRegex returns a regex matching the resource block with the name
+func resourceRegex(name string) *regexp.Regexp {
+	return regexp.MustCompile(`(?s)\n  resource\s['"]` + regexp.QuoteMeta(name) + `['"]\sdo\n.*?\n  end\n`)
+}
+
+// resourceUpToDate reports whether the formula has the resource block pointing to the url and the sha256 of the r
+func resourceUpToDate(content string, r formulaResource) bool {
+	loc := resourceRegex(r.Name).FindStringIndex(content)
+	if loc == nil {
+		return false
+	}
+
+	block := content[loc[0]:loc[1]]
+	url, hash := urlRegex.FindStringSubmatch(block), shaRegex.FindStringSubmatch(block)
+
+	return url != nil && hash != nil && url[1] == r.URL && hash[1] == r.Hash
+}
+
+// updateResource updates url and sha256 of the resource block, or adds the block before install if the formula does not have it
+func updateResource(content string, r formulaResource) (string, error) {
+	var b bytes.Buffer
+	if err := resourceTemplate.Execute(&b, r); err != nil {
+		return "", err
+	}
+
+	loc := resourceRegex(r.Name).FindStringIndex(content)
+	if loc == nil {
+		install := installRegex.FindStringIndex(content)
+		if install == nil {
+			return "", &HandledError{Message: "formula file is likely not to contain proper `def install` block"}
+		}
+
+		return content[:install[0]] + strings.TrimPrefix(b.String(), "\n") + "\n\n" + content[install[0]:], nil
+	}
+
+	block, err := findAndReplace(urlRegex, content[loc[0]:loc[1]], r.URL)
+	if err != nil {
+		return "", errors.Wrapf(err, "resource %s is likely not to contain proper `url` indicator", r.Name)
+	}
+
+	block, err = findAndReplace(shaRegex, block, r.Hash)
+	if err != nil {
+		return "", errors.Wrapf(err, "resource %s is likely not to contain proper `sha256` indicator", r.Name)
+	}
+
+	return content[:loc[0]] + block + content[loc[1]:], nil
+}
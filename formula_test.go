@@ -0,0 +1,367 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFormula_Render(t *testing.T) {
+	cases := []struct {
+		formula formula
+		want    string
+	}{
+		{
+			formula: formula{
+				ClassName:    "TestApp",
+				OriginalRepo: "shuheiktgw/testApp",
+				Homepage:     "https://github.com/shuheiktgw/testApp",
+				Version:      "v0.0.1",
+				URL:          "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip",
+				Hash:         "0001123456789012345678901234567890123456789012345678901234567890",
+				Caveats:      "testApp",
+				Install:      []string{"bin.install 'testApp'"},
+			},
+			want: `require 'formula'
+
+class TestApp < Formula
+  homepage 'https://github.com/shuheiktgw/testApp'
+  version 'v0.0.1'
+
+  url 'https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip'
+  sha256 '0001123456789012345678901234567890123456789012345678901234567890'
+
+  def install
+    bin.install 'testApp'
+  end
+
+  def caveats
+    <<-'EOF'
+testApp
+EOF
+  end
+end
+
+`,
+		},
+		{
+			formula: formula{
+				ClassName:    "TestApp",
+				OriginalRepo: "shuheiktgw/testApp",
+				Homepage:     "https://github.com/shuheiktgw/testApp",
+				Version:      "v0.0.1",
+				URL:          "https://github.com/shuheiktgw/testApp/archive/refs/tags/v0.0.1.tar.gz",
+				Hash:         "0001123456789012345678901234567890123456789012345678901234567890",
+				DependsOn:    []string{"depends_on 'go' => :build"},
+				Install:      []string{"system 'go', 'build', *std_go_args(ldflags: '-s -w')"},
+			},
+			want: `require 'formula'
+
+class TestApp < Formula
+  homepage 'https://github.com/shuheiktgw/testApp'
+  version 'v0.0.1'
+
+  url 'https://github.com/shuheiktgw/testApp/archive/refs/tags/v0.0.1.tar.gz'
+  sha256 '0001123456789012345678901234567890123456789012345678901234567890'
+
+  depends_on 'go' => :build
+
+  def install
+    system 'go', 'build', *std_go_args(ldflags: '-s -w')
+  end
+end
+
+`,
+		},
+		{
+			formula: formula{
+				ClassName:         "TestApp",
+				OriginalRepo:      "shuheiktgw/testApp",
+				Homepage:          "https://github.com/shuheiktgw/testApp",
+				Version:           "v0.0.1",
+				URL:               "https://github.com/shuheiktgw/testApp/archive/refs/tags/v0.0.1.tar.gz",
+				Hash:              "0001123456789012345678901234567890123456789012345678901234567890",
+				DependsOn:         []string{"depends_on 'go' => :build"},
+				Install:           []string{"system 'go', 'build', *std_go_args(ldflags: '-s -w')"},
+				Livecheck:         true,
+				LivecheckStrategy: "github_latest",
+				Head:              &formulaHead{URL: "https://github.com/shuheiktgw/testApp.git", Branch: "master"},
+			},
+			want: `require 'formula'
+
+class TestApp < Formula
+  homepage 'https://github.com/shuheiktgw/testApp'
+  version 'v0.0.1'
+
+  url 'https://github.com/shuheiktgw/testApp/archive/refs/tags/v0.0.1.tar.gz'
+  sha256 '0001123456789012345678901234567890123456789012345678901234567890'
+  head 'https://github.com/shuheiktgw/testApp.git', branch: 'master'
+
+  livecheck do
+    url :stable
+    strategy :github_latest
+  end
+
+  depends_on 'go' => :build
+
+  def install
+    system 'go', 'build', *std_go_args(ldflags: '-s -w')
+  end
+end
+
+`,
+		},
+		{
+			formula: formula{
+				ClassName:    "TestApp",
+				OriginalRepo: "shuheiktgw/testApp",
+				Homepage:     "https://github.com/shuheiktgw/testApp",
+				Version:      "v0.0.1",
+				URL:          "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip",
+				Hash:         "0001123456789012345678901234567890123456789012345678901234567890",
+				Install:      []string{"bin.install 'testApp'"},
+				Resources: []formulaResource{
+					{Name: "plugin", URL: "https://github.com/shuheiktgw/testPlugin/releases/download/v1.0.0/testPlugin_v1.0.0.tar.gz", Hash: "1000123456789012345678901234567890123456789012345678901234567890"},
+				},
+			},
+			want: `require 'formula'
+
+class TestApp < Formula
+  homepage 'https://github.com/shuheiktgw/testApp'
+  version 'v0.0.1'
+
+  url 'https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip'
+  sha256 '0001123456789012345678901234567890123456789012345678901234567890'
+
+  resource 'plugin' do
+    url 'https://github.com/shuheiktgw/testPlugin/releases/download/v1.0.0/testPlugin_v1.0.0.tar.gz'
+    sha256 '1000123456789012345678901234567890123456789012345678901234567890'
+  end
+
+  def install
+    bin.install 'testApp'
+  end
+end
+
+`,
+		},
+	}
+
+	for i, tc := range cases {
+		got, err := tc.formula.render("")
+		if err != nil {
+			t.Fatalf("#%d #render returns unexpected error: %s", i, err)
+		}
+
+		if got != tc.want {
+			t.Errorf("#%d #render returned %s, want %s", i, got, tc.want)
+		}
+	}
+}
+
+func TestFormula_Render_Template(t *testing.T) {
+	f, err := ioutil.TempFile("", "formula")
+	if err != nil {
+		t.Fatalf("failed to create a temp file: %s", err)
+	}
+	defer os.Remove(f.Name())
+
+	f.WriteString("class {{.ClassName}} < Formula\n  version '{{.Version}}'\n{{- range .Resources}}\n{{template \"resource\" .}}\n{{- end}}\nend\n")
+	f.Close()
+
+	fm := formula{
+		ClassName: "TestApp",
+		Version:   "v0.0.1",
+		Resources: []formulaResource{{Name: "plugin", URL: "https://example.com/plugin.tar.gz", Hash: "1000"}},
+	}
+
+	got, err := fm.render(f.Name())
+	if err != nil {
+		t.Fatalf("#render returns unexpected error: %s", err)
+	}
+
+	want := "class TestApp < Formula\n  version 'v0.0.1'\n\n  resource 'plugin' do\n    url 'https://example.com/plugin.tar.gz'\n    sha256 '1000'\n  end\nend\n"
+	if got != want {
+		t.Errorf("#render returned %q, want %q", got, want)
+	}
+
+	if _, err := fm.render(f.Name() + ".unknown"); err == nil {
+		t.Errorf("#render did not return error for a missing template")
+	}
+}
+
+func TestFormula_CaveatsTerminator(t *testing.T) {
+	cases := []struct {
+		caveats, want string
+	}{
+		{caveats: "Run `testApp init` first", want: "EOF"},
+		{caveats: "cat <<EOF\nsome text\nEOF", want: "EOF1"},
+		{caveats: "  EOF\nEOF1", want: "EOF2"},
+	}
+
+	for i, tc := range cases {
+		f := &formula{Caveats: tc.caveats}
+		if got := f.CaveatsTerminator(); got != tc.want {
+			t.Errorf("#%d #CaveatsTerminator returned %q, want %q", i, got, tc.want)
+		}
+	}
+}
+
+func TestRenderCaveats(t *testing.T) {
+	f, err := ioutil.TempFile("", "caveats")
+	if err != nil {
+		t.Fatalf("failed to create a temp file: %s", err)
+	}
+	defer os.Remove(f.Name())
+
+	f.WriteString("Run `testApp init` first\n")
+	f.Close()
+
+	cases := []struct {
+		option, want string
+	}{
+		{option: "none", want: ""},
+		{option: "text:Run `testApp init` first", want: "Run `testApp init` first"},
+		{option: "file:" + f.Name(), want: "Run `testApp init` first"},
+	}
+
+	for i, tc := range cases {
+		got, err := renderCaveats(tc.option, "testApp", "alphabet")
+		if err != nil {
+			t.Fatalf("#%d #renderCaveats returns unexpected error: %s", i, err)
+		}
+
+		if got != tc.want {
+			t.Errorf("#%d #renderCaveats returned %q, want %q", i, got, tc.want)
+		}
+	}
+
+	got, err := renderCaveats("figure", "testApp", "alphabet")
+	if err != nil || !strings.Contains(got, "\n") {
+		t.Errorf("#renderCaveats returned %q, %v, want ascii art", got, err)
+	}
+
+	if _, err := renderCaveats("unknown", "testApp", "alphabet"); err == nil {
+		t.Errorf("#renderCaveats did not return error for unknown option")
+	}
+}
+
+func TestReconcileDependencies(t *testing.T) {
+	cases := []struct {
+		content      string
+		dependencies []string
+		want         string
+	}{
+		{
+			// Add dependencies before install
+			content:      "  sha256 'abc'\n\n  def install\n  end\n",
+			dependencies: []string{"git", "gnupg"},
+			want:         "  sha256 'abc'\n\n  depends_on 'git'\n  depends_on 'gnupg'\n\n  def install\n  end\n",
+		},
+		{
+			// Add dependencies after the existing ones and remove undeclared ones
+			content:      "  sha256 'abc'\n\n  depends_on 'go' => :build\n  depends_on \"git\"\n  depends_on 'curl'\n\n  def install\n  end\n",
+			dependencies: []string{"git", "gnupg"},
+			want:         "  sha256 'abc'\n\n  depends_on 'go' => :build\n  depends_on \"git\"\n  depends_on 'gnupg'\n\n  def install\n  end\n",
+		},
+		{
+			// Remove all dependencies
+			content:      "  sha256 'abc'\n\n  depends_on 'git'\n\n  def install\n  end\n\n\n",
+			dependencies: []string{},
+			want:         "  sha256 'abc'\n\n  def install\n  end\n\n\n",
+		},
+		{
+			// Keep runtime dependencies of the language
+			content:      "  depends_on 'node'\n\n  def install\n    system 'npm', 'install', *std_npm_args\n  end\n",
+			dependencies: []string{},
+			want:         "  depends_on 'node'\n\n  def install\n    system 'npm', 'install', *std_npm_args\n  end\n",
+		},
+	}
+
+	for i, tc := range cases {
+		got, err := reconcileDependencies(tc.content, tc.dependencies)
+		if err != nil {
+			t.Fatalf("#%d #reconcileDependencies returns unexpected error: %s", i, err)
+		}
+
+		if got != tc.want {
+			t.Errorf("#%d #reconcileDependencies returned %q, want %q", i, got, tc.want)
+		}
+	}
+
+	if _, err := reconcileDependencies("  sha256 'abc'\n", []string{"git"}); err == nil {
+		t.Errorf("#reconcileDependencies did not return error for a formula without install")
+	}
+}
+
+func TestFindSourceLanguage(t *testing.T) {
+	if l, ok := findSourceLanguage("rust"); !ok || l.manifest != "Cargo.toml" {
+		t.Errorf("#findSourceLanguage returned %+v, want rust", l)
+	}
+
+	if _, ok := findSourceLanguage("cobol"); ok {
+		t.Errorf("#findSourceLanguage unexpectedly found cobol")
+	}
+}
+
+func TestResourceUpToDate(t *testing.T) {
+	r := formulaResource{
+		Name: "plugin",
+		URL:  "https://github.com/shuheiktgw/testPlugin/releases/download/v1.0.1/testPlugin_v1.0.1.tar.gz",
+		Hash: "1001123456789012345678901234567890123456789012345678901234567890",
+	}
+
+	block := "\n  resource 'plugin' do\n    url '%s'\n    sha256 '%s'\n  end\n"
+
+	cases := []struct {
+		content string
+		want    bool
+	}{
+		{content: fmt.Sprintf(block, r.URL, r.Hash), want: true},
+		{content: fmt.Sprintf(block, "https://example.com/v1.0.0.tar.gz", r.Hash), want: false},
+		{content: fmt.Sprintf(block, r.URL, "1000123456789012345678901234567890123456789012345678901234567890"), want: false},
+		{content: "\n  def install\n  end\n", want: false},
+	}
+
+	for i, tc := range cases {
+		if got := resourceUpToDate(tc.content, r); got != tc.want {
+			t.Errorf("#%d #resourceUpToDate returned %t, want %t", i, got, tc.want)
+		}
+	}
+}
+
+func TestUpdateResource(t *testing.T) {
+	r := formulaResource{
+		Name: "plugin",
+		URL:  "https://github.com/shuheiktgw/testPlugin/releases/download/v1.0.1/testPlugin_v1.0.1.tar.gz",
+		Hash: "1001123456789012345678901234567890123456789012345678901234567890",
+	}
+
+	cases := []struct {
+		content, want string
+	}{
+		{
+			// Update the existing resource
+			content: "  sha256 'abc'\n\n  resource \"plugin\" do\n    url \"https://example.com/v1.0.0.tar.gz\"\n    sha256 \"1000123456789012345678901234567890123456789012345678901234567890\"\n  end\n\n  def install\n  end\n",
+			want:    "  sha256 'abc'\n\n  resource \"plugin\" do\n    url \"https://github.com/shuheiktgw/testPlugin/releases/download/v1.0.1/testPlugin_v1.0.1.tar.gz\"\n    sha256 \"1001123456789012345678901234567890123456789012345678901234567890\"\n  end\n\n  def install\n  end\n",
+		},
+		{
+			// Add the resource before install
+			content: "  sha256 'abc'\n\n  def install\n  end\n",
+			want:    "  sha256 'abc'\n\n  resource 'plugin' do\n    url 'https://github.com/shuheiktgw/testPlugin/releases/download/v1.0.1/testPlugin_v1.0.1.tar.gz'\n    sha256 '1001123456789012345678901234567890123456789012345678901234567890'\n  end\n\n  def install\n  end\n",
+		},
+	}
+
+	for i, tc := range cases {
+		got, err := updateResource(tc.content, r)
+		if err != nil {
+			t.Fatalf("#%d #updateResource returns unexpected error: %s", i, err)
+		}
+
+		if got != tc.want {
+			t.Errorf("#%d #updateResource returned %q, want %q", i, got, tc.want)
+		}
+	}
+}
@@ -1,24 +1,30 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"path"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/common-nighthawk/go-figure"
 	"github.com/google/go-github/github"
 	"github.com/iancoleman/strcase"
 	"github.com/pkg/errors"
 )
 
 var versionRegex = regexp.MustCompile(`version\s['"]([\w.-]+)['"]`)
-var urlRegex = regexp.MustCompile(`url\s['"]((http|https)://[\w-./?%&=]+)['"]`)
+var urlRegex = regexp.MustCompile(`url\s['"]((http|https)://[\w-./?%&=:]+)['"]`)
 var shaRegex = regexp.MustCompile(`sha256\s['"]([0-9A-Fa-f]{64})['"]`)
 
 // HandledError represents the error is properly handled and is not unexpected
@@ -32,28 +38,175 @@ func (e *HandledError) Error() string {
 
 type GhbrGenerator func(token string) *Ghbr
 
-// GenerateGhbr defines a method to create ghbr
+// GenerateGhbr defines a method to create ghbr, which logs to stderr at the level set via --quiet, --verbose or --debug
 func GenerateGhbr(token string) *Ghbr {
-	return &Ghbr{GitHub: NewGitHubClient(token), outStream: os.Stdout}
+	tapToken := globalOpts.tapTokenOr(token)
+	logger := NewLogger(globalOpts.level(), os.Stderr, token, tapToken)
+	g := &Ghbr{Forge: NewForge(globalOpts.forge, globalOpts.forgeURL, token, globalOpts.app, logger), outStream: os.Stdout, logger: logger, stateDir: defaultStateDir()}
+
+	if len(globalOpts.releaseURL) != 0 {
+		g.Source = NewReleaseSource(globalOpts.releaseURL, logger)
+	}
+
+	switch {
+	case len(globalOpts.tapPath) != 0:
+		// The author is validated in advance by validateLocalTapFlags
+		name, email, _ := parseAuthor(globalOpts.author)
+		g.TapForge = NewLocalClient(globalOpts.tapPath, name, email, globalOpts.push, logger)
+	case len(globalOpts.tapForge) != 0:
+		g.TapForge = NewForge(globalOpts.tapForge, globalOpts.tapForgeURL, tapToken, globalOpts.app, logger)
+	}
+
+	return g
 }
 
 // Ghbr defines functions for Homebrew Formula
 type Ghbr struct {
-	GitHub *GitHubClient
+	// Forge hosts the application and the tap unless TapForge is set, which is GitHub unless --forge is passed
+	Forge Forge
+
+	// Source is where the latest release of the application is found instead of Forge unless it is nil, e.g. an index passed via --release-url
+	Source ReleaseSource
+
+	// TapForge hosts the tap instead of Forge unless it is nil, e.g. a local repository passed via --tap-path or another forge via --tap-forge
+	TapForge Forge
 
 	outStream io.Writer
+
+	// reporter reports the progress and the result, the text one writing to outStream is used if it is nil
+	reporter Reporter
+
+	// logger logs diagnostics, nothing is logged if it is nil
+	logger *Logger
+
+	// stateDir is the directory operations are saved to so that they can be resumed, nothing is saved if it is empty
+	stateDir string
+}
+
+// report returns the reporter of the Ghbr
+func (g *Ghbr) report() Reporter {
+	if g.reporter != nil {
+		return g.reporter
+	}
+
+	return newReporter(OutputText, g.outStream, g.logger.Quiet())
+}
+
+// releaseSource returns where the latest release of the application is found
+func (g *Ghbr) releaseSource() ReleaseSource {
+	if g.Source != nil {
+		return g.Source
+	}
+
+	return g.Forge
+}
+
+// tapForge returns the forge hosting the tap
+func (g *Ghbr) tapForge() Forge {
+	if g.TapForge != nil {
+		return g.TapForge
+	}
+
+	return g.Forge
+}
+
+// setOutput sets the output format of the progress and the result
+func (g *Ghbr) setOutput(output string) {
+	g.reporter = newReporter(output, g.outStream, g.logger.Quiet())
+}
+
+// Tap is a repository hosting formulae and casks
+type Tap struct {
+	// Owner is a user or an organization owning the tap, the owner of the application is used if it is empty
+	Owner string
+
+	// Name is a name of the tap repository, homebrew-[app] is used if it is empty
+	Name string
+
+	// Path is a path of the formula file in the tap, it is detected from the layout of the tap if it is empty
+	Path string
+}
+
+// owner returns the owner of the tap
+func (t Tap) owner(user string) string {
+	if len(t.Owner) != 0 {
+		return t.Owner
+	}
+
+	return user
+}
+
+// name returns the name of the tap repository for the app
+func (t Tap) name(app string) string {
+	if len(t.Name) != 0 {
+		return t.Name
+	}
+
+	return fmt.Sprintf("homebrew-%s", app)
+}
+
+// shared reports whether the tap is a shared one passed via --tap, which hosts formulae of other applications as well
+func (t Tap) shared() bool {
+	return len(t.Name) != 0
+}
+
+// bumpBranch returns the name of the branch bumping up the app to the version, which is named after the app
+// in a shared tap so that formulae released at the same version do not collide
+func (t Tap) bumpBranch(app, version string) string {
+	if t.shared() {
+		return fmt.Sprintf("bumps_up_%s_to_%s", app, version)
+	}
+
+	return fmt.Sprintf("bumps_up_to_%s", version)
+}
+
+// tapRepository is a tap repository which formula and cask files are added to
+type tapRepository struct {
+	owner, name, branch, htmlURL string
+
+	// created is true if the repository is newly created by ghbr
+	created bool
 }
 
 // LatestRelease contains latest release info
 type LatestRelease struct {
 	version, url, hash string
+
+	// language is a name of sourceLanguage, set only when the release is built from source
+	language string
+
+	// files are files in the release archive, set only when the archive is inspected
+	files []archiveFile
 }
 
-// GetLatestRelease returns the latest release and calculates its checksum
-func (g *Ghbr) GetLatestRelease(owner, repo string) (*LatestRelease, error) {
+// FormulaOptions contains optional stanzas of a formula created by ghbr
+type FormulaOptions struct {
+	// Caveats is omitted from the formula if it is empty
+	Caveats string
+
+	// Livecheck adds a livecheck block to check the latest release on GitHub
+	Livecheck bool
+
+	// Head adds a head stanza pointing to the default branch of the repository
+	Head bool
+
+	// Dependencies are runtime dependencies of the formula, which are also
+	// reconciled on update unless they are nil
+	Dependencies []string
+
+	// Resources are resource blocks of the formula, which are also updated to their latest releases on update
+	Resources []Resource
+
+	// Template is a path to a custom template of the formula, the built-in one is used if it is empty
+	Template string
+}
+
+// GetLatestRelease returns the latest release and calculates its checksum. The asset is a glob pattern
+// of the released asset, the Darwin AMD64 one is used if it is empty. If inspect is true, it also lists files in the release archive
+func (g *Ghbr) GetLatestRelease(ctx context.Context, owner, repo, asset string, inspect bool) (*LatestRelease, error) {
 	// Get latest release of the repository
-	fmt.Fprint(g.outStream, "[ghbr] ===> Checking the latest release\n")
-	release, err := g.GitHub.GetLatestRelease(owner, repo)
+	g.report().Step("check_release", "Checking the latest release")
+	release, err := g.releaseSource().GetLatestRelease(ctx, owner, repo)
 	if err != nil {
 		return nil, err
 	}
@@ -62,14 +215,99 @@ func (g *Ghbr) GetLatestRelease(owner, repo string) (*LatestRelease, error) {
 	version := *release.TagName
 
 	// Get a URL of a released asset for Mac
-	url, err := findMacAssetURL(release)
+	var url string
+	if len(asset) != 0 {
+		url, err = findAssetURL(release, asset)
+	} else {
+		url, err = findMacAssetURL(release)
+	}
+
 	if err != nil {
 		return nil, err
 	}
 
+	g.logger.Verbosef("the latest release of %s/%s is %s, using %s", owner, repo, version, url)
+
 	// Download the release asset
-	fmt.Fprint(g.outStream, "[ghbr] ===> Downloading Darwin AMD64 release\n")
-	body, err := g.downloadFile(url)
+	if len(asset) != 0 {
+		g.report().Step("download_release", "Downloading %s", path.Base(url))
+	} else {
+		g.report().Step("download_release", "Downloading Darwin AMD64 release")
+	}
+
+	body, err := g.downloadFile(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	defer body.Close()
+
+	if !inspect {
+		// Calculate hash
+		g.report().Step("calculate_checksum", "Calculating a checksum of the release")
+		hash, err := calculateSha256(body)
+		if err != nil {
+			return nil, err
+		}
+
+		g.logger.Verbosef("sha256 of %s is %s", path.Base(url), hash)
+
+		return &LatestRelease{version: version, url: url, hash: hash}, nil
+	}
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to download the release asset")
+	}
+
+	// Calculate hash
+	g.report().Step("calculate_checksum", "Calculating a checksum of the release")
+	hash, err := calculateSha256(ioutil.NopCloser(bytes.NewReader(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	g.logger.Verbosef("sha256 of %s is %s", path.Base(url), hash)
+
+	// Inspect the release archive
+	g.report().Step("inspect_archive", "Inspecting the release archive")
+	files, err := listArchive(url, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LatestRelease{version: version, url: url, hash: hash, files: files}, nil
+}
+
+// GetLatestSourceRelease returns the source tarball of the latest release,
+// calculates its checksum and detects the language of the repository
+func (g *Ghbr) GetLatestSourceRelease(ctx context.Context, owner, repo string) (*LatestRelease, error) {
+	// Get latest release of the repository
+	g.report().Step("check_release", "Checking the latest release")
+	release, err := g.releaseSource().GetLatestRelease(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Extract version
+	version := *release.TagName
+
+	if release.TarballURL == nil {
+		return nil, &HandledError{Message: fmt.Sprintf("The latest release %s does not have a source tarball", version)}
+	}
+
+	url := *release.TarballURL
+
+	// Detect the language to build the source with
+	g.report().Step("detect_language", "Detecting the language of the source")
+	language, err := g.detectLanguage(ctx, owner, repo, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Download the source tarball
+	g.report().Step("download_release", "Downloading the source tarball")
+	body, err := g.downloadFile(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -77,74 +315,318 @@ func (g *Ghbr) GetLatestRelease(owner, repo string) (*LatestRelease, error) {
 	defer body.Close()
 
 	// Calculate hash
-	fmt.Fprint(g.outStream, "[ghbr] ===> Calculating a checksum of the release\n")
+	g.report().Step("calculate_checksum", "Calculating a checksum of the release")
 	hash, err := calculateSha256(body)
 	if err != nil {
 		return nil, err
 	}
 
-	return &LatestRelease{version: version, url: url, hash: hash}, nil
+	return &LatestRelease{version: version, url: url, hash: hash, language: language}, nil
 }
 
-func (g *Ghbr) CreateFormula(org, owner, app, font string, private bool, release *LatestRelease) error {
-	// Create a new Repository
-	formulaRepoName := fmt.Sprintf("homebrew-%s", app)
-	originalRepo := fmt.Sprintf("%s/%s", owner, app)
+// GetLatestCaskRelease returns the latest release of a macOS app and calculates its checksum.
+// The asset is a glob pattern of the released app, which is looked up by its extension if it is empty
+func (g *Ghbr) GetLatestCaskRelease(ctx context.Context, owner, repo, asset string) (*LatestRelease, error) {
+	// Get latest release of the repository
+	g.report().Step("check_release", "Checking the latest release")
+	release, err := g.releaseSource().GetLatestRelease(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
 
-	fmt.Fprint(g.outStream, "[ghbr] ===> Creating a repository\n")
-	repo, err := g.GitHub.CreateRepository(
-		org,
-		formulaRepoName,
-		fmt.Sprintf("Homebrew formula for %s", originalRepo),
-		fmt.Sprintf("https://github.com/%s", originalRepo),
-		private,
-	)
+	// Casks conventionally omit the `v` prefix of a tag
+	version := strings.TrimPrefix(*release.TagName, "v")
+
+	// Get a URL of a released macOS app
+	var url string
+	if len(asset) != 0 {
+		url, err = findAssetURL(release, asset)
+	} else {
+		url, err = findCaskAssetURL(release)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Download the release asset
+	g.report().Step("download_release", "Downloading macOS app release")
+	body, err := g.downloadFile(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	defer body.Close()
+
+	// Calculate hash
+	g.report().Step("calculate_checksum", "Calculating a checksum of the release")
+	hash, err := calculateSha256(body)
+	if err != nil {
+		return nil, err
+	}
 
+	return &LatestRelease{version: version, url: url, hash: hash}, nil
+}
+
+// CreateFormula adds a formula file pointing to the release to the tap, the tap is created if it does not exist yet
+func (g *Ghbr) CreateFormula(ctx context.Context, tap Tap, owner, app string, private bool, opts FormulaOptions, release *LatestRelease) (err error) {
+	f, err := g.buildFormula(ctx, owner, app, opts, release)
 	if err != nil {
 		return err
 	}
 
-	var formulaOwner string
-	if len(org) != 0 {
-		formulaOwner = org
-	} else {
-		formulaOwner = owner
+	content, err := f.render(opts.Template)
+	if err != nil {
+		return err
 	}
 
-	// Create README.md
-	fmt.Fprint(g.outStream, "[ghbr] ===> Adding README.md to the repository\n")
-	if err := g.createReadme(formulaOwner, formulaRepoName, originalRepo); err != nil {
+	// Lint the formula before creating anything, only the file name of the path matters here
+	name := tap.Path
+	if len(name) == 0 {
+		name = fmt.Sprintf("%s.rb", app)
+	}
+
+	if err := g.lint(content, name, nil); err != nil {
+		return err
+	}
+
+	// Delete the repository if it is created but the formula fails to be added
+	j := g.newJournal()
+	defer j.finish(&err)
+
+	repo, err := g.prepareTap(ctx, j, tap, owner, app, private)
+	if err != nil {
 		return err
 	}
 
+	path := tap.Path
+	switch {
+	case len(path) != 0:
+	case repo.created:
+		path = fmt.Sprintf("%s.rb", app)
+	default:
+		if path, err = g.formulaPath(ctx, repo.owner, repo.name, repo.branch, app); err != nil {
+			return err
+		}
+	}
+
 	// Create Formula
-	fmt.Fprintf(g.outStream, "[ghbr] ===> Adding %s.rb to the repository\n", app)
-	if err := g.createFormula(formulaOwner, app, formulaRepoName, originalRepo, font, release); err != nil {
+	g.report().Step("create_file", "Adding %s to the repository", path)
+	if err := g.createFormula(ctx, repo.owner, repo.name, repo.branch, path, content); err != nil {
+		return err
+	}
+
+	g.printCreated("formula", path, repo, release)
+
+	return nil
+}
+
+// CreateCask creates a new tap repository and adds a cask file pointing to the release,
+// the template is a path to a custom template of the cask and the built-in one is used if it is empty
+func (g *Ghbr) CreateCask(ctx context.Context, tap Tap, owner, app string, private bool, template string, release *LatestRelease) (err error) {
+	// Get the description of the application
+	original, err := g.Forge.GetRepository(ctx, owner, app)
+	if err != nil {
 		return err
 	}
 
-	fmt.Fprintf(g.outStream, "\n\n")
-	fmt.Fprintf(g.outStream, "Yay! Your Homebrew formula repository has been successfully created!\n")
-	fmt.Fprintf(g.outStream, "Access %s and see what we achieved.\n\n", *repo.HTMLURL)
+	// Delete the repository if it is created but the cask fails to be added
+	j := g.newJournal()
+	defer j.finish(&err)
+
+	repo, err := g.prepareTap(ctx, j, tap, owner, app, private)
+	if err != nil {
+		return err
+	}
+
+	// Create Cask
+	path := caskPath(app)
+	g.report().Step("create_file", "Adding %s to the repository", path)
+	if err := g.createCask(ctx, repo.owner, app, repo.name, repo.branch, path, g.Forge.RepositoryURL(owner, app), original.GetDescription(), template, release); err != nil {
+		return err
+	}
+
+	g.printCreated("cask", path, repo, release)
 
 	return nil
 }
 
-// UpdateFormula updates the formula file to point to the latest release
-func (g *Ghbr) UpdateFormula(org, owner, app, branch string, force, merge bool, release *LatestRelease) error {
-	repo := fmt.Sprintf("homebrew-%s", app)
-	path := fmt.Sprintf("%s.rb", app)
+// printCreated reports the result after a formula or cask file is added to the tap at the path, kind is either "formula" or "cask"
+func (g *Ghbr) printCreated(kind, path string, repo *tapRepository, release *LatestRelease) {
+	g.report().Message("\n\n")
 
-	var formulaOwner string
-	if len(org) != 0 {
-		formulaOwner = org
+	if repo.created {
+		g.report().Message("Yay! Your Homebrew %s repository has been successfully created!\n", kind)
 	} else {
-		formulaOwner = owner
+		g.report().Message("Yay! Your Homebrew %s has been successfully added to %s/%s!\n", kind, repo.owner, repo.name)
+	}
+
+	g.report().Message("Access %s and see what we achieved.\n\n", repo.htmlURL)
+
+	g.report().Result(&Result{
+		Kind:       kind,
+		Repository: repo.htmlURL,
+		Created:    repo.created,
+		Path:       path,
+		NewVersion: release.version,
+		NewSHA256:  release.hash,
+		URL:        release.url,
+	})
+}
+
+// UpdateFormula updates the formula file to point to the latest release,
+// only Dependencies and Resources of the opts are applied to the existing formula
+func (g *Ghbr) UpdateFormula(ctx context.Context, tap Tap, owner, app, branch string, force, merge bool, opts FormulaOptions, release *LatestRelease) error {
+	path := tap.Path
+	if len(path) == 0 {
+		var err error
+		if path, err = g.formulaPath(ctx, tap.owner(owner), tap.name(app), branch, app); err != nil {
+			return err
+		}
+	}
+
+	// Resources are resolved while checking whether the formula is up-to-date, so that their new releases
+	// are picked up even if the application itself has not been released since
+	var resources []formulaResource
+	upToDate := func(content string, release *LatestRelease) (bool, error) {
+		current, err := checkVersionLatest(content, release)
+		if err != nil {
+			return false, err
+		}
+
+		for _, r := range opts.Resources {
+			fr, err := g.getLatestResource(ctx, r)
+			if err != nil {
+				return false, err
+			}
+
+			resources = append(resources, *fr)
+			current = current && resourceUpToDate(content, *fr)
+		}
+
+		return current, nil
+	}
+
+	bumpsUp := func(content string, release *LatestRelease) (string, error) {
+		c, err := bumpsUpFormula(content, release)
+		if err != nil {
+			return "", err
+		}
+
+		if opts.Dependencies != nil {
+			if c, err = reconcileDependencies(c, opts.Dependencies); err != nil {
+				return "", err
+			}
+		}
+
+		for _, r := range resources {
+			if c, err = updateResource(c, r); err != nil {
+				return "", err
+			}
+		}
+
+		// Problems the formula already has do not prevent it from being bumped up
+		if err := g.lint(c, path, lintFormula(content, path)); err != nil {
+			return "", err
+		}
+
+		return c, nil
+	}
+
+	return g.updateFile(ctx, "formula", tap, owner, app, branch, path, force, merge, release, upToDate, bumpsUp)
+}
+
+// UpdateCask updates the cask file to point to the latest release
+func (g *Ghbr) UpdateCask(ctx context.Context, tap Tap, owner, app, branch string, force, merge bool, release *LatestRelease) error {
+	return g.updateFile(ctx, "cask", tap, owner, app, branch, caskPath(app), force, merge, release, checkVersionLatest, bumpsUpCask)
+}
+
+// prepareTap returns the tap repository if it exists, or creates a new one with README.md otherwise.
+// Deleting the created repository is recorded in the j
+func (g *Ghbr) prepareTap(ctx context.Context, j *journal, tap Tap, owner, app string, private bool) (*tapRepository, error) {
+	formulaOwner := tap.owner(owner)
+	formulaRepoName := tap.name(app)
+
+	existing, err := g.tapForge().GetRepository(ctx, formulaOwner, formulaRepoName)
+	if err == nil {
+		g.report().Step("use_repository", "Using the existing repository %s/%s", formulaOwner, formulaRepoName)
+		return &tapRepository{owner: formulaOwner, name: formulaRepoName, branch: existing.GetDefaultBranch(), htmlURL: existing.GetHTMLURL()}, nil
 	}
 
+	if !isNotFound(err) {
+		return nil, err
+	}
+
+	// Create the repository under the organization unless the tap is owned by the authenticated user,
+	// the owner is always an organization for GitHub Apps, which are not users
+	var org string
+	if len(tap.Owner) != 0 {
+		login, err := g.tapForge().GetLogin(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if login != tap.Owner {
+			org = tap.Owner
+		}
+	}
+
+	// Create a new Repository
+	originalRepo := fmt.Sprintf("%s/%s", owner, app)
+
+	g.report().Step("create_repository", "Creating a repository")
+	repo, err := g.tapForge().CreateRepository(ctx,
+		org,
+		formulaRepoName,
+		fmt.Sprintf("Homebrew formula for %s", originalRepo),
+		g.Forge.RepositoryURL(owner, app),
+		private,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	j.record(fmt.Sprintf("delete the repository %s/%s", formulaOwner, formulaRepoName), func(ctx context.Context) error {
+		return g.tapForge().DeleteRepository(ctx, formulaOwner, formulaRepoName)
+	})
+
+	// Create README.md
+	g.report().Step("create_readme", "Adding README.md to the repository")
+	if err := g.createReadme(ctx, formulaOwner, formulaRepoName, originalRepo, g.Forge.RepositoryURL(owner, app)); err != nil {
+		return nil, err
+	}
+
+	return &tapRepository{owner: formulaOwner, name: formulaRepoName, branch: "master", htmlURL: repo.GetHTMLURL(), created: true}, nil
+}
+
+// formulaPath returns a path of the formula file in the tap,
+// which is Formula/[app].rb if the tap has Formula directory or [app].rb otherwise
+func (g *Ghbr) formulaPath(ctx context.Context, owner, repo, branch, app string) (string, error) {
+	dir, err := g.tapForge().GetDirectory(ctx, owner, repo, branch, "Formula")
+	if err != nil && !isNotFound(err) {
+		return "", err
+	}
+
+	if dir == nil {
+		g.logger.Verbosef("%s/%s has no Formula directory, using %s.rb", owner, repo, app)
+		return fmt.Sprintf("%s.rb", app), nil
+	}
+
+	g.logger.Verbosef("%s/%s has Formula directory, using Formula/%s.rb", owner, repo, app)
+	return fmt.Sprintf("Formula/%s.rb", app), nil
+}
+
+// updateFile updates the formula or cask file at the path to point to the latest release through a Pull Request unless upToDate
+// reports the file already does, kind is either "formula" or "cask"
+func (g *Ghbr) updateFile(ctx context.Context, kind string, tap Tap, owner, app, branch, path string, force, merge bool, release *LatestRelease,
+	upToDate func(string, *LatestRelease) (bool, error), bumpsUp func(string, *LatestRelease) (string, error)) error {
+	repo := tap.name(app)
+	formulaOwner := tap.owner(owner)
+
 	// Get the formula file
-	fmt.Fprintf(g.outStream, "[ghbr] ===> Checking the current formula\n")
-	rc, err := g.GitHub.GetFile(formulaOwner, repo, branch, path)
+	g.report().Step("check_current", "Checking the current %s", kind)
+	rc, err := g.tapForge().GetFile(ctx, formulaOwner, repo, branch, path)
 
 	if err != nil {
 		return err
@@ -158,112 +640,352 @@ func (g *Ghbr) UpdateFormula(org, owner, app, branch string, force, merge bool,
 	}
 
 	// Check current version
-	upToDate, err := checkVersionLatest(currentFormula, release)
+	current, err := upToDate(currentFormula, release)
 
 	if err != nil {
 		return err
 	}
 
-	if upToDate && !force {
-		fmt.Fprintf(g.outStream, "\n\n")
-		fmt.Fprintf(g.outStream, "ghbr aborted!\n\n")
+	result := &Result{
+		Kind:       kind,
+		Repository: g.tapForge().RepositoryURL(formulaOwner, repo),
+		Path:       path,
+		NewVersion: release.version,
+		NewSHA256:  release.hash,
+		URL:        release.url,
+	}
+
+	if ms := versionRegex.FindStringSubmatch(currentFormula); ms != nil {
+		result.OldVersion = ms[1]
+	}
+
+	if ms := shaRegex.FindStringSubmatch(currentFormula); ms != nil {
+		result.OldSHA256 = ms[1]
+	}
+
+	if current && !force {
+		g.report().Message("\n\n")
+		g.report().Message("ghbr aborted!\n\n")
+
+		g.report().Message("The current %s (pointing to version %s) is up-to-date.\n", kind, release.version)
+		g.report().Message("If you want to update the %s anyway, run `ghbr release` with `--force` option.\n\n", kind)
+
+		result.UpToDate = true
+		g.report().Result(result)
 
-		fmt.Fprintf(g.outStream, "The current formula (pointing to version %s) is up-to-date.\n", release.version)
-		fmt.Fprintf(g.outStream, "If you want to update the formula anyway, run `ghbr release` with `--force` option.\n\n")
 		return nil
 	}
 
 	// Edit the formula file
-	newFormula, err := bumpsUpFormula(currentFormula, release)
+	newFormula, err := bumpsUp(currentFormula, release)
 
 	if err != nil {
 		return err
 	}
 
-	// Create a new feature branch
-	fmt.Fprintf(g.outStream, "[ghbr] ===> Creating a new feature branch\n")
-	newBranch := fmt.Sprintf("bumps_up_to_%s", release.version)
+	message := fmt.Sprintf("Bumps up to %s", release.version)
+	if tap.shared() {
+		message = fmt.Sprintf("Bumps up %s to %s", app, release.version)
+	}
 
-	err = g.GitHub.CreateBranch(formulaOwner, repo, branch, newBranch)
+	updates := []fileUpdate{{label: fmt.Sprintf("the %s file", kind), path: path, sha: *rc.SHA, content: newFormula}}
 
+	pr, err := g.pullRequest(ctx, formulaOwner, repo, branch, tap.bumpBranch(app, release.version), message, message, updates, merge)
 	if err != nil {
 		return err
 	}
 
-	// Update formula file on the feature branch
-	fmt.Fprintf(g.outStream, "[ghbr] ===> Updating the formula file\n")
-	message := fmt.Sprintf("Bumps up to %s", release.version)
+	result.PullRequest = &PullRequestResult{Number: pr.GetNumber(), URL: pr.GetHTMLURL()}
+	result.Merged = merge
 
-	err = g.GitHub.UpdateFile(
-		formulaOwner,
-		repo,
-		newBranch,
-		path,
-		*rc.SHA,
-		message,
-		[]byte(newFormula),
-	)
+	if merge {
+		g.report().Message("\n\n")
+		g.report().Message("Yay! Now your %s is up-to-date!\n\n", kind)
+		g.report().Result(result)
+
+		return nil
+	}
+
+	g.report().Message("\n\n")
+	g.report().Message("Yay! Now your %s is ready to update!\n\n", kind)
+	g.report().Message("Access %s and merge the Pull Request\n\n", *pr.HTMLURL)
+	g.report().Result(result)
+
+	return nil
+}
+
+// Statuses of a formula compared with the latest release
+const (
+	StatusCurrent  = "current"
+	StatusOutdated = "outdated"
+	StatusAhead    = "ahead"
+)
+
+// FormulaStatus is a status of the formula compared with the latest release of the application
+type FormulaStatus struct {
+	Path          string `json:"path"`
+	Version       string `json:"version"`
+	URL           string `json:"url"`
+	SHA256        string `json:"sha256"`
+	LatestVersion string `json:"latest_version"`
+	Status        string `json:"status"`
+
+	// PullRequests are URLs of open Pull Requests bumping up the formula
+	PullRequests []string `json:"pull_requests"`
+}
+
+// GetStatus compares the formula in the tap with the latest release of the application without changing anything
+func (g *Ghbr) GetStatus(ctx context.Context, tap Tap, owner, app, branch string) (*FormulaStatus, error) {
+	formulaOwner, repo := tap.owner(owner), tap.name(app)
 
+	path := tap.Path
+	if len(path) == 0 {
+		var err error
+		if path, err = g.formulaPath(ctx, formulaOwner, repo, branch, app); err != nil {
+			return nil, err
+		}
+	}
+
+	rc, err := g.tapForge().GetFile(ctx, formulaOwner, repo, branch, path)
 	if err != nil {
-		// Delete branch if the update fails
-		g.GitHub.DeleteLatestRef(formulaOwner, repo, newBranch)
+		return nil, err
+	}
 
-		return err
+	content, err := decodeContent(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FormulaStatus{Path: path, PullRequests: []string{}}
+	for _, f := range []struct {
+		reg  *regexp.Regexp
+		dest *string
+	}{{versionRegex, &s.Version}, {urlRegex, &s.URL}, {shaRegex, &s.SHA256}} {
+		if ms := f.reg.FindStringSubmatch(content); ms != nil {
+			*f.dest = ms[1]
+		}
 	}
 
-	// Create a PR from the feature branch to its origin
-	fmt.Fprintf(g.outStream, "[ghbr] ===> Creating a Pull Request\n")
-	pr, err := g.GitHub.CreatePullRequest(formulaOwner, repo, message, newBranch, branch, message)
+	if len(s.Version) == 0 {
+		return nil, &HandledError{Message: fmt.Sprintf("could not find version in %s", path)}
+	}
 
+	release, err := g.releaseSource().GetLatestRelease(ctx, owner, app)
 	if err != nil {
-		// Delete the branch if PR creation fails
-		g.GitHub.DeleteLatestRef(formulaOwner, repo, newBranch)
+		return nil, err
+	}
 
-		return err
+	s.LatestVersion = release.GetTagName()
+
+	switch c := compareVersions(s.Version, s.LatestVersion); {
+	case c == 0:
+		s.Status = StatusCurrent
+	case c > 0:
+		s.Status = StatusAhead
+	default:
+		s.Status = StatusOutdated
 	}
 
-	// Merge the PR
-	if merge {
-		fmt.Fprintf(g.outStream, "[ghbr] ===> Merging the Pull Request\n")
+	prs, err := g.tapForge().ListPullRequests(ctx, formulaOwner, repo, branch)
+	if err != nil {
+		return nil, err
+	}
 
-		if err := g.GitHub.MergePullRequest(formulaOwner, repo, *pr.Number); err != nil {
-			// Delete the branch and the PR if the merge fails]
-			g.GitHub.ClosePullRequest(formulaOwner, repo, *pr.Number)
-			g.GitHub.DeleteLatestRef(formulaOwner, repo, newBranch)
+	for _, pr := range prs {
+		if isBumpPullRequest(pr, tap, app) {
+			s.PullRequests = append(s.PullRequests, pr.GetHTMLURL())
+		}
+	}
 
-			return err
+	return s, nil
+}
+
+// PrintStatus prints the status as text or json
+func (g *Ghbr) PrintStatus(s *FormulaStatus, output string) error {
+	if output == "json" {
+		return json.NewEncoder(g.outStream).Encode(s)
+	}
+
+	fmt.Fprintf(g.outStream, "Formula:        %s\n", s.Path)
+	fmt.Fprintf(g.outStream, "Version:        %s\n", s.Version)
+	fmt.Fprintf(g.outStream, "URL:            %s\n", orDash(s.URL))
+	fmt.Fprintf(g.outStream, "SHA256:         %s\n", orDash(s.SHA256))
+	fmt.Fprintf(g.outStream, "Latest Release: %s\n", s.LatestVersion)
+	fmt.Fprintf(g.outStream, "Status:         %s\n", s.Status)
+
+	if len(s.PullRequests) != 0 {
+		fmt.Fprintf(g.outStream, "Pull Requests:\n")
+		for _, pr := range s.PullRequests {
+			fmt.Fprintf(g.outStream, "  %s\n", pr)
 		}
+	}
 
-		fmt.Fprintf(g.outStream, "[ghbr] ===> Deleting the branch\n")
+	return nil
+}
 
-		if err := g.GitHub.DeleteLatestRef(formulaOwner, repo, newBranch); err != nil {
-			return err
+// isBumpPullRequest reports whether the Pull Request is opened by ghbr to bump up the formula of the app.
+// Bump branches are named after the formula in a shared tap, so that those of the other formulae are not matched
+func isBumpPullRequest(pr *github.PullRequest, tap Tap, app string) bool {
+	ref := pr.GetHead().GetRef()
+
+	switch {
+	case strings.HasPrefix(ref, fmt.Sprintf("bumps_up_%s_to_", app)):
+		return true
+	case strings.HasPrefix(ref, "bumps_up_to_"):
+		return !tap.shared()
+	case strings.HasPrefix(ref, "bumps_up_formulae_"):
+		return strings.Contains(pr.GetBody(), fmt.Sprintf("- %s: ", app))
+	default:
+		return false
+	}
+}
+
+// compareVersions compares dot separated versions numerically, ignoring the `v` prefix.
+// Missing components are regarded as 0, and non numeric ones like rc10 are compared by their alphabetic and numeric parts.
+// Pre-releases like 1.0.0-rc1 are ranked lower than the release of the same version
+func compareVersions(a, b string) int {
+	ac, ap := splitPreRelease(strings.TrimPrefix(a, "v"))
+	bc, bp := splitPreRelease(strings.TrimPrefix(b, "v"))
+
+	if c := compareVersionComponents(ac, bc); c != 0 {
+		return c
+	}
+
+	switch {
+	case ap == bp:
+		return 0
+	case len(ap) == 0:
+		return 1
+	case len(bp) == 0:
+		return -1
+	default:
+		return compareVersionComponents(ap, bp)
+	}
+}
+
+// splitPreRelease splits the version into the core and the pre-release suffix after `-`
+func splitPreRelease(version string) (core, pre string) {
+	if i := strings.Index(version, "-"); i >= 0 {
+		return version[:i], version[i+1:]
+	}
+
+	return version, ""
+}
+
+// compareVersionComponents compares dot separated components, identifier by identifier
+func compareVersionComponents(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		x, y := "0", "0"
+		if i < len(as) {
+			x = as[i]
 		}
 
-		fmt.Fprintf(g.outStream, "\n\n")
-		fmt.Fprintf(g.outStream, "Yay! Now your formula is up-to-date!\n\n")
+		if i < len(bs) {
+			y = bs[i]
+		}
 
-		return nil
+		if c := compareVersionIdentifiers(x, y); c != 0 {
+			return c
+		}
 	}
 
-	fmt.Fprintf(g.outStream, "\n\n")
-	fmt.Fprintf(g.outStream, "Yay! Now your formula is ready to update!\n\n")
-	fmt.Fprintf(g.outStream, "Access %s and merge the Pull Request\n\n", *pr.HTMLURL)
+	return 0
+}
 
-	return nil
+var versionIdentifierRegex = regexp.MustCompile(`[0-9]+|[^0-9]+`)
+
+// compareVersionIdentifiers compares identifiers like rc10 by their alphabetic and numeric parts,
+// so that rc2 ranks lower than rc10. Numeric parts rank lower than alphabetic ones
+func compareVersionIdentifiers(a, b string) int {
+	as := versionIdentifierRegex.FindAllString(a, -1)
+	bs := versionIdentifierRegex.FindAllString(b, -1)
+
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		xn, xerr := strconv.Atoi(as[i])
+		yn, yerr := strconv.Atoi(bs[i])
+
+		switch {
+		case xerr == nil && yerr == nil:
+			if xn != yn {
+				if xn < yn {
+					return -1
+				}
+
+				return 1
+			}
+		case xerr == nil:
+			return -1
+		case yerr == nil:
+			return 1
+		case as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// fileUpdate is an update of a file committed to a Pull Request
+type fileUpdate struct {
+	// label describes the file in the progress message
+	label string
+
+	path, sha, content string
+}
+
+// pullRequest creates a new branch from the base, commits the updates to it and opens a Pull Request with the title,
+// which is merged if merge is true. The branch and the Pull Request are cleaned up if any of the steps fails.
+// It refuses to start while the last operation on the repository is incomplete
+func (g *Ghbr) pullRequest(ctx context.Context, owner, repo, base, newBranch, title, body string, updates []fileUpdate, merge bool) (*github.PullRequest, error) {
+	last, err := g.loadOperation(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if last != nil {
+		return nil, &HandledError{Message: fmt.Sprintf("the last operation on %s/%s, \"%s\" started at %s, has not completed\n\n"+
+			"Please run `ghbr resume` to continue it or `ghbr abort` to roll it back first", owner, repo, last.Title, last.StartedAt.Local().Format(time.RFC3339))}
+	}
+
+	op := &operation{
+		Owner:     owner,
+		Repo:      repo,
+		Base:      base,
+		Branch:    newBranch,
+		Title:     title,
+		Body:      body,
+		Merge:     merge,
+		StartedAt: time.Now().UTC(),
+	}
+
+	for _, u := range updates {
+		op.Updates = append(op.Updates, operationUpdate{Label: u.label, Path: u.path, SHA: u.sha, Content: u.content})
+	}
+
+	return g.runOperation(ctx, op)
 }
 
 // createReadme creates a README.md on master branch
-func (g *Ghbr) createReadme(owner, formulaRepoName, originalRepo string) error {
+func (g *Ghbr) createReadme(ctx context.Context, owner, formulaRepoName, originalRepo, homepage string) error {
 
 	content := fmt.Sprintf(`%s
 ====
 
-[Homebrew](http://brew.sh/) formula for [%s](https://github.com/%s)
+[Homebrew](http://brew.sh/) formula for [%s](%s)
 
-`, formulaRepoName, originalRepo, originalRepo)
+`, formulaRepoName, originalRepo, homepage)
 
-	_, err := g.GitHub.CreateFile(
+	_, err := g.tapForge().CreateFile(ctx,
 		owner,
 		formulaRepoName,
 		"master",
@@ -275,38 +997,97 @@ func (g *Ghbr) createReadme(owner, formulaRepoName, originalRepo string) error {
 	return err
 }
 
-// createReadme creates a README.md on master branch
-func (g *Ghbr) createFormula(owner, app, repo, originalRepo, font string, release *LatestRelease) error {
+// buildFormula builds a formula of the application pointing to the release
+func (g *Ghbr) buildFormula(ctx context.Context, owner, app string, opts FormulaOptions, release *LatestRelease) (*formula, error) {
+	f := &formula{
+		ClassName:    strcase.ToCamel(app),
+		OriginalRepo: fmt.Sprintf("%s/%s", owner, app),
+		Homepage:     g.Forge.RepositoryURL(owner, app),
+		Version:      release.version,
+		URL:          release.url,
+		Hash:         release.hash,
+		Caveats:      opts.Caveats,
+		Livecheck:    opts.Livecheck,
+		Install:      installLines(app, release.files),
+	}
+
+	// The release may be found on another forge or via --release-url, where the latest release of GitHub is not checked
+	f.LivecheckStrategy = livecheckStrategy(f.URL)
+
+	// Build the application from source instead of installing a prebuilt binary
+	if len(release.language) != 0 {
+		l, ok := findSourceLanguage(release.language)
+		if !ok {
+			return nil, errors.Errorf("unknown source language: %s", release.language)
+		}
+
+		f.DependsOn = append([]string{}, l.dependsOn...)
+		f.Install = l.install
+	}
+
+	for _, d := range opts.Dependencies {
+		f.DependsOn = append(f.DependsOn, dependsOn(d))
+	}
 
-	caveats := figure.NewFigure(app, font, true)
+	for _, r := range opts.Resources {
+		fr, err := g.getLatestResource(ctx, r)
+		if err != nil {
+			return nil, err
+		}
 
-	content := fmt.Sprintf(`require 'formula'
+		f.Resources = append(f.Resources, *fr)
+	}
 
-class %s < Formula
-  homepage 'https://github.com/%s'
-  version '%s'
+	if !opts.Head {
+		return f, nil
+	}
 
-  url '%s'
-  sha256 '%s'
+	// The repository of the application is not necessarily on the forge when the release is found elsewhere
+	if g.Source != nil {
+		g.logger.Verbosef("omitting head since the latest release is not found on the forge")
+		return f, nil
+	}
+
+	// Point head to the default branch of the repository
+	g.report().Step("check_default_branch", "Checking the default branch of the repository")
+	repo, err := g.Forge.GetRepository(ctx, owner, app)
+	if err != nil {
+		return nil, err
+	}
+
+	f.Head = &formulaHead{URL: f.Homepage + ".git", Branch: repo.GetDefaultBranch()}
+
+	if len(release.language) != 0 {
+		return f, nil
+	}
+
+	// A prebuilt binary cannot be installed from the head, so build it from source only for the head
+	// The head is optional, so the formula is created without it rather than failing
+	language, err := g.detectLanguage(ctx, owner, app, f.Head.Branch)
+	if _, ok := err.(*HandledError); ok {
+		g.report().Warn("Omitting head since the language of %s/%s could not be detected to build it from source", owner, app)
+		f.Head = nil
+		return f, nil
+	}
 
-  def install
-    bin.install '%s'
-  end
+	if err != nil {
+		return nil, err
+	}
 
-  def caveats
-    <<-'EOF'
-%s
-EOF
-  end
-end
+	l, _ := findSourceLanguage(language)
+	f.Head.DependsOn = l.dependsOn
+	f.Head.Install = l.install
 
-`, strcase.ToCamel(app), originalRepo, release.version, release.url, release.hash, app, caveats.String())
+	return f, nil
+}
 
-	_, err := g.GitHub.CreateFile(
+// createFormula creates a formula file with the content at the path on the branch
+func (g *Ghbr) createFormula(ctx context.Context, owner, repo, branch, path, content string) error {
+	_, err := g.tapForge().CreateFile(ctx,
 		owner,
 		repo,
-		"master",
-		fmt.Sprintf("%s.rb", app),
+		branch,
+		path,
 		"Create formula",
 		[]byte(content),
 	)
@@ -314,22 +1095,120 @@ end
 	return err
 }
 
+// createCask creates a cask file at the path on the branch
+func (g *Ghbr) createCask(ctx context.Context, owner, app, repo, branch, path, homepage, description, template string, release *LatestRelease) error {
+	c := cask{
+		Token:    caskToken(app),
+		Version:  release.version,
+		Hash:     release.hash,
+		URL:      release.url,
+		Name:     app,
+		Desc:     strings.Replace(description, `"`, `\"`, -1),
+		Homepage: homepage,
+		Artifact: caskArtifact(app, release.url),
+	}
+
+	content, err := c.render(template)
+	if err != nil {
+		return err
+	}
+
+	_, err = g.tapForge().CreateFile(ctx,
+		owner,
+		repo,
+		branch,
+		path,
+		"Create cask",
+		[]byte(content),
+	)
+
+	return err
+}
+
 // downloadFile downloads a file from the url and return the content
-func (g *Ghbr) downloadFile(url string) (io.ReadCloser, error) {
+func (g *Ghbr) downloadFile(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get the data
-	res, err := http.Get(url)
+	client := &http.Client{Transport: newRetryTransport(newLoggingTransport(http.DefaultTransport, g.logger), g.logger)}
+	res, err := client.Do(req.WithContext(ctx))
 
 	if err != nil {
 		return nil, err
 	}
 
 	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
 		return nil, errors.Errorf("#downloadFile returns invalid http status: %s", res.Status)
 	}
 
 	return res.Body, nil
 }
 
+// getLatestResource returns the resource pointing to the latest release of its repository
+func (g *Ghbr) getLatestResource(ctx context.Context, r Resource) (*formulaResource, error) {
+	g.report().Step("check_resource", "Checking the latest release of resource %s", r.Name)
+	ownerRepo := strings.Split(r.Repository, "/")
+	release, err := g.Forge.GetLatestRelease(ctx, ownerRepo[0], ownerRepo[1])
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := findAssetURL(release, r.Asset)
+	if err != nil {
+		return nil, err
+	}
+
+	g.report().Step("download_resource", "Downloading resource %s", r.Name)
+	body, err := g.downloadFile(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	defer body.Close()
+
+	hash, err := calculateSha256(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &formulaResource{Name: r.Name, URL: url, Hash: hash}, nil
+}
+
+// detectLanguage detects the language of the repository at the ref by looking for its manifest file
+func (g *Ghbr) detectLanguage(ctx context.Context, owner, repo, ref string) (string, error) {
+	for _, l := range sourceLanguages {
+		_, err := g.Forge.GetFile(ctx, owner, repo, ref, l.manifest)
+
+		if err == nil {
+			return l.name, nil
+		}
+
+		if !isNotFound(err) {
+			return "", err
+		}
+	}
+
+	return "", &HandledError{
+		Message: "Could not detect the language of the repository to build it from source.\n" +
+			"ghbr looks for go.mod, Cargo.toml or package.json at the root of the repository.",
+	}
+}
+
+// findAssetURL returns a URL of a released asset whose name matches the glob pattern
+func findAssetURL(release *github.RepositoryRelease, pattern string) (string, error) {
+	for _, a := range release.Assets {
+		if ok, _ := path.Match(pattern, *a.Name); ok {
+			return *a.BrowserDownloadURL, nil
+		}
+	}
+
+	return "", &HandledError{Message: fmt.Sprintf("No released asset of %s matches %q", *release.TagName, pattern)}
+}
+
 func findMacAssetURL(release *github.RepositoryRelease) (string, error) {
 	for _, a := range release.Assets {
 		if strings.Contains(*a.Name, "darwin") && strings.Contains(*a.Name, "amd64") {
@@ -401,14 +1280,13 @@ func bumpsUpFormula(content string, release *LatestRelease) (string, error) {
 	return c, nil
 }
 
+// findAndReplace replaces the sub match of the first match of the regex, leaving the rest of the content untouched
 func findAndReplace(reg *regexp.Regexp, content, new string) (string, error) {
-	ms := reg.FindStringSubmatch(content)
+	loc := reg.FindStringSubmatchIndex(content)
 
-	if ms == nil {
+	if loc == nil {
 		return "", &HandledError{Message: fmt.Sprintf("could not find sub match: content: %s, regex: %v", content, reg)}
 	}
 
-	old := ms[1]
-
-	return strings.Replace(content, old, new, -1), nil
+	return content[:loc[2]] + new + content[loc[3]:], nil
 }
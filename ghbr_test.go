@@ -2,14 +2,21 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
 )
 
 func TestGhbr_GetLatestRelease_Success(t *testing.T) {
@@ -17,7 +24,7 @@ func TestGhbr_GetLatestRelease_Success(t *testing.T) {
 	defer tearDown()
 
 	outStream := new(bytes.Buffer)
-	ghbr := Ghbr{GitHub: client, outStream: outStream}
+	ghbr := Ghbr{Forge: client, outStream: outStream}
 
 	assetPath := fmt.Sprintf("/%s/%s/releases/download/v0.0.1/ghbr_v0.0.1_darwin_amd64.zip", TestOwner, TestRepo)
 	assetURL := fmt.Sprintf("%s/%s", client.Client.BaseURL, assetPath)
@@ -32,7 +39,7 @@ func TestGhbr_GetLatestRelease_Success(t *testing.T) {
 		fmt.Fprintf(w, "test")
 	})
 
-	got, err := ghbr.GetLatestRelease(TestOwner, TestRepo)
+	got, err := ghbr.GetLatestRelease(context.Background(), TestOwner, TestRepo, "", false)
 	if err != nil {
 		t.Fatalf("#GetLatestRelease returns unexpected error: %s", err)
 	}
@@ -51,29 +58,194 @@ func TestGhbr_GetLatestRelease_Success(t *testing.T) {
 	}
 }
 
+func TestGhbr_GetLatestRelease_Asset(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{Forge: client, outStream: outStream}
+
+	assetPath := fmt.Sprintf("/%s/%s/releases/download/v0.0.1/ghbr_v0.0.1_darwin_arm64.tar.gz", TestOwner, TestRepo)
+	assetURL := fmt.Sprintf("%s/%s", client.Client.BaseURL, assetPath)
+
+	// Mock GetLatestRelease request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/releases/latest", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"id":1,"name":"Release v0.0.1","tag_name":"v0.0.1","assets":[{"name":"ghbr_v0.0.1_darwin_amd64.zip", "browser_download_url":"https://example.com/ghbr_v0.0.1_darwin_amd64.zip"},{"name":"ghbr_v0.0.1_darwin_arm64.tar.gz", "browser_download_url":"%s"}]}`, assetURL)
+	})
+
+	// Mock downloadFile request
+	mux.HandleFunc(assetPath, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "test")
+	})
+
+	got, err := ghbr.GetLatestRelease(context.Background(), TestOwner, TestRepo, "*_darwin_arm64.tar.gz", false)
+	if err != nil {
+		t.Fatalf("#GetLatestRelease returns unexpected error: %s", err)
+	}
+
+	expectedRelease := &LatestRelease{version: "v0.0.1", url: assetURL, hash: fmt.Sprintf("%x", sha256.Sum256([]byte("test")))}
+	if !reflect.DeepEqual(got, expectedRelease) {
+		t.Errorf("#GetLatestRelease returned %+v, want %+v", got, expectedRelease)
+	}
+
+	expectedOutput := "[ghbr] ===> Checking the latest release\n" +
+		"[ghbr] ===> Downloading ghbr_v0.0.1_darwin_arm64.tar.gz\n" +
+		"[ghbr] ===> Calculating a checksum of the release\n"
+
+	if got := outStream.String(); got != expectedOutput {
+		t.Errorf("#GetLatestRelease outputed %+v, want %+v", got, expectedOutput)
+	}
+
+	if _, err := ghbr.GetLatestRelease(context.Background(), TestOwner, TestRepo, "*.deb", false); err == nil {
+		t.Errorf("#GetLatestRelease did not return error for an asset pattern matching nothing")
+	}
+}
+
+func TestGhbr_GetLatestRelease_Inspect(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{Forge: client, outStream: outStream}
+
+	assetPath := fmt.Sprintf("/%s/%s/releases/download/v0.0.1/ghbr_v0.0.1_darwin_amd64.zip", TestOwner, TestRepo)
+	assetURL := fmt.Sprintf("%s/%s", client.Client.BaseURL, assetPath)
+	archive := testZip(t, map[string]bool{"ghbr_v0.0.1_darwin_amd64/ghbr": true, "ghbr_v0.0.1_darwin_amd64/README.md": false})
+
+	// Mock GetLatestRelease request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/releases/latest", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"id":1,"name":"Release v0.0.1","tag_name":"v0.0.1","assets":[{"name":"ghbr_v0.0.1_darwin_amd64.zip", "browser_download_url":"%s"}]}`, assetURL)
+	})
+
+	// Mock downloadFile request
+	mux.HandleFunc(assetPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	})
+
+	got, err := ghbr.GetLatestRelease(context.Background(), TestOwner, TestRepo, "", true)
+	if err != nil {
+		t.Fatalf("#GetLatestRelease returns unexpected error: %s", err)
+	}
+
+	expectedRelease := &LatestRelease{
+		version: "v0.0.1",
+		url:     assetURL,
+		hash:    fmt.Sprintf("%x", sha256.Sum256(archive)),
+		files:   []archiveFile{{name: "README.md"}, {name: "ghbr", executable: true}},
+	}
+	if !reflect.DeepEqual(got, expectedRelease) {
+		t.Errorf("#GetLatestRelease returned %+v, want %+v", got, expectedRelease)
+	}
+
+	expectedOutput := "[ghbr] ===> Checking the latest release\n" +
+		"[ghbr] ===> Downloading Darwin AMD64 release\n" +
+		"[ghbr] ===> Calculating a checksum of the release\n" +
+		"[ghbr] ===> Inspecting the release archive\n"
+
+	if got := outStream.String(); got != expectedOutput {
+		t.Errorf("#GetLatestRelease outputed %+v, want %+v", got, expectedOutput)
+	}
+}
+
 func TestGhbr_GetLatestRelease_Fail(t *testing.T) {
 	client, mux, _, tearDown := setup()
 	defer tearDown()
 
-	ghbr := Ghbr{GitHub: client, outStream: ioutil.Discard}
+	ghbr := Ghbr{Forge: client, outStream: ioutil.Discard}
 
 	// Mock GetLatestRelease request
 	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/releases/latest", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, `{"id":1,"name":"Release v0.0.1","tag_name":"v0.0.1","assets":[{"name":"ghbr_v0.0.1_darwin_386.zip"}]}`)
 	})
 
-	_, err := ghbr.GetLatestRelease(TestOwner, TestRepo)
+	_, err := ghbr.GetLatestRelease(context.Background(), TestOwner, TestRepo, "", false)
 	if _, ok := err.(*HandledError); !ok {
 		t.Fatalf("#GetLatestRelease returns invalid error: %s", err)
 	}
 }
 
+func TestGhbr_GetLatestSourceRelease_Success(t *testing.T) {
+	client, mux, serverURL, tearDown := setup()
+	defer tearDown()
+
+	// The test server stands in for the web of GitHub as well to serve the archive
+	client.webURL = serverURL
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{Forge: client, outStream: outStream}
+
+	tarballPath := fmt.Sprintf("/%s/%s/archive/refs/tags/v0.0.1.tar.gz", TestOwner, TestRepo)
+	tarballURL := serverURL + tarballPath
+
+	// Mock GetLatestRelease request, whose tarball_url is not used
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/releases/latest", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"id":1,"name":"Release v0.0.1","tag_name":"v0.0.1","tarball_url":"%s/repos/%s/%s/tarball/v0.0.1"}`, serverURL, TestOwner, TestRepo)
+	})
+
+	// Mock GetFile requests to detect the language
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/contents/go.mod", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		testFormValues(t, r, values{"ref": "v0.0.1"})
+		http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+	})
+
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/contents/Cargo.toml", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		testFormValues(t, r, values{"ref": "v0.0.1"})
+		fmt.Fprint(w, `{"path":"Cargo.toml","encoding":"base64","content":""}`)
+	})
+
+	// Mock downloadFile request
+	mux.HandleFunc(tarballPath, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "test")
+	})
+
+	got, err := ghbr.GetLatestSourceRelease(context.Background(), TestOwner, TestRepo)
+	if err != nil {
+		t.Fatalf("#GetLatestSourceRelease returns unexpected error: %s", err)
+	}
+
+	expectedRelease := &LatestRelease{version: "v0.0.1", url: tarballURL, hash: fmt.Sprintf("%x", sha256.Sum256([]byte("test"))), language: "rust"}
+	if !reflect.DeepEqual(got, expectedRelease) {
+		t.Errorf("#GetLatestSourceRelease returned %+v, want %+v", got, expectedRelease)
+	}
+
+	expectedOutput := "[ghbr] ===> Checking the latest release\n" +
+		"[ghbr] ===> Detecting the language of the source\n" +
+		"[ghbr] ===> Downloading the source tarball\n" +
+		"[ghbr] ===> Calculating a checksum of the release\n"
+
+	if got := outStream.String(); got != expectedOutput {
+		t.Errorf("#GetLatestSourceRelease outputed %+v, want %+v", got, expectedOutput)
+	}
+}
+
+func TestGhbr_GetLatestSourceRelease_UnknownLanguage(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	ghbr := Ghbr{Forge: client, outStream: ioutil.Discard}
+
+	// Mock GetLatestRelease request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/releases/latest", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":1,"name":"Release v0.0.1","tag_name":"v0.0.1","tarball_url":"https://api.github.com/repos/shuheiktgw/ghbr/tarball/v0.0.1"}`)
+	})
+
+	// Mock GetFile requests to detect the language
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/contents/", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+	})
+
+	_, err := ghbr.GetLatestSourceRelease(context.Background(), TestOwner, TestRepo)
+	if _, ok := err.(*HandledError); !ok {
+		t.Fatalf("#GetLatestSourceRelease returns invalid error: %s", err)
+	}
+}
+
 func TestGhbr_CreateFormula_WithoutOrg(t *testing.T) {
 	client, mux, _, tearDown := setup()
 	defer tearDown()
 
 	outStream := new(bytes.Buffer)
-	ghbr := Ghbr{GitHub: client, outStream: outStream}
+	ghbr := Ghbr{Forge: client, outStream: outStream}
 
 	// Mock CreateRepository request
 	mux.HandleFunc("/user/repos", func(w http.ResponseWriter, r *http.Request) {
@@ -94,11 +266,11 @@ func TestGhbr_CreateFormula_WithoutOrg(t *testing.T) {
 
 	release := LatestRelease{
 		version: "v0.0.1",
-		url:     "httos://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testAoo_v0.0.1_darwin_amd64.zip",
-		hash:    "abcdefg",
+		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip",
+		hash:    "0001123456789012345678901234567890123456789012345678901234567890",
 	}
 
-	err := ghbr.CreateFormula("", TestOwner, "testApp", "alphabet", false, &release)
+	err := ghbr.CreateFormula(context.Background(), Tap{}, TestOwner, "testApp", false, FormulaOptions{}, &release)
 	if err != nil {
 		t.Fatalf("#CreateFormula returns unexpected error: %s", err)
 	}
@@ -115,60 +287,673 @@ func TestGhbr_CreateFormula_WithoutOrg(t *testing.T) {
 	}
 }
 
-func TestGhbr_CreateFormula_WithOrg(t *testing.T) {
+func TestGhbr_CreateFormula_RollsBack(t *testing.T) {
+	cases := []struct {
+		deleteStatus int
+		want         string
+	}{
+		{deleteStatus: http.StatusNoContent, want: "#Repositories.CreateFile failed"},
+		{deleteStatus: http.StatusForbidden, want: "failed to delete the repository shuheiktgw/homebrew-testApp"},
+	}
+
+	for i, tc := range cases {
+		client, mux, _, tearDown := setup()
+
+		outStream := new(bytes.Buffer)
+		ghbr := Ghbr{Forge: client, outStream: outStream}
+
+		// Mock CreateRepository request
+		mux.HandleFunc("/user/repos", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"html_url":"https://github.com/shuheiktgw/homebrew-testApp"}`)
+		})
+
+		// Mock GetRepository and DeleteRepository request
+		var deleted bool
+		mux.HandleFunc(fmt.Sprintf("/repos/%s/%s", TestOwner, "homebrew-testApp"), func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+				return
+			}
+
+			testMethod(t, r, http.MethodDelete)
+			deleted = true
+			w.WriteHeader(tc.deleteStatus)
+		})
+
+		// Mock CreateFile request for README.md
+		mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/contents/%s", TestOwner, "homebrew-testApp", "README.md"), func(w http.ResponseWriter, r *http.Request) {
+			testMethod(t, r, http.MethodPut)
+		})
+
+		// CreateFile request for formula file fails
+		mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/contents/%s", TestOwner, "homebrew-testApp", "testApp.rb"), func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `{"message":"Invalid request"}`, http.StatusUnprocessableEntity)
+		})
+
+		release := LatestRelease{
+			version: "v0.0.1",
+			url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip",
+			hash:    "0001123456789012345678901234567890123456789012345678901234567890",
+		}
+
+		err := ghbr.CreateFormula(context.Background(), Tap{}, TestOwner, "testApp", false, FormulaOptions{}, &release)
+		tearDown()
+
+		if err == nil {
+			t.Fatalf("#%d CreateFormula returned nil error", i)
+		}
+
+		if !deleted {
+			t.Errorf("#%d CreateFormula did not delete the created repository", i)
+		}
+
+		if !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("#%d CreateFormula returned %q, want it to contain %q", i, err, tc.want)
+		}
+	}
+}
+
+func TestGhbr_CreateFormula_WithOrg(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{Forge: client, outStream: outStream}
+	org := "TestOrg"
+
+	// Mock GetLogin request
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"login":"shuheiktgw"}`)
+	})
+
+	// Mock CreateRepository request
+	mux.HandleFunc("/orgs/TestOrg/repos", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		testBody(t, r, fmt.Sprintf(`{"name":"homebrew-testApp","description":"Homebrew formula for shuheiktgw/testApp","homepage":"https://github.com/shuheiktgw/testApp","private":false}`+"\n"))
+		fmt.Fprintf(w, `{"html_url":"https://github.com/TestOrg/homebrew-testApp"}`)
+	})
+
+	// Mock CreateFile request for README.md
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/contents/%s", org, "homebrew-testApp", "README.md"), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPut)
+	})
+
+	// Mock CreateFile request for formula file
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/contents/%s", org, "homebrew-testApp", "testApp.rb"), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPut)
+	})
+
+	release := LatestRelease{
+		version: "v0.0.1",
+		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip",
+		hash:    "0001123456789012345678901234567890123456789012345678901234567890",
+	}
+
+	err := ghbr.CreateFormula(context.Background(), Tap{Owner: org}, TestOwner, "testApp", false, FormulaOptions{}, &release)
+	if err != nil {
+		t.Fatalf("#CreateFormula returns unexpected error: %s", err)
+	}
+
+	expectedOutput := "[ghbr] ===> Creating a repository\n" +
+		"[ghbr] ===> Adding README.md to the repository\n" +
+		"[ghbr] ===> Adding testApp.rb to the repository\n" +
+		"\n\n" +
+		"Yay! Your Homebrew formula repository has been successfully created!\n" +
+		"Access https://github.com/TestOrg/homebrew-testApp and see what we achieved.\n\n"
+
+	if got := outStream.String(); got != expectedOutput {
+		t.Errorf("#CreateFormula outputed %+v, want %+v", got, expectedOutput)
+	}
+}
+
+func TestGhbr_CreateFormula_ExistingTap(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{Forge: client, outStream: outStream}
+
+	// Mock GetRepository request
+	mux.HandleFunc("/repos/TestOrg/homebrew-tools", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"name":"homebrew-tools","default_branch":"main","html_url":"https://github.com/TestOrg/homebrew-tools"}`)
+	})
+
+	// Mock GetDirectory request
+	mux.HandleFunc("/repos/TestOrg/homebrew-tools/contents/Formula", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		testFormValues(t, r, values{"ref": "main"})
+		fmt.Fprint(w, `[{"type":"file","name":"otherApp.rb","path":"Formula/otherApp.rb"}]`)
+	})
+
+	// Mock CreateFile request for formula file
+	mux.HandleFunc("/repos/TestOrg/homebrew-tools/contents/Formula/testApp.rb", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPut)
+
+		var opt struct {
+			Branch string `json:"branch"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&opt); err != nil || opt.Branch != "main" {
+			t.Errorf("#CreateFormula created the formula on %q, want main", opt.Branch)
+		}
+	})
+
+	release := LatestRelease{
+		version: "v0.0.1",
+		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip",
+		hash:    "0001123456789012345678901234567890123456789012345678901234567890",
+	}
+
+	err := ghbr.CreateFormula(context.Background(), Tap{Owner: "TestOrg", Name: "homebrew-tools"}, TestOwner, "testApp", false, FormulaOptions{}, &release)
+	if err != nil {
+		t.Fatalf("#CreateFormula returns unexpected error: %s", err)
+	}
+
+	expectedOutput := "[ghbr] ===> Using the existing repository TestOrg/homebrew-tools\n" +
+		"[ghbr] ===> Adding Formula/testApp.rb to the repository\n" +
+		"\n\n" +
+		"Yay! Your Homebrew formula has been successfully added to TestOrg/homebrew-tools!\n" +
+		"Access https://github.com/TestOrg/homebrew-tools and see what we achieved.\n\n"
+
+	if got := outStream.String(); got != expectedOutput {
+		t.Errorf("#CreateFormula outputed %+v, want %+v", got, expectedOutput)
+	}
+}
+
+func TestGhbr_CreateFormula_FromSource(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	ghbr := Ghbr{Forge: client, outStream: ioutil.Discard}
+
+	// Mock CreateRepository request
+	mux.HandleFunc("/user/repos", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"html_url":"https://github.com/shuheiktgw/homebrew-testApp"}`)
+	})
+
+	// Mock CreateFile request for README.md
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/contents/%s", TestOwner, "homebrew-testApp", "README.md"), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPut)
+	})
+
+	// Mock CreateFile request for formula file
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/contents/%s", TestOwner, "homebrew-testApp", "testApp.rb"), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPut)
+
+		content := testFileContent(t, r)
+		for _, want := range []string{
+			"url 'https://github.com/shuheiktgw/testApp/archive/refs/tags/v0.0.1.tar.gz'",
+			"depends_on 'go' => :build",
+			"system 'go', 'build', *std_go_args(ldflags: '-s -w')",
+		} {
+			if !strings.Contains(content, want) {
+				t.Errorf("formula file does not contain %q: %s", want, content)
+			}
+		}
+	})
+
+	release := LatestRelease{
+		version:  "v0.0.1",
+		url:      "https://github.com/shuheiktgw/testApp/archive/refs/tags/v0.0.1.tar.gz",
+		hash:     "0001123456789012345678901234567890123456789012345678901234567890",
+		language: "go",
+	}
+
+	if err := ghbr.CreateFormula(context.Background(), Tap{}, TestOwner, "testApp", false, FormulaOptions{}, &release); err != nil {
+		t.Fatalf("#CreateFormula returns unexpected error: %s", err)
+	}
+}
+
+func TestGhbr_CreateFormula_HeadUnknownLanguage(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{Forge: client, outStream: outStream}
+
+	// Mock GetRepository requests
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s", TestOwner, "testApp"), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name":"testApp","default_branch":"main"}`)
+	})
+
+	mux.HandleFunc("/repos/TestOrg/homebrew-tools", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name":"homebrew-tools","default_branch":"main","html_url":"https://github.com/TestOrg/homebrew-tools"}`)
+	})
+
+	// Mock GetFile requests to detect the language, none of the manifests exists
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/contents/", TestOwner, "testApp"), func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+	})
+
+	// Mock CreateFile request for formula file
+	mux.HandleFunc("/repos/TestOrg/homebrew-tools/contents/testApp.rb", func(w http.ResponseWriter, r *http.Request) {
+		if content := testFileContent(t, r); strings.Contains(content, "head") {
+			t.Errorf("formula file contains head: %s", content)
+		}
+	})
+
+	release := LatestRelease{
+		version: "v0.0.1",
+		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip",
+		hash:    "0001123456789012345678901234567890123456789012345678901234567890",
+	}
+
+	tap := Tap{Owner: "TestOrg", Name: "homebrew-tools", Path: "testApp.rb"}
+	if err := ghbr.CreateFormula(context.Background(), tap, TestOwner, "testApp", false, FormulaOptions{Head: true}, &release); err != nil {
+		t.Fatalf("#CreateFormula returns unexpected error: %s", err)
+	}
+
+	if want := "[ghbr] ===> Omitting head since the language of shuheiktgw/testApp could not be detected to build it from source\n"; !strings.Contains(outStream.String(), want) {
+		t.Errorf("#CreateFormula outputed %+v, want %+v", outStream.String(), want)
+	}
+}
+
+func TestGhbr_CreateCask(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{Forge: client, outStream: outStream}
+
+	// Mock GetRepository request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s", TestOwner, "testApp"), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"name":"testApp","description":"A \"test\" app"}`)
+	})
+
+	// Mock CreateRepository request
+	mux.HandleFunc("/user/repos", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		fmt.Fprintf(w, `{"html_url":"https://github.com/shuheiktgw/homebrew-testApp"}`)
+	})
+
+	// Mock CreateFile request for README.md
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/contents/%s", TestOwner, "homebrew-testApp", "README.md"), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPut)
+	})
+
+	// Mock CreateFile request for cask file
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/contents/%s", TestOwner, "homebrew-testApp", "Casks/test-app.rb"), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPut)
+
+		want := `cask "test-app" do
+  version "0.0.1"
+  sha256 "0001123456789012345678901234567890123456789012345678901234567890"
+
+  url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp.dmg"
+  name "testApp"
+  desc "A \"test\" app"
+  homepage "https://github.com/shuheiktgw/testApp"
+
+  app "testApp.app"
+end
+`
+		if got := testFileContent(t, r); got != want {
+			t.Errorf("cask file is %s, want %s", got, want)
+		}
+	})
+
+	release := LatestRelease{
+		version: "0.0.1",
+		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp.dmg",
+		hash:    "0001123456789012345678901234567890123456789012345678901234567890",
+	}
+
+	if err := ghbr.CreateCask(context.Background(), Tap{}, TestOwner, "testApp", false, "", &release); err != nil {
+		t.Fatalf("#CreateCask returns unexpected error: %s", err)
+	}
+
+	expectedOutput := "[ghbr] ===> Creating a repository\n" +
+		"[ghbr] ===> Adding README.md to the repository\n" +
+		"[ghbr] ===> Adding Casks/test-app.rb to the repository\n" +
+		"\n\n" +
+		"Yay! Your Homebrew cask repository has been successfully created!\n" +
+		"Access https://github.com/shuheiktgw/homebrew-testApp and see what we achieved.\n\n"
+
+	if got := outStream.String(); got != expectedOutput {
+		t.Errorf("#CreateCask outputed %+v, want %+v", got, expectedOutput)
+	}
+}
+
+func TestGhbr_UpdateFormulaWithMerge(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{Forge: client, outStream: outStream}
+
+	content := base64.StdEncoding.EncodeToString([]byte(`
+version "v0.0.1"
+url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip"
+sha256 "0001123456789012345678901234567890123456789012345678901234567890"
+`))
+
+	expectedContent, _ := json.Marshal([]byte(`
+version "v0.0.2"
+url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp_v0.0.2_darwin_amd64.zip"
+sha256 "0002123456789012345678901234567890123456789012345678901234567890"
+`))
+
+	// Mock GetFile and UpdateFile request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/contents/testApp.rb", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprintf(w, `{"path":"testApp.rb","sha":"formulaV0.0.1","encoding":"base64","content":"%s"}`, content)
+		case http.MethodPut:
+			testBody(t, r, fmt.Sprintf(`{"message":"Bumps up to v0.0.2","content":%s,"sha":"formulaV0.0.1","branch":"bumps_up_to_v0.0.2"}`+"\n", expectedContent))
+		}
+	})
+
+	release := LatestRelease{
+		version: "v0.0.2",
+		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp_v0.0.2_darwin_amd64.zip",
+		hash:    "0002123456789012345678901234567890123456789012345678901234567890",
+	}
+
+	// Mock CreateBranch request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/git/refs/%s", TestOwner, "homebrew-testApp", "heads/master"), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprintf(w, `{"object":{"sha":"abcdefg"}}`)
+	})
+
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/git/refs", TestOwner, "homebrew-testApp"), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		testBody(t, r, fmt.Sprintf(`{"ref":"refs/heads/bumps_up_to_v0.0.2","sha":"abcdefg"}`+"\n"))
+		fmt.Fprintf(w, `{"object":{"sha":"abcdefg"}}`)
+	})
+
+	// Mock CreatePullRequest request
+	mux.HandleFunc(fmt.Sprintf("/repos/%v/%v/pulls", TestOwner, "homebrew-testApp"), func(w http.ResponseWriter, r *http.Request) {
+		testBody(t, r, fmt.Sprintf(`{"title":"%s","head":"%s","base":"%s","body":"%s"}`+"\n", "Bumps up to v0.0.2", "bumps_up_to_v0.0.2", "master", "Bumps up to v0.0.2"))
+		testMethod(t, r, http.MethodPost)
+		fmt.Fprintf(w, `{"number":100}`)
+	})
+
+	// Mock MergePullRequest request
+	mux.HandleFunc(fmt.Sprintf("/repos/%v/%v/pulls/%d/merge", TestOwner, "homebrew-testApp", 100), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPut)
+		fmt.Fprint(w, `{"sha":"abcdefg","merged":true}`)
+	})
+
+	// Mock DeleteLatestRef request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/git/refs/%s", TestOwner, "homebrew-testApp", "heads/bumps_up_to_v0.0.2"), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodDelete)
+	})
+
+	err := ghbr.UpdateFormula(context.Background(), Tap{}, TestOwner, "testApp", "master", false, true, FormulaOptions{}, &release)
+
+	if err != nil {
+		t.Fatalf("#UpdateFormula returns unexpected error: %s", err)
+	}
+
+	expectedOutput := "[ghbr] ===> Checking the current formula\n" +
+		"[ghbr] ===> Creating a new feature branch\n" +
+		"[ghbr] ===> Updating the formula file\n" +
+		"[ghbr] ===> Creating a Pull Request\n" +
+		"[ghbr] ===> Merging the Pull Request\n" +
+		"[ghbr] ===> Deleting the branch\n" +
+		"\n\n" +
+		"Yay! Now your formula is up-to-date!\n\n"
+
+	if got := outStream.String(); got != expectedOutput {
+		t.Errorf("#UpdateFormula outputed %+v, want %+v", got, expectedOutput)
+	}
+}
+
+func TestGhbr_UpdateFormula_Canceled(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{Forge: client, outStream: outStream}
+
+	content := base64.StdEncoding.EncodeToString([]byte(`
+version "v0.0.1"
+url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip"
+sha256 "0001123456789012345678901234567890123456789012345678901234567890"
+`))
+
+	// Mock GetFile and UpdateFile request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/contents/testApp.rb", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			fmt.Fprintf(w, `{"path":"testApp.rb","sha":"formulaV0.0.1","encoding":"base64","content":"%s"}`, content)
+		}
+	})
+
+	release := LatestRelease{
+		version: "v0.0.2",
+		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp_v0.0.2_darwin_amd64.zip",
+		hash:    "0002123456789012345678901234567890123456789012345678901234567890",
+	}
+
+	// Mock CreateBranch request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/git/refs/%s", TestOwner, "homebrew-testApp", "heads/master"), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"object":{"sha":"abcdefg"}}`)
+	})
+
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/git/refs", TestOwner, "homebrew-testApp"), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"object":{"sha":"abcdefg"}}`)
+	})
+
+	// Mock CreatePullRequest request
+	mux.HandleFunc(fmt.Sprintf("/repos/%v/%v/pulls", TestOwner, "homebrew-testApp"), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"number":100}`)
+	})
+
+	// MergePullRequest must not be sent since the context is canceled while waiting for the Pull Request to be mergeable
+	mux.HandleFunc(fmt.Sprintf("/repos/%v/%v/pulls/%d/merge", TestOwner, "homebrew-testApp", 100), func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("#UpdateFormula merged the Pull Request after the context is canceled")
+	})
+
+	// Mock ClosePullRequest request
+	var closed bool
+	mux.HandleFunc(fmt.Sprintf("/repos/%v/%v/pulls/%d", TestOwner, "homebrew-testApp", 100), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPatch)
+		testBody(t, r, `{"state":"closed"}`+"\n")
+		closed = true
+		fmt.Fprintf(w, `{"number":100}`)
+	})
+
+	// Mock DeleteLatestRef request
+	var deleted bool
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/git/refs/%s", TestOwner, "homebrew-testApp", "heads/bumps_up_to_v0.0.2"), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodDelete)
+		deleted = true
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := ghbr.UpdateFormula(ctx, Tap{}, TestOwner, "testApp", "master", false, true, FormulaOptions{}, &release)
+
+	if errors.Cause(err) != context.DeadlineExceeded {
+		t.Fatalf("#UpdateFormula returned %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if !closed || !deleted {
+		t.Errorf("#UpdateFormula did not roll back on cancellation: closed the Pull Request: %t, deleted the branch: %t", closed, deleted)
+	}
+}
+
+func TestGhbr_UpdateFormulaWithoutMerge(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{Forge: client, outStream: outStream}
+
+	content := base64.StdEncoding.EncodeToString([]byte(`
+version "v0.0.1"
+url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip"
+sha256 "0001123456789012345678901234567890123456789012345678901234567890"
+`))
+
+	expectedContent, _ := json.Marshal([]byte(`
+version "v0.0.2"
+url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp_v0.0.2_darwin_amd64.zip"
+sha256 "0002123456789012345678901234567890123456789012345678901234567890"
+`))
+
+	// Mock GetFile and UpdateFile request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/contents/testApp.rb", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprintf(w, `{"path":"testApp.rb","sha":"formulaV0.0.1","encoding":"base64","content":"%s"}`, content)
+		case http.MethodPut:
+			testBody(t, r, fmt.Sprintf(`{"message":"Bumps up to v0.0.2","content":%s,"sha":"formulaV0.0.1","branch":"bumps_up_to_v0.0.2"}`+"\n", expectedContent))
+		}
+	})
+
+	release := LatestRelease{
+		version: "v0.0.2",
+		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp_v0.0.2_darwin_amd64.zip",
+		hash:    "0002123456789012345678901234567890123456789012345678901234567890",
+	}
+
+	// Mock CreateBranch request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/git/refs/%s", TestOwner, "homebrew-testApp", "heads/master"), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprintf(w, `{"object":{"sha":"abcdefg"}}`)
+	})
+
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/git/refs", TestOwner, "homebrew-testApp"), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		testBody(t, r, fmt.Sprintf(`{"ref":"refs/heads/bumps_up_to_v0.0.2","sha":"abcdefg"}`+"\n"))
+		fmt.Fprintf(w, `{"object":{"sha":"abcdefg"}}`)
+	})
+
+	// Mock CreatePullRequest request
+	mux.HandleFunc(fmt.Sprintf("/repos/%v/%v/pulls", TestOwner, "homebrew-testApp"), func(w http.ResponseWriter, r *http.Request) {
+		testBody(t, r, fmt.Sprintf(`{"title":"%s","head":"%s","base":"%s","body":"%s"}`+"\n", "Bumps up to v0.0.2", "bumps_up_to_v0.0.2", "master", "Bumps up to v0.0.2"))
+		testMethod(t, r, http.MethodPost)
+		fmt.Fprintf(w, `{"number":100, "html_url":"https://github.com/shuheiktgw/homebrew-testApp/pullls/100"}`)
+	})
+
+	err := ghbr.UpdateFormula(context.Background(), Tap{}, TestOwner, "testApp", "master", false, false, FormulaOptions{}, &release)
+
+	if err != nil {
+		t.Fatalf("#UpdateFormula returns unexpected error: %s", err)
+	}
+
+	expectedOutput := "[ghbr] ===> Checking the current formula\n" +
+		"[ghbr] ===> Creating a new feature branch\n" +
+		"[ghbr] ===> Updating the formula file\n" +
+		"[ghbr] ===> Creating a Pull Request\n" +
+		"\n\n" +
+		"Yay! Now your formula is ready to update!\n\n" +
+		"Access https://github.com/shuheiktgw/homebrew-testApp/pullls/100 and merge the Pull Request\n\n"
+
+	if got := outStream.String(); got != expectedOutput {
+		t.Errorf("#UpdateFormula outputed %+v, want %+v", got, expectedOutput)
+	}
+}
+
+func TestGhbr_UpdateFormula_SharedTap(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	ghbr := Ghbr{Forge: client, outStream: ioutil.Discard}
+
+	content := base64.StdEncoding.EncodeToString([]byte(`
+version "v0.0.1"
+url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip"
+sha256 "0001123456789012345678901234567890123456789012345678901234567890"
+`))
+
+	// Mock GetFile and UpdateFile request
+	mux.HandleFunc("/repos/TestOrg/homebrew-tools/contents/Formula/testApp.rb", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprintf(w, `{"path":"Formula/testApp.rb","sha":"formulaV0.0.1","encoding":"base64","content":"%s"}`, content)
+		case http.MethodPut:
+			var opt struct {
+				Branch string `json:"branch"`
+			}
+
+			if err := json.NewDecoder(r.Body).Decode(&opt); err != nil || opt.Branch != "bumps_up_testApp_to_v0.0.2" {
+				t.Errorf("#UpdateFormula updated the formula on %q, want bumps_up_testApp_to_v0.0.2", opt.Branch)
+			}
+		}
+	})
+
+	// Mock CreateBranch request, the branch is named after the formula since other formulae may be released at the same version
+	mux.HandleFunc("/repos/TestOrg/homebrew-tools/git/refs/heads/master", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"object":{"sha":"abcdefg"}}`)
+	})
+
+	mux.HandleFunc("/repos/TestOrg/homebrew-tools/git/refs", func(w http.ResponseWriter, r *http.Request) {
+		testBody(t, r, `{"ref":"refs/heads/bumps_up_testApp_to_v0.0.2","sha":"abcdefg"}`+"\n")
+		fmt.Fprintf(w, `{"object":{"sha":"abcdefg"}}`)
+	})
+
+	// Mock CreatePullRequest request
+	mux.HandleFunc("/repos/TestOrg/homebrew-tools/pulls", func(w http.ResponseWriter, r *http.Request) {
+		testBody(t, r, `{"title":"Bumps up testApp to v0.0.2","head":"bumps_up_testApp_to_v0.0.2","base":"master","body":"Bumps up testApp to v0.0.2"}`+"\n")
+		fmt.Fprintf(w, `{"number":100, "html_url":"https://github.com/TestOrg/homebrew-tools/pull/100"}`)
+	})
+
+	release := LatestRelease{
+		version: "v0.0.2",
+		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp_v0.0.2_darwin_amd64.zip",
+		hash:    "0002123456789012345678901234567890123456789012345678901234567890",
+	}
+
+	tap := Tap{Owner: "TestOrg", Name: "homebrew-tools", Path: "Formula/testApp.rb"}
+	if err := ghbr.UpdateFormula(context.Background(), tap, TestOwner, "testApp", "master", false, false, FormulaOptions{}, &release); err != nil {
+		t.Fatalf("#UpdateFormula returns unexpected error: %s", err)
+	}
+}
+
+func TestGhbr_UpdateFormula_AlreadyLatest(t *testing.T) {
 	client, mux, _, tearDown := setup()
 	defer tearDown()
 
 	outStream := new(bytes.Buffer)
-	ghbr := Ghbr{GitHub: client, outStream: outStream}
-	org := "TestOrg"
-
-	// Mock CreateRepository request
-	mux.HandleFunc("/orgs/TestOrg/repos", func(w http.ResponseWriter, r *http.Request) {
-		testMethod(t, r, http.MethodPost)
-		testBody(t, r, fmt.Sprintf(`{"name":"homebrew-testApp","description":"Homebrew formula for shuheiktgw/testApp","homepage":"https://github.com/shuheiktgw/testApp","private":false}`+"\n"))
-		fmt.Fprintf(w, `{"html_url":"https://github.com/TestOrg/homebrew-testApp"}`)
-	})
+	ghbr := Ghbr{Forge: client, outStream: outStream}
 
-	// Mock CreateFile request for README.md
-	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/contents/%s", org, "homebrew-testApp", "README.md"), func(w http.ResponseWriter, r *http.Request) {
-		testMethod(t, r, http.MethodPut)
-	})
+	content := base64.StdEncoding.EncodeToString([]byte(`
+version "v0.0.1"
+url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip"
+sha256 "0001123456789012345678901234567890123456789012345678901234567890"
+`))
 
-	// Mock CreateFile request for formula file
-	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/contents/%s", org, "homebrew-testApp", "testApp.rb"), func(w http.ResponseWriter, r *http.Request) {
-		testMethod(t, r, http.MethodPut)
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/contents/testApp.rb", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		testFormValues(t, r, values{"ref": "master"})
+		fmt.Fprintf(w, `{"path":"testApp.rb","encoding":"base64","content":"%s"}`, content)
 	})
 
 	release := LatestRelease{
 		version: "v0.0.1",
-		url:     "httos://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip",
-		hash:    "abcdefg",
+		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip",
+		hash:    "0001123456789012345678901234567890123456789012345678901234567890",
 	}
 
-	err := ghbr.CreateFormula(org, TestOwner, "testApp", "alphabet", false, &release)
+	err := ghbr.UpdateFormula(context.Background(), Tap{}, TestOwner, "testApp", "master", false, true, FormulaOptions{}, &release)
+
 	if err != nil {
-		t.Fatalf("#CreateFormula returns unexpected error: %s", err)
+		t.Fatalf("#UpdateFormula returns unexpected error: %s", err)
 	}
 
-	expectedOutput := "[ghbr] ===> Creating a repository\n" +
-		"[ghbr] ===> Adding README.md to the repository\n" +
-		"[ghbr] ===> Adding testApp.rb to the repository\n" +
+	expectedOutput := "[ghbr] ===> Checking the current formula\n" +
 		"\n\n" +
-		"Yay! Your Homebrew formula repository has been successfully created!\n" +
-		"Access https://github.com/TestOrg/homebrew-testApp and see what we achieved.\n\n"
+		"ghbr aborted!\n\n" +
+		"The current formula (pointing to version v0.0.1) is up-to-date.\n" +
+		"If you want to update the formula anyway, run `ghbr release` with `--force` option.\n\n"
 
 	if got := outStream.String(); got != expectedOutput {
-		t.Errorf("#CreateFormula outputed %+v, want %+v", got, expectedOutput)
+		t.Errorf("#UpdateFormula outputed %+v, want %+v", got, expectedOutput)
 	}
 }
 
-func TestGhbr_UpdateFormulaWithMerge(t *testing.T) {
+func TestGhbr_UpdateFormulaForceUpdate(t *testing.T) {
 	client, mux, _, tearDown := setup()
 	defer tearDown()
 
 	outStream := new(bytes.Buffer)
-	ghbr := Ghbr{GitHub: client, outStream: outStream}
+	ghbr := Ghbr{Forge: client, outStream: outStream}
 
 	content := base64.StdEncoding.EncodeToString([]byte(`
 version "v0.0.1"
@@ -177,9 +962,9 @@ sha256 "0001123456789012345678901234567890123456789012345678901234567890"
 `))
 
 	expectedContent, _ := json.Marshal([]byte(`
-version "v0.0.2"
-url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp_v0.0.2_darwin_amd64.zip"
-sha256 "0002123456789012345678901234567890123456789012345678901234567890"
+version "v0.0.1"
+url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip"
+sha256 "0001123456789012345678901234567890123456789012345678901234567890"
 `))
 
 	// Mock GetFile and UpdateFile request
@@ -188,14 +973,14 @@ sha256 "0002123456789012345678901234567890123456789012345678901234567890"
 		case http.MethodGet:
 			fmt.Fprintf(w, `{"path":"testApp.rb","sha":"formulaV0.0.1","encoding":"base64","content":"%s"}`, content)
 		case http.MethodPut:
-			testBody(t, r, fmt.Sprintf(`{"message":"Bumps up to v0.0.2","content":%s,"sha":"formulaV0.0.1","branch":"bumps_up_to_v0.0.2"}`+"\n", expectedContent))
+			testBody(t, r, fmt.Sprintf(`{"message":"Bumps up to v0.0.1","content":%s,"sha":"formulaV0.0.1","branch":"bumps_up_to_v0.0.1"}`+"\n", expectedContent))
 		}
 	})
 
 	release := LatestRelease{
-		version: "v0.0.2",
-		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp_v0.0.2_darwin_amd64.zip",
-		hash:    "0002123456789012345678901234567890123456789012345678901234567890",
+		version: "v0.0.1",
+		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip",
+		hash:    "0001123456789012345678901234567890123456789012345678901234567890",
 	}
 
 	// Mock CreateBranch request
@@ -206,13 +991,13 @@ sha256 "0002123456789012345678901234567890123456789012345678901234567890"
 
 	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/git/refs", TestOwner, "homebrew-testApp"), func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, http.MethodPost)
-		testBody(t, r, fmt.Sprintf(`{"ref":"refs/heads/bumps_up_to_v0.0.2","sha":"abcdefg"}`+"\n"))
+		testBody(t, r, fmt.Sprintf(`{"ref":"refs/heads/bumps_up_to_v0.0.1","sha":"abcdefg"}`+"\n"))
 		fmt.Fprintf(w, `{"object":{"sha":"abcdefg"}}`)
 	})
 
 	// Mock CreatePullRequest request
 	mux.HandleFunc(fmt.Sprintf("/repos/%v/%v/pulls", TestOwner, "homebrew-testApp"), func(w http.ResponseWriter, r *http.Request) {
-		testBody(t, r, fmt.Sprintf(`{"title":"%s","head":"%s","base":"%s","body":"%s"}`+"\n", "Bumps up to v0.0.2", "bumps_up_to_v0.0.2", "master", "Bumps up to v0.0.2"))
+		testBody(t, r, fmt.Sprintf(`{"title":"%s","head":"%s","base":"%s","body":"%s"}`+"\n", "Bumps up to v0.0.1", "bumps_up_to_v0.0.1", "master", "Bumps up to v0.0.1"))
 		testMethod(t, r, http.MethodPost)
 		fmt.Fprintf(w, `{"number":100}`)
 	})
@@ -224,11 +1009,11 @@ sha256 "0002123456789012345678901234567890123456789012345678901234567890"
 	})
 
 	// Mock DeleteLatestRef request
-	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/git/refs/%s", TestOwner, "homebrew-testApp", "heads/bumps_up_to_v0.0.2"), func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/git/refs/%s", TestOwner, "homebrew-testApp", "heads/bumps_up_to_v0.0.1"), func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, http.MethodDelete)
 	})
 
-	err := ghbr.UpdateFormula("", TestOwner, "testApp", "master", false, true, &release)
+	err := ghbr.UpdateFormula(context.Background(), Tap{}, TestOwner, "testApp", "master", true, true, FormulaOptions{}, &release)
 
 	if err != nil {
 		t.Fatalf("#UpdateFormula returns unexpected error: %s", err)
@@ -248,24 +1033,48 @@ sha256 "0002123456789012345678901234567890123456789012345678901234567890"
 	}
 }
 
-func TestGhbr_UpdateFormulaWithoutMerge(t *testing.T) {
+func TestGhbr_UpdateFormula_WithResource(t *testing.T) {
 	client, mux, _, tearDown := setup()
 	defer tearDown()
 
 	outStream := new(bytes.Buffer)
-	ghbr := Ghbr{GitHub: client, outStream: outStream}
-
-	content := base64.StdEncoding.EncodeToString([]byte(`
-version "v0.0.1"
-url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip"
-sha256 "0001123456789012345678901234567890123456789012345678901234567890"
+	ghbr := Ghbr{Forge: client, outStream: outStream}
+
+	assetPath := fmt.Sprintf("/%s/testPlugin/releases/download/v1.0.1/testPlugin_v1.0.1.tar.gz", TestOwner)
+	assetURL := fmt.Sprintf("%s%s", strings.TrimSuffix(client.Client.BaseURL.String(), "/"), assetPath)
+	asset := []byte("testPlugin v1.0.1")
+
+	content := base64.StdEncoding.EncodeToString([]byte(`class TestApp < Formula
+  version 'v0.0.1'
+  url 'https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip'
+  sha256 '0001123456789012345678901234567890123456789012345678901234567890'
+
+  resource 'plugin' do
+    url 'https://github.com/shuheiktgw/testPlugin/releases/download/v1.0.0/testPlugin_v1.0.0.tar.gz'
+    sha256 '1000123456789012345678901234567890123456789012345678901234567890'
+  end
+
+  def install
+    bin.install 'testApp'
+  end
+end
 `))
 
-	expectedContent, _ := json.Marshal([]byte(`
-version "v0.0.2"
-url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp_v0.0.2_darwin_amd64.zip"
-sha256 "0002123456789012345678901234567890123456789012345678901234567890"
-`))
+	expectedContent, _ := json.Marshal([]byte(fmt.Sprintf(`class TestApp < Formula
+  version 'v0.0.2'
+  url 'https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp_v0.0.2_darwin_amd64.zip'
+  sha256 '0002123456789012345678901234567890123456789012345678901234567890'
+
+  resource 'plugin' do
+    url '%s'
+    sha256 '%x'
+  end
+
+  def install
+    bin.install 'testApp'
+  end
+end
+`, assetURL, sha256.Sum256(asset))))
 
 	// Mock GetFile and UpdateFile request
 	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/contents/testApp.rb", TestOwner), func(w http.ResponseWriter, r *http.Request) {
@@ -277,11 +1086,15 @@ sha256 "0002123456789012345678901234567890123456789012345678901234567890"
 		}
 	})
 
-	release := LatestRelease{
-		version: "v0.0.2",
-		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp_v0.0.2_darwin_amd64.zip",
-		hash:    "0002123456789012345678901234567890123456789012345678901234567890",
-	}
+	// Mock GetLatestRelease request of the resource
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/testPlugin/releases/latest", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"id":1,"name":"Release v1.0.1","tag_name":"v1.0.1","assets":[{"name":"testPlugin_v1.0.1.zip", "browser_download_url":"https://example.com/testPlugin_v1.0.1.zip"},{"name":"testPlugin_v1.0.1.tar.gz", "browser_download_url":"%s"}]}`, assetURL)
+	})
+
+	// Mock downloadFile request of the resource
+	mux.HandleFunc(assetPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(asset)
+	})
 
 	// Mock CreateBranch request
 	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/git/refs/%s", TestOwner, "homebrew-testApp", "heads/master"), func(w http.ResponseWriter, r *http.Request) {
@@ -291,24 +1104,31 @@ sha256 "0002123456789012345678901234567890123456789012345678901234567890"
 
 	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/git/refs", TestOwner, "homebrew-testApp"), func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, http.MethodPost)
-		testBody(t, r, fmt.Sprintf(`{"ref":"refs/heads/bumps_up_to_v0.0.2","sha":"abcdefg"}`+"\n"))
 		fmt.Fprintf(w, `{"object":{"sha":"abcdefg"}}`)
 	})
 
 	// Mock CreatePullRequest request
 	mux.HandleFunc(fmt.Sprintf("/repos/%v/%v/pulls", TestOwner, "homebrew-testApp"), func(w http.ResponseWriter, r *http.Request) {
-		testBody(t, r, fmt.Sprintf(`{"title":"%s","head":"%s","base":"%s","body":"%s"}`+"\n", "Bumps up to v0.0.2", "bumps_up_to_v0.0.2", "master", "Bumps up to v0.0.2"))
 		testMethod(t, r, http.MethodPost)
 		fmt.Fprintf(w, `{"number":100, "html_url":"https://github.com/shuheiktgw/homebrew-testApp/pullls/100"}`)
 	})
 
-	err := ghbr.UpdateFormula("", TestOwner, "testApp", "master", false, false, &release)
+	release := LatestRelease{
+		version: "v0.0.2",
+		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp_v0.0.2_darwin_amd64.zip",
+		hash:    "0002123456789012345678901234567890123456789012345678901234567890",
+	}
+
+	opts := FormulaOptions{Resources: []Resource{{Name: "plugin", Repository: TestOwner + "/testPlugin", Asset: "*.tar.gz"}}}
 
-	if err != nil {
+	if err := ghbr.UpdateFormula(context.Background(), Tap{}, TestOwner, "testApp", "master", false, false, opts, &release); err != nil {
 		t.Fatalf("#UpdateFormula returns unexpected error: %s", err)
 	}
 
 	expectedOutput := "[ghbr] ===> Checking the current formula\n" +
+		"[ghbr] ===> Checking the latest release of resource plugin\n" +
+		"[ghbr] ===> Downloading resource plugin\n" +
+		fmt.Sprintf("[ghbr] ===> testApp.rb:7: warning: `url` %s is not https\n", assetURL) +
 		"[ghbr] ===> Creating a new feature branch\n" +
 		"[ghbr] ===> Updating the formula file\n" +
 		"[ghbr] ===> Creating a Pull Request\n" +
@@ -321,129 +1141,314 @@ sha256 "0002123456789012345678901234567890123456789012345678901234567890"
 	}
 }
 
-func TestGhbr_UpdateFormula_AlreadyLatest(t *testing.T) {
+func TestGhbr_UpdateCask(t *testing.T) {
 	client, mux, _, tearDown := setup()
 	defer tearDown()
 
 	outStream := new(bytes.Buffer)
-	ghbr := Ghbr{GitHub: client, outStream: outStream}
+	ghbr := Ghbr{Forge: client, outStream: outStream}
 
 	content := base64.StdEncoding.EncodeToString([]byte(`
-version "v0.0.1"
-url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip"
+version "0.0.1"
 sha256 "0001123456789012345678901234567890123456789012345678901234567890"
+url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp.dmg"
 `))
 
-	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/contents/testApp.rb", TestOwner), func(w http.ResponseWriter, r *http.Request) {
-		testMethod(t, r, http.MethodGet)
-		testFormValues(t, r, values{"ref": "master"})
-		fmt.Fprintf(w, `{"path":"testApp.rb","encoding":"base64","content":"%s"}`, content)
+	expectedContent, _ := json.Marshal([]byte(`
+version "0.0.2"
+sha256 "0002123456789012345678901234567890123456789012345678901234567890"
+url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp.dmg"
+`))
+
+	// Mock GetFile and UpdateFile request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/contents/Casks/test-app.rb", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprintf(w, `{"path":"Casks/test-app.rb","sha":"caskV0.0.1","encoding":"base64","content":"%s"}`, content)
+		case http.MethodPut:
+			testBody(t, r, fmt.Sprintf(`{"message":"Bumps up to 0.0.2","content":%s,"sha":"caskV0.0.1","branch":"bumps_up_to_0.0.2"}`+"\n", expectedContent))
+		}
 	})
 
 	release := LatestRelease{
-		version: "v0.0.1",
-		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip",
-		hash:    "0001123456789012345678901234567890123456789012345678901234567890",
+		version: "0.0.2",
+		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp.dmg",
+		hash:    "0002123456789012345678901234567890123456789012345678901234567890",
 	}
 
-	err := ghbr.UpdateFormula("", TestOwner, "testApp", "master", false, true, &release)
+	// Mock CreateBranch request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/git/refs/%s", TestOwner, "homebrew-testApp", "heads/master"), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"object":{"sha":"abcdefg"}}`)
+	})
+
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/git/refs", TestOwner, "homebrew-testApp"), func(w http.ResponseWriter, r *http.Request) {
+		testBody(t, r, fmt.Sprintf(`{"ref":"refs/heads/bumps_up_to_0.0.2","sha":"abcdefg"}`+"\n"))
+		fmt.Fprintf(w, `{"object":{"sha":"abcdefg"}}`)
+	})
 
-	if err != nil {
-		t.Fatalf("#UpdateFormula returns unexpected error: %s", err)
+	// Mock CreatePullRequest request
+	mux.HandleFunc(fmt.Sprintf("/repos/%v/%v/pulls", TestOwner, "homebrew-testApp"), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"number":100, "html_url":"https://github.com/shuheiktgw/homebrew-testApp/pullls/100"}`)
+	})
+
+	if err := ghbr.UpdateCask(context.Background(), Tap{}, TestOwner, "testApp", "master", false, false, &release); err != nil {
+		t.Fatalf("#UpdateCask returns unexpected error: %s", err)
 	}
 
-	expectedOutput := "[ghbr] ===> Checking the current formula\n" +
+	expectedOutput := "[ghbr] ===> Checking the current cask\n" +
+		"[ghbr] ===> Creating a new feature branch\n" +
+		"[ghbr] ===> Updating the cask file\n" +
+		"[ghbr] ===> Creating a Pull Request\n" +
 		"\n\n" +
-		"ghbr aborted!\n\n" +
-		"The current formula (pointing to version v0.0.1) is up-to-date.\n" +
-		"If you want to update the formula anyway, run `ghbr release` with `--force` option.\n\n"
+		"Yay! Now your cask is ready to update!\n\n" +
+		"Access https://github.com/shuheiktgw/homebrew-testApp/pullls/100 and merge the Pull Request\n\n"
 
 	if got := outStream.String(); got != expectedOutput {
-		t.Errorf("#UpdateFormula outputed %+v, want %+v", got, expectedOutput)
+		t.Errorf("#UpdateCask outputed %+v, want %+v", got, expectedOutput)
 	}
 }
 
-func TestGhbr_UpdateFormulaForceUpdate(t *testing.T) {
+func TestGhbr_FormulaPath(t *testing.T) {
 	client, mux, _, tearDown := setup()
 	defer tearDown()
 
-	outStream := new(bytes.Buffer)
-	ghbr := Ghbr{GitHub: client, outStream: outStream}
+	ghbr := Ghbr{Forge: client, outStream: new(bytes.Buffer)}
 
-	content := base64.StdEncoding.EncodeToString([]byte(`
-version "v0.0.1"
-url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip"
-sha256 "0001123456789012345678901234567890123456789012345678901234567890"
-`))
+	// Mock GetDirectory request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-tools/contents/Formula", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		testFormValues(t, r, values{"ref": "master"})
+		fmt.Fprint(w, `[{"type":"file","name":"testApp.rb","path":"Formula/testApp.rb"}]`)
+	})
 
-	expectedContent, _ := json.Marshal([]byte(`
-version "v0.0.1"
-url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip"
-sha256 "0001123456789012345678901234567890123456789012345678901234567890"
-`))
+	cases := []struct {
+		repo, want string
+	}{
+		{repo: "homebrew-tools", want: "Formula/testApp.rb"},
+		{repo: "homebrew-testApp", want: "testApp.rb"},
+	}
 
-	// Mock GetFile and UpdateFile request
-	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/contents/testApp.rb", TestOwner), func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet:
-			fmt.Fprintf(w, `{"path":"testApp.rb","sha":"formulaV0.0.1","encoding":"base64","content":"%s"}`, content)
-		case http.MethodPut:
-			testBody(t, r, fmt.Sprintf(`{"message":"Bumps up to v0.0.1","content":%s,"sha":"formulaV0.0.1","branch":"bumps_up_to_v0.0.1"}`+"\n", expectedContent))
+	for i, tc := range cases {
+		got, err := ghbr.formulaPath(context.Background(), TestOwner, tc.repo, "master", "testApp")
+		if err != nil {
+			t.Fatalf("#%d #formulaPath returns unexpected error: %s", i, err)
 		}
-	})
 
-	release := LatestRelease{
-		version: "v0.0.1",
-		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip",
-		hash:    "0001123456789012345678901234567890123456789012345678901234567890",
+		if got != tc.want {
+			t.Errorf("#%d #formulaPath returned %s, want %s", i, got, tc.want)
+		}
+	}
+}
+
+func TestGhbr_BuildFormula_Livecheck(t *testing.T) {
+	githubClient, _, _, tearDown := setup()
+	defer tearDown()
+
+	gitlabClient, _, _, tearDownGitLab := setupGitLab()
+	defer tearDownGitLab()
+
+	giteaClient, _, _, tearDownGitea := setupGitea()
+	defer tearDownGitea()
+
+	cases := []struct {
+		forge        Forge
+		source       ReleaseSource
+		url          string
+		wantStrategy bool
+	}{
+		{forge: githubClient, url: "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip", wantStrategy: true},
+		{forge: githubClient, url: "https://github.example.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip"},
+		{forge: gitlabClient, url: "https://gitlab.com/shuheiktgw/testApp/-/releases/v0.0.1/downloads/testApp_v0.0.1_darwin_amd64.zip"},
+		{forge: giteaClient, url: "https://codeberg.org/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip"},
+		{forge: githubClient, source: NewReleaseSource("https://example.com/releases/", nil), url: "https://example.com/releases/testApp_v0.0.1_darwin_amd64.zip"},
 	}
 
-	// Mock CreateBranch request
-	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/git/refs/%s", TestOwner, "homebrew-testApp", "heads/master"), func(w http.ResponseWriter, r *http.Request) {
-		testMethod(t, r, http.MethodGet)
-		fmt.Fprintf(w, `{"object":{"sha":"abcdefg"}}`)
+	for i, tc := range cases {
+		ghbr := Ghbr{Forge: tc.forge, Source: tc.source, outStream: ioutil.Discard}
+		release := LatestRelease{version: "v0.0.1", url: tc.url, hash: "0001123456789012345678901234567890123456789012345678901234567890"}
+
+		f, err := ghbr.buildFormula(context.Background(), TestOwner, "testApp", FormulaOptions{Livecheck: true}, &release)
+		if err != nil {
+			t.Fatalf("#%d #buildFormula returns unexpected error: %s", i, err)
+		}
+
+		content, err := f.render("")
+		if err != nil {
+			t.Fatalf("#%d #render returns unexpected error: %s", i, err)
+		}
+
+		if !strings.Contains(content, "  livecheck do\n    url :stable\n") {
+			t.Errorf("#%d #buildFormula rendered %s, want a livecheck block", i, content)
+		}
+
+		if got := strings.Contains(content, "strategy :github_latest"); got != tc.wantStrategy {
+			t.Errorf("#%d #buildFormula rendered %s, want strategy :github_latest: %t", i, content, tc.wantStrategy)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{a: "v0.0.1", b: "v0.0.1", want: 0},
+		{a: "v0.0.1", b: "v0.0.2", want: -1},
+		{a: "v0.10.0", b: "v0.9.0", want: 1},
+		{a: "1.0", b: "v1.0.0", want: 0},
+		{a: "v1.0.0-rc1", b: "v1.0.0-rc2", want: -1},
+		{a: "1.0.0-rc1", b: "1.0.0", want: -1},
+		{a: "v1.0.0", b: "v1.0.0-beta", want: 1},
+		{a: "1.2.3-rc1", b: "1.2.10", want: -1},
+		{a: "v1.2.10-rc1", b: "v1.2.3", want: 1},
+		{a: "v1.0.0-rc.2", b: "v1.0.0-rc.10", want: -1},
+		{a: "v1.0-rc1", b: "v1.0.0-rc1", want: 0},
+		{a: "v1.0.0-rc2", b: "v1.0.0-rc10", want: -1},
+		{a: "v1.0.0-rc10", b: "v1.0.0-rc2", want: 1},
+		{a: "v1.0.0-beta2", b: "v1.0.0-rc1", want: -1},
+		{a: "v1.0.0-rc1", b: "v1.0.0-rc1.1", want: -1},
+	}
+
+	for i, tc := range cases {
+		if got := compareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("#%d #compareVersions(%s, %s) returned %d, want %d", i, tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestIsBumpPullRequest(t *testing.T) {
+	cases := []struct {
+		ref, body string
+		tap       Tap
+		want      bool
+	}{
+		{ref: "bumps_up_to_v0.0.2", tap: Tap{}, want: true},
+		{ref: "bumps_up_to_v0.0.2", tap: Tap{Name: "homebrew-tools"}, want: false},
+		{ref: "bumps_up_testApp_to_v0.0.2", tap: Tap{Name: "homebrew-tools"}, want: true},
+		{ref: "bumps_up_otherApp_to_v0.0.2", tap: Tap{Name: "homebrew-tools"}, want: false},
+		{ref: "bumps_up_formulae_0a1b2c3d", body: "- testApp: v0.0.2", tap: Tap{Name: "homebrew-tools"}, want: true},
+		{ref: "bumps_up_formulae_0a1b2c3d", body: "- otherApp: v0.0.2", tap: Tap{Name: "homebrew-tools"}, want: false},
+		{ref: "feature", tap: Tap{}, want: false},
+	}
+
+	for i, tc := range cases {
+		pr := &github.PullRequest{Head: &github.PullRequestBranch{Ref: github.String(tc.ref)}, Body: github.String(tc.body)}
+		if got := isBumpPullRequest(pr, tc.tap, "testApp"); got != tc.want {
+			t.Errorf("#%d #isBumpPullRequest(%s) returned %t, want %t", i, tc.ref, got, tc.want)
+		}
+	}
+}
+
+func TestBumpsUpFormula_PreservesCaveats(t *testing.T) {
+	content := `class TestApp < Formula
+  version 'v0.0.1'
+  url 'https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip'
+  sha256 '0001123456789012345678901234567890123456789012345678901234567890'
+
+  def caveats
+    <<-'EOF'
+Changelog of v0.0.1: https://github.com/shuheiktgw/testApp/releases/tag/v0.0.1
+EOF
+  end
+end
+`
+
+	release := &LatestRelease{
+		version: "v0.0.2",
+		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp_v0.0.2_darwin_amd64.zip",
+		hash:    "0002123456789012345678901234567890123456789012345678901234567890",
+	}
+
+	got, err := bumpsUpFormula(content, release)
+	if err != nil {
+		t.Fatalf("#bumpsUpFormula returns unexpected error: %s", err)
+	}
+
+	want := `class TestApp < Formula
+  version 'v0.0.2'
+  url 'https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp_v0.0.2_darwin_amd64.zip'
+  sha256 '0002123456789012345678901234567890123456789012345678901234567890'
+
+  def caveats
+    <<-'EOF'
+Changelog of v0.0.1: https://github.com/shuheiktgw/testApp/releases/tag/v0.0.1
+EOF
+  end
+end
+`
+
+	if got != want {
+		t.Errorf("#bumpsUpFormula returned %s, want %s", got, want)
+	}
+}
+
+func TestGhbr_CreateFormula_SeparateTap(t *testing.T) {
+	// The application is released on a GitHub Enterprise instance while the tap is on a Gitea instance
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tap, tapMux, tapURL, tearDown := setupGitea()
+	defer tearDown()
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{Forge: NewGitHubEnterpriseClient(server.URL, "abcdefg", nil), TapForge: tap, outStream: outStream}
+
+	assetPath := fmt.Sprintf("/%s/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip", TestOwner)
+
+	mux.HandleFunc(fmt.Sprintf("/api/v3/repos/%s/testApp/releases/latest", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		testHeader(t, r, "Authorization", "Bearer abcdefg")
+		fmt.Fprintf(w, `{"tag_name":"v0.0.1","assets":[{"name":"testApp_v0.0.1_darwin_amd64.zip","browser_download_url":"%s%s"}]}`, server.URL, assetPath)
 	})
 
-	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/git/refs", TestOwner, "homebrew-testApp"), func(w http.ResponseWriter, r *http.Request) {
-		testMethod(t, r, http.MethodPost)
-		testBody(t, r, fmt.Sprintf(`{"ref":"refs/heads/bumps_up_to_v0.0.1","sha":"abcdefg"}`+"\n"))
-		fmt.Fprintf(w, `{"object":{"sha":"abcdefg"}}`)
+	mux.HandleFunc(assetPath, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "test")
 	})
 
-	// Mock CreatePullRequest request
-	mux.HandleFunc(fmt.Sprintf("/repos/%v/%v/pulls", TestOwner, "homebrew-testApp"), func(w http.ResponseWriter, r *http.Request) {
-		testBody(t, r, fmt.Sprintf(`{"title":"%s","head":"%s","base":"%s","body":"%s"}`+"\n", "Bumps up to v0.0.1", "bumps_up_to_v0.0.1", "master", "Bumps up to v0.0.1"))
+	release, err := ghbr.GetLatestRelease(context.Background(), TestOwner, "testApp", "", false)
+	if err != nil {
+		t.Fatalf("#GetLatestRelease returns unexpected error: %s", err)
+	}
+
+	if want := server.URL + assetPath; release.url != want {
+		t.Errorf("#GetLatestRelease returned %s, want %s", release.url, want)
+	}
+
+	tapMux.HandleFunc("/api/v1/user/repos", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, http.MethodPost)
-		fmt.Fprintf(w, `{"number":100}`)
+		fmt.Fprintf(w, `{"name":"homebrew-testApp","full_name":"shuheiktgw/homebrew-testApp","html_url":"%s/shuheiktgw/homebrew-testApp"}`, tapURL)
 	})
 
-	// Mock MergePullRequest request
-	mux.HandleFunc(fmt.Sprintf("/repos/%v/%v/pulls/%d/merge", TestOwner, "homebrew-testApp", 100), func(w http.ResponseWriter, r *http.Request) {
-		testMethod(t, r, http.MethodPut)
-		fmt.Fprint(w, `{"sha":"abcdefg","merged":true}`)
+	tapMux.HandleFunc(fmt.Sprintf("/api/v1/repos/%s/homebrew-testApp/contents/README.md", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		// README.md links to the application on the GitHub Enterprise instance
+		want := fmt.Sprintf("homebrew-testApp\n====\n\n[Homebrew](http://brew.sh/) formula for [shuheiktgw/testApp](%s/shuheiktgw/testApp)\n\n", server.URL)
+		if got := testFileContent(t, r); got != want {
+			t.Errorf("README.md is %q, want %q", got, want)
+		}
+
+		fmt.Fprint(w, `{"content":{"path":"README.md"}}`)
 	})
 
-	// Mock DeleteLatestRef request
-	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/git/refs/%s", TestOwner, "homebrew-testApp", "heads/bumps_up_to_v0.0.1"), func(w http.ResponseWriter, r *http.Request) {
-		testMethod(t, r, http.MethodDelete)
+	tapMux.HandleFunc(fmt.Sprintf("/api/v1/repos/%s/homebrew-testApp/contents/testApp.rb", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		fmt.Fprint(w, `{"content":{"path":"testApp.rb"}}`)
 	})
 
-	err := ghbr.UpdateFormula("", TestOwner, "testApp", "master", true, true, &release)
+	outStream.Reset()
+	release.url = "https://github.example.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip"
 
-	if err != nil {
-		t.Fatalf("#UpdateFormula returns unexpected error: %s", err)
+	if err := ghbr.CreateFormula(context.Background(), Tap{}, TestOwner, "testApp", false, FormulaOptions{}, release); err != nil {
+		t.Fatalf("#CreateFormula returns unexpected error: %s", err)
 	}
 
-	expectedOutput := "[ghbr] ===> Checking the current formula\n" +
-		"[ghbr] ===> Creating a new feature branch\n" +
-		"[ghbr] ===> Updating the formula file\n" +
-		"[ghbr] ===> Creating a Pull Request\n" +
-		"[ghbr] ===> Merging the Pull Request\n" +
-		"[ghbr] ===> Deleting the branch\n" +
+	expectedOutput := fmt.Sprintf("[ghbr] ===> testApp.rb:4: warning: `homepage` %s/shuheiktgw/testApp is not https\n", server.URL) +
+		"[ghbr] ===> Creating a repository\n" +
+		"[ghbr] ===> Adding README.md to the repository\n" +
+		"[ghbr] ===> Adding testApp.rb to the repository\n" +
 		"\n\n" +
-		"Yay! Now your formula is up-to-date!\n\n"
+		"Yay! Your Homebrew formula repository has been successfully created!\n" +
+		fmt.Sprintf("Access %s/shuheiktgw/homebrew-testApp and see what we achieved.\n\n", tapURL)
 
 	if got := outStream.String(); got != expectedOutput {
-		t.Errorf("#UpdateFormula outputed %+v, want %+v", got, expectedOutput)
+		t.Errorf("#CreateFormula outputed %+v, want %+v", got, expectedOutput)
 	}
 }
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// EnvGiteaToken is used as the token with --forge gitea unless -t is passed
+const EnvGiteaToken = "GITEA_TOKEN"
+
+// giteaPageSize is the number of items requested per page. Gitea responds with fewer items per page
+// if [api] MAX_RESPONSE_ITEMS of the instance is lower, which is 50 by default
+const giteaPageSize = 50
+
+// Merging a Pull Request is retried while Gitea is checking whether it can be merged
+const (
+	giteaMergeRetries       = 5
+	giteaMergeRetryInterval = 2 * time.Second
+)
+
+// GiteaClient is a client to interact with Gitea API, which Forgejo serves as well
+type GiteaClient struct {
+	api *restClient
+
+	// webURL is the URL of the Gitea instance for humans
+	webURL string
+
+	mergeRetryInterval time.Duration
+}
+
+// NewGiteaClient creates and initializes a new GiteaClient for the instance at the webURL like https://codeberg.org,
+// API requests are logged through the logger unless it is nil
+func NewGiteaClient(webURL, token string, logger *Logger) *GiteaClient {
+	webURL = strings.TrimSuffix(webURL, "/")
+
+	return &GiteaClient{
+		api:                newRESTClient(webURL+"/api/v1", http.Header{"Authorization": {"token " + token}}, logger),
+		webURL:             webURL,
+		mergeRetryInterval: giteaMergeRetryInterval,
+	}
+}
+
+// giteaRepoPath returns the API path of the repository owner/repo
+func giteaRepoPath(owner, repo string) string {
+	return fmt.Sprintf("repos/%s/%s", url.PathEscape(owner), url.PathEscape(repo))
+}
+
+// giteaContentsPath returns the API path of the file or directory in the repository, each segment of which is escaped
+func giteaContentsPath(owner, repo, path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+
+	return giteaRepoPath(owner, repo) + "/contents/" + strings.Join(segments, "/")
+}
+
+type giteaRelease struct {
+	TagName    string `json:"tag_name"`
+	Name       string `json:"name"`
+	TarballURL string `json:"tarball_url"`
+	ZipballURL string `json:"zipball_url"`
+	Assets     []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// release converts the release into the one of GitHub
+func (r *giteaRelease) release() *github.RepositoryRelease {
+	rr := &github.RepositoryRelease{
+		TagName:    github.String(r.TagName),
+		Name:       github.String(r.Name),
+		TarballURL: github.String(r.TarballURL),
+		ZipballURL: github.String(r.ZipballURL),
+	}
+
+	for _, a := range r.Assets {
+		rr.Assets = append(rr.Assets, github.ReleaseAsset{Name: github.String(a.Name), BrowserDownloadURL: github.String(a.BrowserDownloadURL)})
+	}
+
+	return rr
+}
+
+type giteaPullRequest struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	State   string `json:"state"`
+	Merged  bool   `json:"merged"`
+	HTMLURL string `json:"html_url"`
+	Head    struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+// pullRequest converts the Pull Request into the one of GitHub
+func (p *giteaPullRequest) pullRequest() *github.PullRequest {
+	return &github.PullRequest{
+		Number:  github.Int(p.Number),
+		Title:   github.String(p.Title),
+		State:   github.String(p.State),
+		Merged:  github.Bool(p.Merged),
+		HTMLURL: github.String(p.HTMLURL),
+		Head:    &github.PullRequestBranch{Ref: github.String(p.Head.Ref)},
+		Base:    &github.PullRequestBranch{Ref: github.String(p.Base.Ref)},
+	}
+}
+
+type giteaRepository struct {
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	Description   string `json:"description"`
+	Website       string `json:"website"`
+	DefaultBranch string `json:"default_branch"`
+	HTMLURL       string `json:"html_url"`
+	Private       bool   `json:"private"`
+}
+
+// repository converts the repository into the one of GitHub
+func (r *giteaRepository) repository() *github.Repository {
+	return &github.Repository{
+		Name:          github.String(r.Name),
+		FullName:      github.String(r.FullName),
+		Description:   github.String(r.Description),
+		Homepage:      github.String(r.Website),
+		DefaultBranch: github.String(r.DefaultBranch),
+		HTMLURL:       github.String(r.HTMLURL),
+		Private:       github.Bool(r.Private),
+	}
+}
+
+type giteaContent struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	SHA      string `json:"sha"`
+	Encoding string `json:"encoding"`
+	Content  string `json:"content"`
+}
+
+// content converts the file or directory into the one of GitHub
+func (c *giteaContent) content() *github.RepositoryContent {
+	rc := &github.RepositoryContent{
+		Type: github.String(c.Type),
+		Name: github.String(c.Name),
+		Path: github.String(c.Path),
+		SHA:  github.String(c.SHA),
+	}
+
+	if len(c.Encoding) != 0 {
+		rc.Encoding = github.String(c.Encoding)
+		rc.Content = github.String(c.Content)
+	}
+
+	return rc
+}
+
+// conflictAsUnprocessable makes a 409 Conflict look like 422 Unprocessable Entity, which GitHub responds with instead
+func conflictAsUnprocessable(err error) error {
+	if e, ok := err.(*APIError); ok && e.StatusCode == http.StatusConflict {
+		e.StatusCode = http.StatusUnprocessableEntity
+	}
+
+	return err
+}
+
+// GetLatestRelease returns the latest release of the given repository
+func (g *GiteaClient) GetLatestRelease(ctx context.Context, owner, repo string) (*github.RepositoryRelease, error) {
+	var r giteaRelease
+	if err := g.api.do(ctx, http.MethodGet, giteaRepoPath(owner, repo)+"/releases/latest", nil, nil, &r); err != nil {
+		return nil, errors.Wrapf(err, "Gitea #GetLatestRelease failed: owner: %s, repo: %s", owner, repo)
+	}
+
+	return r.release(), nil
+}
+
+// CreateBranch creates a new branch from the heads of the origin
+func (g *GiteaClient) CreateBranch(ctx context.Context, owner, repo, origin, new string) error {
+	in := map[string]string{"new_branch_name": new, "old_branch_name": origin}
+
+	if err := g.api.do(ctx, http.MethodPost, giteaRepoPath(owner, repo)+"/branches", nil, in, nil); err != nil {
+		return errors.Wrapf(conflictAsUnprocessable(err), "Gitea #CreateBranch failed: owner: %s, repo: %s, branch: %s", owner, repo, new)
+	}
+
+	return nil
+}
+
+// DeleteLatestRef deletes the branch
+func (g *GiteaClient) DeleteLatestRef(ctx context.Context, owner, repo, branch string) error {
+	if err := g.api.do(ctx, http.MethodDelete, giteaRepoPath(owner, repo)+"/branches/"+url.PathEscape(branch), nil, nil, nil); err != nil {
+		return errors.Wrapf(err, "Gitea #DeleteLatestRef failed: owner: %s, repo: %s, branch: %s", owner, repo, branch)
+	}
+
+	return nil
+}
+
+// CreatePullRequest opens a Pull Request from the head to the base
+func (g *GiteaClient) CreatePullRequest(ctx context.Context, owner, repo, title, head, base, body string) (*github.PullRequest, error) {
+	in := map[string]string{"title": title, "head": head, "base": base, "body": body}
+
+	var pr giteaPullRequest
+	if err := g.api.do(ctx, http.MethodPost, giteaRepoPath(owner, repo)+"/pulls", nil, in, &pr); err != nil {
+		return nil, errors.Wrapf(conflictAsUnprocessable(err), "Gitea #CreatePullRequest failed: owner: %s, repo: %s, head: %s, base: %s", owner, repo, head, base)
+	}
+
+	return pr.pullRequest(), nil
+}
+
+// ListPullRequests lists open Pull Requests to the base
+func (g *GiteaClient) ListPullRequests(ctx context.Context, owner, repo, base string) ([]*github.PullRequest, error) {
+	var prs []giteaPullRequest
+
+	// Pages are requested until an empty one, since a page may be shorter than the limit before the last one
+	for page := 1; ; page++ {
+		var ps []giteaPullRequest
+		query := url.Values{"state": {"open"}, "limit": {strconv.Itoa(giteaPageSize)}, "page": {strconv.Itoa(page)}}
+
+		if err := g.api.do(ctx, http.MethodGet, giteaRepoPath(owner, repo)+"/pulls", query, nil, &ps); err != nil {
+			return nil, errors.Wrapf(err, "Gitea #ListPullRequests failed: owner: %s, repo: %s, base: %s", owner, repo, base)
+		}
+
+		if len(ps) == 0 {
+			break
+		}
+
+		prs = append(prs, ps...)
+	}
+
+	// Gitea cannot filter Pull Requests by the base
+	var res []*github.PullRequest
+	for i := range prs {
+		if prs[i].Base.Ref == base {
+			res = append(res, prs[i].pullRequest())
+		}
+	}
+
+	return res, nil
+}
+
+// GetPullRequest gets the Pull Request with the number
+func (g *GiteaClient) GetPullRequest(ctx context.Context, owner, repo string, number int) (*github.PullRequest, error) {
+	var pr giteaPullRequest
+	if err := g.api.do(ctx, http.MethodGet, fmt.Sprintf("%s/pulls/%d", giteaRepoPath(owner, repo), number), nil, nil, &pr); err != nil {
+		return nil, errors.Wrapf(err, "Gitea #GetPullRequest failed: owner: %s, repo: %s, number: %d", owner, repo, number)
+	}
+
+	return pr.pullRequest(), nil
+}
+
+// MergePullRequest merges the Pull Request with the number, which is retried while Gitea is checking whether it can be merged
+func (g *GiteaClient) MergePullRequest(ctx context.Context, owner, repo string, number int) error {
+	path := fmt.Sprintf("%s/pulls/%d/merge", giteaRepoPath(owner, repo), number)
+	in := map[string]string{"Do": "merge"}
+
+	err := retryMerge(ctx, giteaMergeRetries, g.mergeRetryInterval, func() error {
+		return g.api.do(ctx, http.MethodPost, path, nil, in, nil)
+	}, http.StatusMethodNotAllowed)
+	if err == nil {
+		return nil
+	}
+
+	return errors.Wrapf(err, "Gitea #MergePullRequest failed: owner: %s, repo: %s, number: %d", owner, repo, number)
+}
+
+// ClosePullRequest closes the Pull Request with the number
+func (g *GiteaClient) ClosePullRequest(ctx context.Context, owner, repo string, number int) error {
+	in := map[string]string{"state": "closed"}
+
+	if err := g.api.do(ctx, http.MethodPatch, fmt.Sprintf("%s/pulls/%d", giteaRepoPath(owner, repo), number), nil, in, nil); err != nil {
+		return errors.Wrapf(err, "Gitea #ClosePullRequest failed: owner: %s, repo: %s, number: %d", owner, repo, number)
+	}
+
+	return nil
+}
+
+// GetFile gets the file on the branch
+func (g *GiteaClient) GetFile(ctx context.Context, owner, repo, branch, path string) (*github.RepositoryContent, error) {
+	var c giteaContent
+	if err := g.api.do(ctx, http.MethodGet, giteaContentsPath(owner, repo, path), url.Values{"ref": {branch}}, nil, &c); err != nil {
+		return nil, errors.Wrapf(err, "Gitea #GetFile failed: owner: %s, repo: %s, branch: %s, path: %s", owner, repo, branch, path)
+	}
+
+	return c.content(), nil
+}
+
+// GetDirectory lists files in the directory on the branch
+func (g *GiteaClient) GetDirectory(ctx context.Context, owner, repo, branch, path string) ([]*github.RepositoryContent, error) {
+	var cs []giteaContent
+	if err := g.api.do(ctx, http.MethodGet, giteaContentsPath(owner, repo, path), url.Values{"ref": {branch}}, nil, &cs); err != nil {
+		return nil, errors.Wrapf(err, "Gitea #GetDirectory failed: owner: %s, repo: %s, branch: %s, path: %s", owner, repo, branch, path)
+	}
+
+	dir := make([]*github.RepositoryContent, len(cs))
+	for i := range cs {
+		dir[i] = cs[i].content()
+	}
+
+	return dir, nil
+}
+
+// giteaFileRequest is a request to create, update or delete a file
+type giteaFileRequest struct {
+	Branch  string `json:"branch"`
+	Message string `json:"message"`
+	Content string `json:"content,omitempty"`
+	SHA     string `json:"sha,omitempty"`
+}
+
+// CreateFile commits a new file to the branch
+func (g *GiteaClient) CreateFile(ctx context.Context, owner, repo, branch, path, message string, content []byte) (*github.RepositoryContentResponse, error) {
+	in := giteaFileRequest{Branch: branch, Message: message, Content: base64.StdEncoding.EncodeToString(content)}
+
+	var res struct {
+		Content giteaContent `json:"content"`
+	}
+
+	if err := g.api.do(ctx, http.MethodPost, giteaContentsPath(owner, repo, path), nil, in, &res); err != nil {
+		return nil, errors.Wrapf(err, "Gitea #CreateFile failed: owner: %s, repo: %s, branch: %s, path: %s", owner, repo, branch, path)
+	}
+
+	return &github.RepositoryContentResponse{Content: res.Content.content()}, nil
+}
+
+// UpdateFile commits the content of the file whose SHA is sha to the branch
+func (g *GiteaClient) UpdateFile(ctx context.Context, owner, repo, branch, path, sha, message string, content []byte) error {
+	in := giteaFileRequest{Branch: branch, Message: message, Content: base64.StdEncoding.EncodeToString(content), SHA: sha}
+
+	if err := g.api.do(ctx, http.MethodPut, giteaContentsPath(owner, repo, path), nil, in, nil); err != nil {
+		return errors.Wrapf(err, "Gitea #UpdateFile failed: owner: %s, repo: %s, branch: %s, path: %s", owner, repo, branch, path)
+	}
+
+	return nil
+}
+
+// DeleteFile deletes the file whose SHA is sha from the branch
+func (g *GiteaClient) DeleteFile(ctx context.Context, owner, repo, branch, path, sha, message string) error {
+	in := giteaFileRequest{Branch: branch, Message: message, SHA: sha}
+
+	if err := g.api.do(ctx, http.MethodDelete, giteaContentsPath(owner, repo, path), nil, in, nil); err != nil {
+		return errors.Wrapf(err, "Gitea #DeleteFile failed: owner: %s, repo: %s, branch: %s, path: %s", owner, repo, branch, path)
+	}
+
+	return nil
+}
+
+// CreateRepository creates a new repository under the org, or the authenticated user if org is empty.
+// Its default branch is master, where ghbr commits README.md to. Gitea cannot set the homepage on creation, so it is ignored
+func (g *GiteaClient) CreateRepository(ctx context.Context, org, name, description, homepage string, private bool) (*github.Repository, error) {
+	in := map[string]interface{}{"name": name, "description": description, "private": private, "default_branch": "master"}
+
+	path := "user/repos"
+	if len(org) != 0 {
+		path = fmt.Sprintf("orgs/%s/repos", url.PathEscape(org))
+	}
+
+	var r giteaRepository
+	if err := g.api.do(ctx, http.MethodPost, path, nil, in, &r); err != nil {
+		return nil, errors.Wrapf(err, "Gitea #CreateRepository failed: org: %s, repository name: %s", org, name)
+	}
+
+	return r.repository(), nil
+}
+
+// GetRepository gets the repository
+func (g *GiteaClient) GetRepository(ctx context.Context, owner, name string) (*github.Repository, error) {
+	var r giteaRepository
+	if err := g.api.do(ctx, http.MethodGet, giteaRepoPath(owner, name), nil, nil, &r); err != nil {
+		return nil, errors.Wrapf(err, "Gitea #GetRepository failed: owner: %s, repository name: %s", owner, name)
+	}
+
+	return r.repository(), nil
+}
+
+// GetLogin returns the login name of the authenticated user
+func (g *GiteaClient) GetLogin(ctx context.Context) (string, error) {
+	var u struct {
+		Login string `json:"login"`
+	}
+
+	if err := g.api.do(ctx, http.MethodGet, "user", nil, nil, &u); err != nil {
+		return "", errors.Wrap(err, "Gitea #GetLogin failed")
+	}
+
+	return u.Login, nil
+}
+
+// DeleteRepository deletes the repository
+func (g *GiteaClient) DeleteRepository(ctx context.Context, owner, name string) error {
+	if err := g.api.do(ctx, http.MethodDelete, giteaRepoPath(owner, name), nil, nil, nil); err != nil {
+		return errors.Wrapf(err, "Gitea #DeleteRepository failed: owner: %s, repository name: %s", owner, name)
+	}
+
+	return nil
+}
+
+// RepositoryURL returns the URL of the repository on the Gitea instance
+func (g *GiteaClient) RepositoryURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s", g.webURL, owner, repo)
+}
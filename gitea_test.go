@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func TestGiteaClient_GetLatestRelease(t *testing.T) {
+	client, mux, _, tearDown := setupGitea()
+	defer tearDown()
+
+	mux.HandleFunc(fmt.Sprintf("/api/v1/repos/%s/%s/releases/latest", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		testHeader(t, r, "Authorization", "token abcdefg")
+		fmt.Fprint(w, `{"id":1,"tag_name":"v0.0.1","name":"Release v0.0.1","tarball_url":"https://codeberg.org/shuheiktgw/ghbr/archive/v0.0.1.tar.gz","zipball_url":"https://codeberg.org/shuheiktgw/ghbr/archive/v0.0.1.zip",`+
+			`"assets":[{"id":2,"name":"ghbr_darwin_amd64.zip","browser_download_url":"https://codeberg.org/attachments/abc"}]}`)
+	})
+
+	rr, err := client.GetLatestRelease(context.Background(), TestOwner, TestRepo)
+	if err != nil {
+		t.Fatalf("#GetLatestRelease returns unexpected error: %v", err)
+	}
+
+	want := &github.RepositoryRelease{
+		TagName:    github.String("v0.0.1"),
+		Name:       github.String("Release v0.0.1"),
+		TarballURL: github.String("https://codeberg.org/shuheiktgw/ghbr/archive/v0.0.1.tar.gz"),
+		ZipballURL: github.String("https://codeberg.org/shuheiktgw/ghbr/archive/v0.0.1.zip"),
+		Assets:     []github.ReleaseAsset{{Name: github.String("ghbr_darwin_amd64.zip"), BrowserDownloadURL: github.String("https://codeberg.org/attachments/abc")}},
+	}
+
+	if !reflect.DeepEqual(rr, want) {
+		t.Errorf("#GetLatestRelease returned %+v, want %+v", rr, want)
+	}
+}
+
+func TestGiteaClient_CreateBranch_AlreadyExists(t *testing.T) {
+	client, mux, _, tearDown := setupGitea()
+	defer tearDown()
+
+	mux.HandleFunc(fmt.Sprintf("/api/v1/repos/%s/%s/branches", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		testBody(t, r, `{"new_branch_name":"develop","old_branch_name":"master"}`)
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"message":"The branch already exists."}`)
+	})
+
+	err := client.CreateBranch(context.Background(), TestOwner, TestRepo, "master", "develop")
+	if !isUnprocessable(err) {
+		t.Errorf("#CreateBranch returned %v, want 422", err)
+	}
+}
+
+func TestGiteaClient_GetFile_UpdateFile(t *testing.T) {
+	client, mux, _, tearDown := setupGitea()
+	defer tearDown()
+
+	content := base64.StdEncoding.EncodeToString([]byte("version 'v0.0.1'\n"))
+
+	mux.HandleFunc(fmt.Sprintf("/api/v1/repos/%s/%s/contents/Formula/testApp.rb", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			testFormValues(t, r, values{"ref": "master"})
+			fmt.Fprintf(w, `{"type":"file","name":"testApp.rb","path":"Formula/testApp.rb","sha":"abcdefg","encoding":"base64","content":"%s"}`, content)
+		case http.MethodPut:
+			testBody(t, r, fmt.Sprintf(`{"branch":"develop","message":"Bumps up to v0.0.2","content":"%s","sha":"abcdefg"}`, base64.StdEncoding.EncodeToString([]byte("version 'v0.0.2'\n"))))
+			fmt.Fprint(w, `{"content":{"path":"Formula/testApp.rb","sha":"hijklmn"}}`)
+		default:
+			t.Errorf("unexpected request: %s", r.Method)
+		}
+	})
+
+	rc, err := client.GetFile(context.Background(), TestOwner, TestRepo, "master", "Formula/testApp.rb")
+	if err != nil {
+		t.Fatalf("#GetFile returns unexpected error: %v", err)
+	}
+
+	if got, _ := rc.GetContent(); got != "version 'v0.0.1'\n" {
+		t.Errorf("#GetFile returned the content %q, want %q", got, "version 'v0.0.1'\n")
+	}
+
+	if err := client.UpdateFile(context.Background(), TestOwner, TestRepo, "develop", "Formula/testApp.rb", rc.GetSHA(), "Bumps up to v0.0.2", []byte("version 'v0.0.2'\n")); err != nil {
+		t.Errorf("#UpdateFile returns unexpected error: %v", err)
+	}
+}
+
+func TestGiteaClient_ListPullRequests(t *testing.T) {
+	client, mux, _, tearDown := setupGitea()
+	defer tearDown()
+
+	mux.HandleFunc(fmt.Sprintf("/api/v1/repos/%s/%s/pulls", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		if r.FormValue("page") != "1" {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+
+		testFormValues(t, r, values{"state": "open", "limit": "50", "page": "1"})
+		fmt.Fprint(w, `[{"number":1,"state":"open","head":{"ref":"develop"},"base":{"ref":"master"}},{"number":2,"state":"open","head":{"ref":"feature"},"base":{"ref":"develop"}}]`)
+	})
+
+	prs, err := client.ListPullRequests(context.Background(), TestOwner, TestRepo, "master")
+	if err != nil {
+		t.Fatalf("#ListPullRequests returns unexpected error: %v", err)
+	}
+
+	if len(prs) != 1 || prs[0].GetNumber() != 1 || prs[0].GetHead().GetRef() != "develop" {
+		t.Errorf("#ListPullRequests returned %+v, want only #1 to master", prs)
+	}
+}
+
+func TestGiteaClient_ListPullRequests_Pagination(t *testing.T) {
+	client, mux, _, tearDown := setupGitea()
+	defer tearDown()
+
+	requested := 0
+	mux.HandleFunc(fmt.Sprintf("/api/v1/repos/%s/%s/pulls", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		requested++
+		testFormValues(t, r, values{"state": "open", "limit": "50", "page": strconv.Itoa(requested)})
+
+		// MAX_RESPONSE_ITEMS of the instance is 30, so the first page is shorter than the limit although the second one follows
+		n := map[int]int{1: 30, 2: 1}[requested]
+
+		var prs []string
+		for i := 0; i < n; i++ {
+			prs = append(prs, fmt.Sprintf(`{"number":%d,"state":"open","head":{"ref":"feature%d"},"base":{"ref":"master"}}`, (requested-1)*30+i+1, i))
+		}
+
+		fmt.Fprintf(w, "[%s]", strings.Join(prs, ","))
+	})
+
+	prs, err := client.ListPullRequests(context.Background(), TestOwner, TestRepo, "master")
+	if err != nil {
+		t.Fatalf("#ListPullRequests returns unexpected error: %v", err)
+	}
+
+	if requested != 3 || len(prs) != 31 || prs[30].GetNumber() != 31 {
+		t.Errorf("#ListPullRequests requested %d pages and returned %d Pull Requests, want 3 and 31", requested, len(prs))
+	}
+}
+
+func TestGiteaClient_MergePullRequest_Retry(t *testing.T) {
+	client, mux, _, tearDown := setupGitea()
+	defer tearDown()
+
+	attempts := 0
+	mux.HandleFunc(fmt.Sprintf("/api/v1/repos/%s/%s/pulls/3/merge", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		testBody(t, r, `{"Do":"merge"}`)
+
+		// Gitea responds with 405 until it finishes checking whether the Pull Request can be merged
+		if attempts++; attempts < 2 {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			fmt.Fprint(w, `{"message":"Please try again later"}`)
+		}
+	})
+
+	if err := client.MergePullRequest(context.Background(), TestOwner, TestRepo, 3); err != nil {
+		t.Fatalf("#MergePullRequest returns unexpected error: %v", err)
+	}
+
+	if attempts != 2 {
+		t.Errorf("#MergePullRequest requested %d times, want 2", attempts)
+	}
+}
+
+func TestGiteaClient_CreateRepository_WithOrg(t *testing.T) {
+	client, mux, _, tearDown := setupGitea()
+	defer tearDown()
+
+	mux.HandleFunc("/api/v1/orgs/ghbr-org/repos", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		testBody(t, r, `{"default_branch":"master","description":"Homebrew formula for shuheiktgw/ghbr","name":"homebrew-ghbr","private":true}`)
+		fmt.Fprint(w, `{"name":"homebrew-ghbr","full_name":"ghbr-org/homebrew-ghbr","default_branch":"master","html_url":"https://codeberg.org/ghbr-org/homebrew-ghbr","private":true}`)
+	})
+
+	repo, err := client.CreateRepository(context.Background(), "ghbr-org", "homebrew-ghbr", "Homebrew formula for shuheiktgw/ghbr", "https://codeberg.org/shuheiktgw/ghbr", true)
+	if err != nil {
+		t.Fatalf("#CreateRepository returns unexpected error: %v", err)
+	}
+
+	if got, want := repo.GetFullName(), "ghbr-org/homebrew-ghbr"; got != want {
+		t.Errorf("#CreateRepository returned %s, want %s", got, want)
+	}
+}
+
+func TestGhbr_GetLatestRelease_Gitea(t *testing.T) {
+	client, mux, serverURL, tearDown := setupGitea()
+	defer tearDown()
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{Forge: client, outStream: outStream}
+
+	assetPath := "/attachments/0b1c2d"
+
+	mux.HandleFunc(fmt.Sprintf("/api/v1/repos/%s/%s/releases/latest", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"tag_name":"v0.0.1","assets":[{"name":"ghbr_v0.0.1_darwin_amd64.zip","browser_download_url":"%s%s"}]}`, serverURL, assetPath)
+	})
+
+	mux.HandleFunc(assetPath, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "test")
+	})
+
+	got, err := ghbr.GetLatestRelease(context.Background(), TestOwner, TestRepo, "", false)
+	if err != nil {
+		t.Fatalf("#GetLatestRelease returns unexpected error: %s", err)
+	}
+
+	want := &LatestRelease{version: "v0.0.1", url: serverURL + assetPath, hash: fmt.Sprintf("%x", sha256.Sum256([]byte("test")))}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("#GetLatestRelease returned %+v, want %+v", got, want)
+	}
+}
+
+func TestGhbr_CreateFormula_Gitea(t *testing.T) {
+	client, mux, serverURL, tearDown := setupGitea()
+	defer tearDown()
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{Forge: client, outStream: outStream}
+
+	mux.HandleFunc("/api/v1/user/repos", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		fmt.Fprintf(w, `{"name":"homebrew-testApp","full_name":"shuheiktgw/homebrew-testApp","html_url":"%s/shuheiktgw/homebrew-testApp"}`, serverURL)
+	})
+
+	mux.HandleFunc(fmt.Sprintf("/api/v1/repos/%s/homebrew-testApp/contents/README.md", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+
+		want := fmt.Sprintf("homebrew-testApp\n====\n\n[Homebrew](http://brew.sh/) formula for [shuheiktgw/testApp](%s/shuheiktgw/testApp)\n\n", serverURL)
+		if got := testFileContent(t, r); got != want {
+			t.Errorf("README.md is %q, want %q", got, want)
+		}
+
+		fmt.Fprint(w, `{"content":{"path":"README.md"}}`)
+	})
+
+	mux.HandleFunc(fmt.Sprintf("/api/v1/repos/%s/homebrew-testApp/contents/testApp.rb", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		fmt.Fprint(w, `{"content":{"path":"testApp.rb"}}`)
+	})
+
+	release := LatestRelease{
+		version: "v0.0.1",
+		url:     "https://codeberg.org/attachments/0b1c2d",
+		hash:    "0001123456789012345678901234567890123456789012345678901234567890",
+	}
+
+	if err := ghbr.CreateFormula(context.Background(), Tap{}, TestOwner, "testApp", false, FormulaOptions{}, &release); err != nil {
+		t.Fatalf("#CreateFormula returns unexpected error: %s", err)
+	}
+
+	// The homepage points to the Gitea instance, which the test server serves over http
+	expectedOutput := fmt.Sprintf("[ghbr] ===> testApp.rb:4: warning: `homepage` %s/shuheiktgw/testApp is not https\n", serverURL) +
+		"[ghbr] ===> Creating a repository\n" +
+		"[ghbr] ===> Adding README.md to the repository\n" +
+		"[ghbr] ===> Adding testApp.rb to the repository\n" +
+		"\n\n" +
+		"Yay! Your Homebrew formula repository has been successfully created!\n" +
+		fmt.Sprintf("Access %s/shuheiktgw/homebrew-testApp and see what we achieved.\n\n", serverURL)
+
+	if got := outStream.String(); got != expectedOutput {
+		t.Errorf("#CreateFormula outputed %+v, want %+v", got, expectedOutput)
+	}
+}
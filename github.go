@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/github"
@@ -9,37 +12,79 @@ import (
 	"golang.org/x/oauth2"
 )
 
+// defaultGitHubURL is the URL of GitHub.com for humans
+const defaultGitHubURL = "https://github.com"
+
 // GitHubClient is a clint to interact with Github API
 type GitHubClient struct {
 	Client *github.Client
+
+	// webURL is the URL of GitHub.com or a GitHub Enterprise instance for humans
+	webURL string
+
+	// app is true if the client is authenticated as an installation of a GitHub App, which is not a user
+	app bool
+
+	logger *Logger
 }
 
-// NewGitHubClient creates and initializes a new GitHubClient
-func NewGitHubClient(token string) *GitHubClient {
-	ts := oauth2.StaticTokenSource(&oauth2.Token{
-		AccessToken: token,
-	})
-	tc := oauth2.NewClient(context.TODO(), ts)
+// NewGitHubClient creates and initializes a new GitHubClient, API requests are logged through the logger unless it is nil
+func NewGitHubClient(token string, logger *Logger) *GitHubClient {
+	return newGitHubClient(defaultGitHubURL, newGitHubTransport(staticTokenSource(token), logger), logger)
+}
+
+// NewGitHubEnterpriseClient creates a new GitHubClient of the GitHub Enterprise instance at the webURL like https://github.example.com,
+// which is expected to be validated by validateForge in advance
+func NewGitHubEnterpriseClient(webURL, token string, logger *Logger) *GitHubClient {
+	return newGitHubClient(webURL, newGitHubTransport(staticTokenSource(token), logger), logger)
+}
+
+// newGitHubClient creates a GitHubClient of GitHub.com or the GitHub Enterprise instance at the webURL, which sends requests through the transport
+func newGitHubClient(webURL string, transport http.RoundTripper, logger *Logger) *GitHubClient {
+	hc := &http.Client{Transport: transport}
+
+	webURL = strings.TrimSuffix(webURL, "/")
+	if webURL == defaultGitHubURL {
+		return &GitHubClient{Client: github.NewClient(hc), webURL: webURL, logger: logger}
+	}
 
-	client := github.NewClient(tc)
+	client, err := github.NewEnterpriseClient(webURL+"/api/v3/", webURL+"/api/uploads/", hc)
+	if err != nil {
+		// Never happens since the webURL is a valid URL
+		panic(err)
+	}
+
+	return &GitHubClient{Client: client, webURL: webURL, logger: logger}
+}
 
-	return &GitHubClient{Client: client}
+// newGitHubTransport creates a transport authenticating with tokens from the ts, which retries transient failures and logs requests
+func newGitHubTransport(ts oauth2.TokenSource, logger *Logger) http.RoundTripper {
+	return &oauth2.Transport{Source: ts, Base: newRetryTransport(newLoggingTransport(http.DefaultTransport, logger), logger)}
+}
+
+func staticTokenSource(token string) oauth2.TokenSource {
+	return oauth2.StaticTokenSource(&oauth2.Token{
+		AccessToken: token,
+	})
 }
 
 // GetLatestRelease returns the latest release of the given Repository
-func (g *GitHubClient) GetLatestRelease(owner, repo string) (*github.RepositoryRelease, error) {
-	rr, _, err := g.Client.Repositories.GetLatestRelease(context.TODO(), owner, repo)
+func (g *GitHubClient) GetLatestRelease(ctx context.Context, owner, repo string) (*github.RepositoryRelease, error) {
+	rr, _, err := g.Client.Repositories.GetLatestRelease(ctx, owner, repo)
 
 	if err != nil {
 		return nil, errors.Wrapf(err, "#Repositories.GetLatestRelease failed: owner: %s, repo: %s", owner, repo)
 	}
 
+	// tarball_url points to the API, while Homebrew expects the stable archive of the tag on the web
+	rr.TarballURL = github.String(fmt.Sprintf("%s/archive/refs/tags/%s.tar.gz", g.RepositoryURL(owner, repo), rr.GetTagName()))
+
 	return rr, err
 }
 
 // CreateBranch creates a new branch from the heads of the origin
-func (g *GitHubClient) CreateBranch(owner, repo, origin, new string) error {
-	originRef, _, err := g.Client.Git.GetRef(context.TODO(), owner, repo, "heads/"+origin)
+func (g *GitHubClient) CreateBranch(ctx context.Context, owner, repo, origin, new string) error {
+	originRef, _, err := g.Client.Git.GetRef(ctx, owner, repo, "heads/"+origin)
 
 	if err != nil {
 		return errors.Wrapf(err, "#Git.GetRef failed: owner: %s, repo: %s", owner, repo)
@@ -52,7 +97,7 @@ func (g *GitHubClient) CreateBranch(owner, repo, origin, new string) error {
 		},
 	}
 
-	_, _, err = g.Client.Git.CreateRef(context.TODO(), owner, repo, newRef)
+	_, _, err = g.Client.Git.CreateRef(ctx, owner, repo, newRef)
 
 	if err != nil {
 		return errors.Wrapf(err, "#Git.CreateRef failed: owner: %s, repo: %s, ref: %v", owner, repo, newRef)
@@ -62,8 +107,8 @@ func (g *GitHubClient) CreateBranch(owner, repo, origin, new string) error {
 }
 
 // DeleteLatestRef deletes the latest Ref of the given branch, intended to be used for rollbacks
-func (g *GitHubClient) DeleteLatestRef(owner, repo, branch string) error {
-	_, err := g.Client.Git.DeleteRef(context.TODO(), owner, repo, "heads/"+branch)
+func (g *GitHubClient) DeleteLatestRef(ctx context.Context, owner, repo, branch string) error {
+	_, err := g.Client.Git.DeleteRef(ctx, owner, repo, "heads/"+branch)
 
 	if err != nil {
 		return errors.Wrapf(err, "#Git.DeleteRef failed to delete the latest ref of %s branch", branch)
@@ -73,10 +118,10 @@ func (g *GitHubClient) DeleteLatestRef(owner, repo, branch string) error {
 }
 
 // CreatePullRequest creates Pull Request
-func (g *GitHubClient) CreatePullRequest(owner, repo, title, head, base, body string) (*github.PullRequest, error) {
+func (g *GitHubClient) CreatePullRequest(ctx context.Context, owner, repo, title, head, base, body string) (*github.PullRequest, error) {
 	opt := &github.NewPullRequest{Title: &title, Head: &head, Base: &base, Body: &body}
 
-	pr, _, err := g.Client.PullRequests.Create(context.TODO(), owner, repo, opt)
+	pr, _, err := g.Client.PullRequests.Create(ctx, owner, repo, opt)
 
 	if err != nil {
 		return nil, err
@@ -85,13 +130,30 @@ func (g *GitHubClient) CreatePullRequest(owner, repo, title, head, base, body st
 	return pr, nil
 }
 
+// ListPullRequests lists open Pull Requests to the base branch
+func (g *GitHubClient) ListPullRequests(ctx context.Context, owner, repo, base string) ([]*github.PullRequest, error) {
+	opt := &github.PullRequestListOptions{State: "open", Base: base, ListOptions: github.ListOptions{PerPage: 100}}
+
+	prs, _, err := g.Client.PullRequests.List(ctx, owner, repo, opt)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "#PullRequests.List failed: owner: %s, repo: %s, base: %s", owner, repo, base)
+	}
+
+	return prs, nil
+}
+
 // MergePullRequest merges Pull Request with a give Pull Request number
-func (g *GitHubClient) MergePullRequest(owner, repo string, number int) error {
+func (g *GitHubClient) MergePullRequest(ctx context.Context, owner, repo string, number int) error {
 	// Wait a few seconds to prevent GitHub API returns `405 Base branch was modified`
 	// TODO Move this code to the client side
-	time.Sleep(3 * time.Second)
+	select {
+	case <-time.After(3 * time.Second):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 
-	_, _, err := g.Client.PullRequests.Merge(context.TODO(), owner, repo, number, "", nil)
+	_, _, err := g.Client.PullRequests.Merge(ctx, owner, repo, number, "", nil)
 
 	if err != nil {
 		return err
@@ -100,11 +162,22 @@ func (g *GitHubClient) MergePullRequest(owner, repo string, number int) error {
 	return nil
 }
 
+// GetPullRequest gets the Pull Request with a given Pull Request number
+func (g *GitHubClient) GetPullRequest(ctx context.Context, owner, repo string, number int) (*github.PullRequest, error) {
+	pr, _, err := g.Client.PullRequests.Get(ctx, owner, repo, number)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "#PullRequests.Get failed: owner: %s, repo: %s, number: %d", owner, repo, number)
+	}
+
+	return pr, nil
+}
+
 // ClosePullRequest closes Pull Request with a give Pull Request number
-func (g *GitHubClient) ClosePullRequest(owner, repo string, number int) error {
-	pr := &github.PullRequest{State: github.String("close")}
+func (g *GitHubClient) ClosePullRequest(ctx context.Context, owner, repo string, number int) error {
+	pr := &github.PullRequest{State: github.String("closed")}
 
-	_, _, err := g.Client.PullRequests.Edit(context.TODO(), owner, repo, number, pr)
+	_, _, err := g.Client.PullRequests.Edit(ctx, owner, repo, number, pr)
 
 	if err != nil {
 		return errors.Wrapf(err, "#PullRequests.Edit failed to close Pull Request: owner: %s, repo: %s, number: %d", owner, repo, number)
@@ -114,10 +187,10 @@ func (g *GitHubClient) ClosePullRequest(owner, repo string, number int) error {
 }
 
 // GetFile gets the specified file on GitHub
-func (g *GitHubClient) GetFile(owner, repo, branch, path string) (*github.RepositoryContent, error) {
+func (g *GitHubClient) GetFile(ctx context.Context, owner, repo, branch, path string) (*github.RepositoryContent, error) {
 	opt := &github.RepositoryContentGetOptions{Ref: branch}
 
-	file, _, _, err := g.Client.Repositories.GetContents(context.TODO(), owner, repo, path, opt)
+	file, _, _, err := g.Client.Repositories.GetContents(ctx, owner, repo, path, opt)
 
 	if err != nil {
 		return nil, errors.Wrapf(err, "#Repositories.GetContents failed: repository name: %s, branch name: %s, file path: %s", repo, branch, path)
@@ -126,11 +199,24 @@ func (g *GitHubClient) GetFile(owner, repo, branch, path string) (*github.Reposi
 	return file, nil
 }
 
+// GetDirectory gets contents of a directory on GitHub, it returns nil if the path is a file
+func (g *GitHubClient) GetDirectory(ctx context.Context, owner, repo, branch, path string) ([]*github.RepositoryContent, error) {
+	opt := &github.RepositoryContentGetOptions{Ref: branch}
+
+	_, dir, _, err := g.Client.Repositories.GetContents(ctx, owner, repo, path, opt)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "#Repositories.GetContents failed: repository name: %s, branch name: %s, directory path: %s", repo, branch, path)
+	}
+
+	return dir, nil
+}
+
 // CreateFile create a file with a given content on GitHub
-func (g *GitHubClient) CreateFile(owner, repo, branch, path, message string, content []byte) (*github.RepositoryContentResponse, error) {
+func (g *GitHubClient) CreateFile(ctx context.Context, owner, repo, branch, path, message string, content []byte) (*github.RepositoryContentResponse, error) {
 	opt := &github.RepositoryContentFileOptions{Message: &message, Content: content, Branch: &branch}
 
-	rc, _, err := g.Client.Repositories.CreateFile(context.TODO(), owner, repo, path, opt)
+	rc, _, err := g.Client.Repositories.CreateFile(ctx, owner, repo, path, opt)
 
 	if err != nil {
 		return nil, errors.Wrapf(err, "#Repositories.CreateFile failed: owner: %s, repo: %s, branch: %s, path: %s", owner, repo, branch, path)
@@ -140,10 +226,10 @@ func (g *GitHubClient) CreateFile(owner, repo, branch, path, message string, con
 }
 
 // UpdateFile updates a file on GitHub with a given content
-func (g *GitHubClient) UpdateFile(owner, repo, branch, path, sha, message string, content []byte) error {
+func (g *GitHubClient) UpdateFile(ctx context.Context, owner, repo, branch, path, sha, message string, content []byte) error {
 	opt := &github.RepositoryContentFileOptions{Message: &message, Content: content, SHA: &sha, Branch: &branch}
 
-	_, _, err := g.Client.Repositories.UpdateFile(context.TODO(), owner, repo, path, opt)
+	_, _, err := g.Client.Repositories.UpdateFile(ctx, owner, repo, path, opt)
 
 	if err != nil {
 		return errors.Wrapf(err, "#Repositories.UpdateFile failed: repo: %s, branch: %s, path: %s", repo, branch, path)
@@ -153,10 +239,10 @@ func (g *GitHubClient) UpdateFile(owner, repo, branch, path, sha, message string
 }
 
 // DeleteFile deletes a file on GitHub
-func (g *GitHubClient) DeleteFile(owner, repo, branch, path, sha, message string) error {
+func (g *GitHubClient) DeleteFile(ctx context.Context, owner, repo, branch, path, sha, message string) error {
 	opt := &github.RepositoryContentFileOptions{Message: &message, SHA: &sha, Branch: &branch}
 
-	_, _, err := g.Client.Repositories.DeleteFile(context.TODO(), owner, repo, path, opt)
+	_, _, err := g.Client.Repositories.DeleteFile(ctx, owner, repo, path, opt)
 
 	if err != nil {
 		return errors.Wrapf(err, "#Repositories.DeleteFile failed: repo: %s, branch: %s, path: %s", repo, branch, path)
@@ -166,7 +252,7 @@ func (g *GitHubClient) DeleteFile(owner, repo, branch, path, sha, message string
 }
 
 // CreateRepository creates a new GitHub repository
-func (g *GitHubClient) CreateRepository(org, name, description, homepage string, private bool) (*github.Repository, error) {
+func (g *GitHubClient) CreateRepository(ctx context.Context, org, name, description, homepage string, private bool) (*github.Repository, error) {
 	opt := &github.Repository{
 		Name:        &name,
 		Description: &description,
@@ -174,7 +260,7 @@ func (g *GitHubClient) CreateRepository(org, name, description, homepage string,
 		Private:     &private,
 	}
 
-	repo, _, err := g.Client.Repositories.Create(context.TODO(), org, opt)
+	repo, _, err := g.Client.Repositories.Create(ctx, org, opt)
 
 	if err != nil {
 		return nil, errors.Wrapf(err, "#Repositories.Create failed: repository org: %s, name: %s", org, name)
@@ -183,9 +269,36 @@ func (g *GitHubClient) CreateRepository(org, name, description, homepage string,
 	return repo, nil
 }
 
+// GetRepository gets a GitHub repository
+func (g *GitHubClient) GetRepository(ctx context.Context, owner, name string) (*github.Repository, error) {
+	repo, _, err := g.Client.Repositories.Get(ctx, owner, name)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "#Repositories.Get failed: repository owner: %s, name: %s", owner, name)
+	}
+
+	return repo, nil
+}
+
+// GetLogin gets the login name of the authenticated user, which is empty for GitHub Apps
+func (g *GitHubClient) GetLogin(ctx context.Context) (string, error) {
+	// Installations of GitHub Apps are not users, GET /user is forbidden for them
+	if g.app {
+		return "", nil
+	}
+
+	user, _, err := g.Client.Users.Get(ctx, "")
+
+	if err != nil {
+		return "", errors.Wrap(err, "#Users.Get failed")
+	}
+
+	return user.GetLogin(), nil
+}
+
 // DeleteRepository deletes a GitHub repository
-func (g *GitHubClient) DeleteRepository(owner, name string) error {
-	_, err := g.Client.Repositories.Delete(context.TODO(), owner, name)
+func (g *GitHubClient) DeleteRepository(ctx context.Context, owner, name string) error {
+	_, err := g.Client.Repositories.Delete(ctx, owner, name)
 
 	if err != nil {
 		return errors.Wrapf(err, "#Repositories.Delete failed: repository name: %s", name)
@@ -193,3 +306,8 @@ func (g *GitHubClient) DeleteRepository(owner, name string) error {
 
 	return nil
 }
+
+// RepositoryURL returns the URL of the repository on GitHub.com or the GitHub Enterprise instance
+func (g *GitHubClient) RepositoryURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s", g.webURL, owner, repo)
+}
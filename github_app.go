@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// Environment variables configuring GitHub App authentication
+const (
+	EnvGitHubAppID             = "GHBR_APP_ID"
+	EnvGitHubAppInstallationID = "GHBR_APP_INSTALLATION_ID"
+	EnvGitHubAppPrivateKey     = "GHBR_APP_PRIVATE_KEY"
+)
+
+const (
+	// appJWTLifetime is how long a JWT of a GitHub App is valid, which GitHub allows up to 10 minutes
+	appJWTLifetime = 9 * time.Minute
+
+	// appClockSkew backdates JWTs to tolerate the clock of ghbr running ahead of GitHub
+	appClockSkew = time.Minute
+
+	// installationTokenMargin is how long before its expiry an installation token is renewed,
+	// so that a request does not start with a token about to expire
+	installationTokenMargin = 5 * time.Minute
+)
+
+// GitHubApp is a GitHub App ghbr authenticates as instead of a personal access token
+type GitHubApp struct {
+	ID int64
+
+	// InstallationID is the installation of the app used for every request,
+	// the installation on the owner of the repository is discovered for each request if it is 0
+	InstallationID int64
+
+	PrivateKey *rsa.PrivateKey
+}
+
+// LoadGitHubApp loads the private key of the app from the PEM file at the keyPath
+func LoadGitHubApp(id, installationID int64, keyPath string) (*GitHubApp, error) {
+	b, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the private key of GitHub App %d", id)
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.Errorf("%s is not a PEM encoded private key", keyPath)
+	}
+
+	// Keys downloaded from GitHub are in PKCS #1, while those converted by tools like openssl may be in PKCS #8
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		k, err8 := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err8 != nil {
+			return nil, errors.Wrapf(err, "failed to parse the private key in %s", keyPath)
+		}
+
+		rk, ok := k.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.Errorf("%s is not an RSA private key", keyPath)
+		}
+
+		key = rk
+	}
+
+	return &GitHubApp{ID: id, InstallationID: installationID, PrivateKey: key}, nil
+}
+
+// validateGitHubApp validates the flags of GitHub App authentication
+func validateGitHubApp(id, installationID int64, keyPath, forge, tapForge string) error {
+	if id == 0 {
+		if installationID != 0 || len(keyPath) != 0 {
+			return errors.New("--app-installation-id and --app-private-key can be used only with --app-id\n")
+		}
+
+		return nil
+	}
+
+	if len(keyPath) == 0 {
+		return fmt.Errorf("missing the private key of GitHub App %d\n\n"+
+			"Please set the path of it via `--app-private-key` option or %s environment variable\n", id, EnvGitHubAppPrivateKey)
+	}
+
+	if forge != ForgeGitHub && tapForge != ForgeGitHub {
+		return errors.New("--app-id can be used only with github\n")
+	}
+
+	return nil
+}
+
+// validateAppOrg validates the tap is owned by an organization passed via --org or --tap if the tap forge is authenticated as a GitHub App
+func validateAppOrg(org, tap string) error {
+	tapForge := globalOpts.tapForge
+	if len(tapForge) == 0 {
+		tapForge = globalOpts.forge
+	}
+
+	if globalOpts.app == nil || len(globalOpts.tapPath) != 0 || tapForge != ForgeGitHub || len(parseTap(org, tap, "").Owner) != 0 {
+		return nil
+	}
+
+	return fmt.Errorf("missing the organization hosting the tap\n\n"+
+		"GitHub App %d cannot create a tap under a user, please set the organization via `--org` option or `--tap` in owner/name form\n", globalOpts.app.ID)
+}
+
+// JWT returns a JSON Web Token the app authenticates as itself with, which is valid until the expiry
+func (a *GitHubApp) JWT(now time.Time) (jwt string, expiry time.Time, err error) {
+	expiry = now.Add(appJWTLifetime)
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]int64{
+		"iat": now.Add(-appClockSkew).Unix(),
+		"exp": expiry.Unix(),
+		"iss": a.ID,
+	})
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	h := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.PrivateKey, crypto.SHA256, h[:])
+	if err != nil {
+		return "", time.Time{}, errors.Wrapf(err, "failed to sign a JWT of GitHub App %d", a.ID)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), expiry, nil
+}
+
+// appJWTSource is a token source of JWTs of the app
+type appJWTSource struct {
+	app *GitHubApp
+}
+
+func (s appJWTSource) Token() (*oauth2.Token, error) {
+	jwt, expiry, err := s.app.JWT(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &oauth2.Token{AccessToken: jwt, TokenType: "Bearer", Expiry: expiry}, nil
+}
+
+// installationTokenSource is a token source of installation tokens of an installation of the app
+type installationTokenSource struct {
+	ctx            context.Context
+	client         *github.Client
+	installationID int64
+}
+
+func (s installationTokenSource) Token() (*oauth2.Token, error) {
+	// Apps.CreateInstallationToken of go-github still uses the endpoint without app/, which GitHub has retired
+	req, err := s.client.NewRequest(http.MethodPost, fmt.Sprintf("app/installations/%d/access_tokens", s.installationID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	t := new(github.InstallationToken)
+	if _, err := s.client.Do(s.ctx, req, t); err != nil {
+		return nil, errors.Wrapf(err, "failed to create an installation token: installation: %d", s.installationID)
+	}
+
+	return &oauth2.Token{AccessToken: t.GetToken(), TokenType: "token", Expiry: t.GetExpiresAt().Add(-installationTokenMargin)}, nil
+}
+
+// appTransport authenticates requests as an installation of the app, installation tokens are renewed before they expire
+type appTransport struct {
+	app  *GitHubApp
+	base http.RoundTripper
+
+	// jwtClient is authenticated as the app itself to find installations and create installation tokens
+	jwtClient *github.Client
+
+	mu sync.Mutex
+
+	// sources are token sources of installations by the owners they are installed on
+	sources map[string]oauth2.TokenSource
+}
+
+// NewGitHubAppClient creates a new GitHubClient of GitHub.com, or the GitHub Enterprise instance at the webURL unless it is empty,
+// authenticating as an installation of the app. API requests are logged through the logger unless it is nil
+func NewGitHubAppClient(webURL string, app *GitHubApp, logger *Logger) *GitHubClient {
+	if len(webURL) == 0 {
+		webURL = defaultGitHubURL
+	}
+
+	base := newRetryTransport(newLoggingTransport(http.DefaultTransport, logger), logger)
+	jwtClient := newGitHubClient(webURL, &oauth2.Transport{Source: oauth2.ReuseTokenSource(nil, appJWTSource{app: app}), Base: base}, logger)
+
+	client := newGitHubClient(webURL, &appTransport{app: app, base: base, jwtClient: jwtClient.Client, sources: make(map[string]oauth2.TokenSource)}, logger)
+	client.app = true
+
+	return client
+}
+
+func (t *appTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ts, err := t.tokenSource(req.Context(), requestOwner(req.URL.Path))
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := ts.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	// RoundTrippers must not modify the request
+	r := req.WithContext(req.Context())
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+
+	r.Header.Set("Authorization", "token "+token.AccessToken)
+
+	return t.base.RoundTrip(r)
+}
+
+// tokenSource returns the token source of the installation on the owner, which is the only one of the app if the owner is empty
+func (t *appTransport) tokenSource(ctx context.Context, owner string) (oauth2.TokenSource, error) {
+	if t.app.InstallationID != 0 {
+		owner = ""
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if ts, ok := t.sources[owner]; ok {
+		return ts, nil
+	}
+
+	id, err := t.findInstallation(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	// Installation tokens are created outside of requests, so that they outlive the context of the first request
+	ts := oauth2.ReuseTokenSource(nil, installationTokenSource{ctx: context.Background(), client: t.jwtClient, installationID: id})
+	t.sources[owner] = ts
+
+	return ts, nil
+}
+
+// findInstallation finds the installation of the app on the owner, which is either an organization or a user
+func (t *appTransport) findInstallation(ctx context.Context, owner string) (int64, error) {
+	if t.app.InstallationID != 0 {
+		return t.app.InstallationID, nil
+	}
+
+	if len(owner) == 0 {
+		installations, _, err := t.jwtClient.Apps.ListInstallations(ctx, nil)
+		if err != nil {
+			return 0, errors.Wrap(err, "#Apps.ListInstallations failed")
+		}
+
+		if len(installations) != 1 {
+			return 0, &HandledError{Message: fmt.Sprintf("GitHub App %d has %d installations, please set the one to use via `--app-installation-id` option", t.app.ID, len(installations))}
+		}
+
+		return installations[0].GetID(), nil
+	}
+
+	installation, _, err := t.jwtClient.Apps.FindOrganizationInstallation(ctx, owner)
+	if isNotFound(err) {
+		installation, _, err = t.jwtClient.Apps.FindUserInstallation(ctx, owner)
+	}
+
+	if isNotFound(err) {
+		return 0, &HandledError{Message: fmt.Sprintf("GitHub App %d is not installed on %s", t.app.ID, owner)}
+	}
+
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to find the installation of GitHub App %d on %s", t.app.ID, owner)
+	}
+
+	return installation.GetID(), nil
+}
+
+// requestOwner returns the owner of the repository, the organization or the user the API request at the path is about,
+// or an empty string if it is not about any of them like /user
+func requestOwner(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i+1 < len(segments); i++ {
+		switch segments[i] {
+		case "repos", "orgs", "users":
+			return segments[i+1]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testGitHubApp generates a GitHub App with a new private key, which is also saved to a temporary file
+func testGitHubApp(t *testing.T) (app *GitHubApp, keyPath string, tearDown func()) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Error generating a private key: %s", err)
+	}
+
+	f, err := ioutil.TempFile("", "ghbr")
+	if err != nil {
+		t.Fatalf("Error creating a temporary file: %s", err)
+	}
+
+	pem.Encode(f, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	f.Close()
+
+	return &GitHubApp{ID: 42, PrivateKey: key}, f.Name(), func() { os.Remove(f.Name()) }
+}
+
+func TestLoadGitHubApp(t *testing.T) {
+	want, keyPath, tearDown := testGitHubApp(t)
+	defer tearDown()
+
+	app, err := LoadGitHubApp(42, 7, keyPath)
+	if err != nil {
+		t.Fatalf("#LoadGitHubApp returns unexpected error: %s", err)
+	}
+
+	if app.ID != 42 || app.InstallationID != 7 || app.PrivateKey.N.Cmp(want.PrivateKey.N) != 0 {
+		t.Errorf("#LoadGitHubApp returned %+v", app)
+	}
+
+	if _, err := LoadGitHubApp(42, 0, "not_exist.pem"); err == nil {
+		t.Errorf("#LoadGitHubApp expected to fail with a missing key")
+	}
+}
+
+func TestGitHubApp_JWT(t *testing.T) {
+	app, _, tearDown := testGitHubApp(t)
+	defer tearDown()
+
+	now := time.Unix(1500000000, 0)
+
+	jwt, expiry, err := app.JWT(now)
+	if err != nil {
+		t.Fatalf("#JWT returns unexpected error: %s", err)
+	}
+
+	if want := now.Add(9 * time.Minute); !expiry.Equal(want) {
+		t.Errorf("#JWT expires at %s, want %s", expiry, want)
+	}
+
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("#JWT returned %s, which is not a JWT", jwt)
+	}
+
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	h := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&app.PrivateKey.PublicKey, crypto.SHA256, h[:], sig); err != nil {
+		t.Errorf("#JWT returned an invalid signature: %s", err)
+	}
+
+	var claims map[string]int64
+	b, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	if err := json.Unmarshal(b, &claims); err != nil {
+		t.Fatalf("Error decoding claims: %s", err)
+	}
+
+	if claims["iss"] != 42 || claims["iat"] != 1499999940 || claims["exp"] != 1500000540 {
+		t.Errorf("#JWT returned claims %+v", claims)
+	}
+}
+
+func TestNewGitHubAppClient(t *testing.T) {
+	app, _, tearDown := testGitHubApp(t)
+	defer tearDown()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewGitHubAppClient(server.URL, app, nil)
+
+	testJWT := func(r *http.Request) {
+		if auth := r.Header.Get("Authorization"); !strings.HasPrefix(auth, "Bearer ey") {
+			t.Errorf("%s %s is not authenticated with a JWT: %s", r.Method, r.URL.Path, auth)
+		}
+	}
+
+	// The installation on the owner is discovered, which is a user rather than an organization
+	mux.HandleFunc(fmt.Sprintf("/api/v3/orgs/%s/installation", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		testJWT(r)
+		http.NotFound(w, r)
+	})
+
+	discovered := 0
+	mux.HandleFunc(fmt.Sprintf("/api/v3/users/%s/installation", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		testJWT(r)
+		discovered++
+		fmt.Fprint(w, `{"id":7}`)
+	})
+
+	// Tokens expiring within the margin are renewed for every request
+	created := 0
+	mux.HandleFunc("/api/v3/app/installations/7/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		testJWT(r)
+		created++
+		fmt.Fprintf(w, `{"token":"v1.token%d","expires_at":"%s"}`, created, time.Now().Add(time.Minute).Format(time.RFC3339))
+	})
+
+	mux.HandleFunc(fmt.Sprintf("/api/v3/repos/%s/%s/releases/latest", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		testHeader(t, r, "Authorization", fmt.Sprintf("token v1.token%d", created))
+		fmt.Fprint(w, `{"tag_name":"v0.0.1"}`)
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetLatestRelease(context.Background(), TestOwner, TestRepo); err != nil {
+			t.Fatalf("#GetLatestRelease returns unexpected error: %s", err)
+		}
+	}
+
+	if discovered != 1 || created != 2 {
+		t.Errorf("the installation is discovered %d times and its token is created %d times, want 1 and 2", discovered, created)
+	}
+}
+
+func TestNewGitHubAppClient_NotInstalled(t *testing.T) {
+	app, _, tearDown := testGitHubApp(t)
+	defer tearDown()
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := NewGitHubAppClient(server.URL, app, nil).GetLatestRelease(context.Background(), TestOwner, TestRepo)
+	if err == nil || !strings.Contains(err.Error(), "GitHub App 42 is not installed on shuheiktgw") {
+		t.Errorf("#GetLatestRelease returned %v, want an error telling the app is not installed", err)
+	}
+}
+
+func TestGhbr_CreateFormula_GitHubApp(t *testing.T) {
+	app, _, tearDown := testGitHubApp(t)
+	defer tearDown()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{Forge: NewGitHubAppClient(server.URL, app, nil), outStream: outStream}
+
+	mux.HandleFunc("/api/v3/orgs/TestOrg/installation", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":7}`)
+	})
+
+	mux.HandleFunc("/api/v3/app/installations/7/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"token":"v1.token","expires_at":"%s"}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+	})
+
+	// Installations are not users, so GitHub forbids them to get the authenticated user
+	mux.HandleFunc("/api/v3/user", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("#CreateFormula requested GET /user, which is forbidden for GitHub Apps")
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	// Mock GetRepository request
+	mux.HandleFunc("/api/v3/repos/TestOrg/homebrew-testApp", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		http.NotFound(w, r)
+	})
+
+	// Mock CreateRepository request, the tap is created under the organization
+	mux.HandleFunc("/api/v3/orgs/TestOrg/repos", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		testHeader(t, r, "Authorization", "token v1.token")
+		fmt.Fprint(w, `{"html_url":"https://github.com/TestOrg/homebrew-testApp"}`)
+	})
+
+	// Mock CreateFile requests for README.md and formula file
+	mux.HandleFunc("/api/v3/repos/TestOrg/homebrew-testApp/contents/", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPut)
+		testHeader(t, r, "Authorization", "token v1.token")
+	})
+
+	release := LatestRelease{
+		version: "v0.0.1",
+		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip",
+		hash:    "0001123456789012345678901234567890123456789012345678901234567890",
+	}
+
+	if err := ghbr.CreateFormula(context.Background(), Tap{Owner: "TestOrg"}, TestOwner, "testApp", false, FormulaOptions{}, &release); err != nil {
+		t.Fatalf("#CreateFormula returns unexpected error: %s", err)
+	}
+
+	if want := "Access https://github.com/TestOrg/homebrew-testApp"; !strings.Contains(outStream.String(), want) {
+		t.Errorf("#CreateFormula outputed %q, want it to contain %q", outStream.String(), want)
+	}
+}
+
+func TestRequestOwner(t *testing.T) {
+	cases := map[string]string{
+		"/repos/shuheiktgw/ghbr/releases/latest":        "shuheiktgw",
+		"/api/v3/repos/shuheiktgw/ghbr/contents/a.rb":   "shuheiktgw",
+		"/orgs/ghbr-org/repos":                          "ghbr-org",
+		"/user/repos":                                   "",
+		"/user":                                         "",
+		"/api/uploads/repos/shuheiktgw/ghbr/releases/1": "shuheiktgw",
+	}
+
+	for path, want := range cases {
+		if got := requestOwner(path); got != want {
+			t.Errorf("requestOwner(%q) returned %q, want %q", path, got, want)
+		}
+	}
+}
@@ -3,6 +3,7 @@
 package main
 
 import (
+	"context"
 	"encoding/base64"
 	"os"
 	"testing"
@@ -17,13 +18,13 @@ const (
 
 func testGitHubClient() *GitHubClient {
 	token := os.Getenv(IntegrationTestGitHubToken)
-	return NewGitHubClient(token)
+	return NewGitHubClient(token, nil)
 }
 
 func TestGetLatestReleaseFail(t *testing.T) {
 	c := testGitHubClient()
 
-	if _, err := c.GetLatestRelease(IntegrationTestOwner, "unknown"); err == nil {
+	if _, err := c.GetLatestRelease(context.Background(), IntegrationTestOwner, "unknown"); err == nil {
 		t.Fatalf("#GetLatestRelease did not return error")
 	}
 }
@@ -31,7 +32,7 @@ func TestGetLatestReleaseFail(t *testing.T) {
 func TestGetLatestReleaseSuccess(t *testing.T) {
 	c := testGitHubClient()
 
-	if _, err := c.GetLatestRelease(IntegrationTestOwner, IntegrationTestRepo); err != nil {
+	if _, err := c.GetLatestRelease(context.Background(), IntegrationTestOwner, IntegrationTestRepo); err != nil {
 		t.Fatalf("GetLatestRelease: unexpected error occured: %s", err)
 	}
 }
@@ -47,8 +48,8 @@ func TestCreateBranchFail(t *testing.T) {
 	for i, tc := range cases {
 		c := testGitHubClient()
 
-		if err := c.CreateBranch(IntegrationTestOwner, tc.repo, tc.origin, tc.new); err == nil {
-			if e := c.DeleteLatestRef(IntegrationTestOwner, tc.repo, tc.new); e != nil {
+		if err := c.CreateBranch(context.Background(), IntegrationTestOwner, tc.repo, tc.origin, tc.new); err == nil {
+			if e := c.DeleteLatestRef(context.Background(), IntegrationTestOwner, tc.repo, tc.new); e != nil {
 				t.Errorf("#%d #DeleteLatestRef failed to rollback CreateBranch: %s", i, e)
 			}
 			t.Fatalf("#%d #CreateBranch did not return error", i)
@@ -67,7 +68,7 @@ func TestDeleteLatestRefFail(t *testing.T) {
 	for i, tc := range cases {
 		c := testGitHubClient()
 
-		if err := c.DeleteLatestRef(IntegrationTestOwner, tc.repo, tc.branch); err == nil {
+		if err := c.DeleteLatestRef(context.Background(), IntegrationTestOwner, tc.repo, tc.branch); err == nil {
 			t.Fatalf("#%d #DeleteLatestRef did not return error", i)
 		}
 	}
@@ -76,13 +77,13 @@ func TestDeleteLatestRefFail(t *testing.T) {
 func TestCreateAndDeleteBranch(t *testing.T) {
 	c := testGitHubClient()
 
-	err := c.CreateBranch(IntegrationTestOwner, IntegrationTestRepo, "master", "test")
+	err := c.CreateBranch(context.Background(), IntegrationTestOwner, IntegrationTestRepo, "master", "test")
 
 	if err != nil {
 		t.Fatalf("#CreateBranch returns unexpected error: %s", err)
 	}
 
-	err = c.DeleteLatestRef(IntegrationTestOwner, IntegrationTestRepo, "test")
+	err = c.DeleteLatestRef(context.Background(), IntegrationTestOwner, IntegrationTestRepo, "test")
 
 	if err != nil {
 		t.Fatalf("#DeleteLatestRef returns unexpected error: %s", err)
@@ -101,8 +102,8 @@ func TestCreatePullRequestFail(t *testing.T) {
 	for i, tc := range cases {
 		c := testGitHubClient()
 
-		if pr, err := c.CreatePullRequest(IntegrationTestOwner, tc.repo, tc.title, tc.head, tc.base, tc.body); err == nil {
-			if e := c.ClosePullRequest(IntegrationTestOwner, tc.repo, *pr.Number); e != nil {
+		if pr, err := c.CreatePullRequest(context.Background(), IntegrationTestOwner, tc.repo, tc.title, tc.head, tc.base, tc.body); err == nil {
+			if e := c.ClosePullRequest(context.Background(), IntegrationTestOwner, tc.repo, *pr.Number); e != nil {
 				t.Errorf("#%d #ClosePullRequest failed to rollback #CreatePullRequest: %s", i, e)
 			}
 			t.Fatalf("#%d #CreatePullRequest did not return error", i)
@@ -122,7 +123,7 @@ func TestMergePullRequestFail(t *testing.T) {
 	for i, tc := range cases {
 		c := testGitHubClient()
 
-		if err := c.MergePullRequest(IntegrationTestRepo, tc.repo, tc.number); err == nil {
+		if err := c.MergePullRequest(context.Background(), IntegrationTestRepo, tc.repo, tc.number); err == nil {
 			t.Fatalf("#%d #MergePullRequest did not reutrn error", i)
 		}
 	}
@@ -140,7 +141,7 @@ func TestClosePullRequestFail(t *testing.T) {
 	for i, tc := range cases {
 		c := testGitHubClient()
 
-		if err := c.ClosePullRequest(IntegrationTestOwner, tc.repo, tc.number); err == nil {
+		if err := c.ClosePullRequest(context.Background(), IntegrationTestOwner, tc.repo, tc.number); err == nil {
 			t.Fatalf("#%d #ClosePullRequest did not return error", i)
 		}
 	}
@@ -152,13 +153,13 @@ func TestCreateAndMergeAndClosePullRequestSuccess(t *testing.T) {
 	masterReplica, developReplica := "master_replica", "develop_replica"
 
 	// Create new branches for this test
-	err := c.CreateBranch(IntegrationTestOwner, IntegrationTestRepo, "master", masterReplica)
+	err := c.CreateBranch(context.Background(), IntegrationTestOwner, IntegrationTestRepo, "master", masterReplica)
 
 	if err != nil {
 		t.Fatalf("CreateBranch: unexpected error occured: %s", err)
 	}
 
-	err = c.CreateBranch(IntegrationTestOwner, IntegrationTestRepo, "develop", developReplica)
+	err = c.CreateBranch(context.Background(), IntegrationTestOwner, IntegrationTestRepo, "develop", developReplica)
 
 	if err != nil {
 		t.Fatalf("CreateBranch: unexpected error occured: %s", err)
@@ -166,13 +167,13 @@ func TestCreateAndMergeAndClosePullRequestSuccess(t *testing.T) {
 
 	// Clean up the branches created for this test
 	defer func() {
-		err = c.DeleteLatestRef(IntegrationTestOwner, IntegrationTestRepo, masterReplica)
+		err = c.DeleteLatestRef(context.Background(), IntegrationTestOwner, IntegrationTestRepo, masterReplica)
 
 		if err != nil {
 			t.Fatalf("DeleteLatestRef: unexpected error occured: %s", err)
 		}
 
-		err = c.DeleteLatestRef(IntegrationTestOwner, IntegrationTestRepo, developReplica)
+		err = c.DeleteLatestRef(context.Background(), IntegrationTestOwner, IntegrationTestRepo, developReplica)
 
 		if err != nil {
 			t.Fatalf("DeleteLatestRef: unexpected error occured: %s", err)
@@ -180,28 +181,28 @@ func TestCreateAndMergeAndClosePullRequestSuccess(t *testing.T) {
 	}()
 
 	// Create PR develop_replica -> master_replica
-	developRepToMasterRepPR, err := c.CreatePullRequest(IntegrationTestOwner, IntegrationTestRepo, "First Test PR for TestCreateAndMergeAndClosePullRequest", developReplica, masterReplica, "Test PR!")
+	developRepToMasterRepPR, err := c.CreatePullRequest(context.Background(), IntegrationTestOwner, IntegrationTestRepo, "First Test PR for TestCreateAndMergeAndClosePullRequest", developReplica, masterReplica, "Test PR!")
 
 	if err != nil {
 		t.Fatalf("CreatePullRequest: unexpected error occured: %s", err)
 	}
 
 	// Merge PR develop_replica -> master_replica
-	err = c.MergePullRequest(IntegrationTestOwner, IntegrationTestRepo, *developRepToMasterRepPR.Number)
+	err = c.MergePullRequest(context.Background(), IntegrationTestOwner, IntegrationTestRepo, *developRepToMasterRepPR.Number)
 
 	if err != nil {
 		t.Fatalf("MergePullRequest: unexpected error occured: %s", err)
 	}
 
 	// Create PR master_replica -> master
-	masterRepToMasterPR, err := c.CreatePullRequest(IntegrationTestOwner, IntegrationTestRepo, "Second Test PR for TestCreateAndMergeAndClosePullRequest", masterReplica, "master", "Test PR!")
+	masterRepToMasterPR, err := c.CreatePullRequest(context.Background(), IntegrationTestOwner, IntegrationTestRepo, "Second Test PR for TestCreateAndMergeAndClosePullRequest", masterReplica, "master", "Test PR!")
 
 	if err != nil {
 		t.Fatalf("CreatePullRequest: unexpected error occured: %s", err)
 	}
 
 	// Close PR master_replica -> master
-	err = c.ClosePullRequest(IntegrationTestOwner, IntegrationTestRepo, *masterRepToMasterPR.Number)
+	err = c.ClosePullRequest(context.Background(), IntegrationTestOwner, IntegrationTestRepo, *masterRepToMasterPR.Number)
 
 	if err != nil {
 		t.Fatalf("MergePullRequest: unexpected error occured: %s", err)
@@ -220,7 +221,7 @@ func TestGetFileFail(t *testing.T) {
 	for i, tc := range cases {
 		c := testGitHubClient()
 
-		if _, err := c.GetFile(IntegrationTestOwner, tc.repo, tc.branch, tc.path); err == nil {
+		if _, err := c.GetFile(context.Background(), IntegrationTestOwner, tc.repo, tc.branch, tc.path); err == nil {
 			t.Fatalf("#%d GetFile: error is not supposed to be nil", i)
 		}
 	}
@@ -229,7 +230,7 @@ func TestGetFileFail(t *testing.T) {
 func TestGetFileSuccess(t *testing.T) {
 	c := testGitHubClient()
 
-	file, err := c.GetFile(IntegrationTestOwner, IntegrationTestRepo, "master", "main.go")
+	file, err := c.GetFile(context.Background(), IntegrationTestOwner, IntegrationTestRepo, "master", "main.go")
 
 	if err != nil {
 		t.Fatalf("GetFile: unexpected error occured: %s", err)
@@ -251,7 +252,7 @@ func TestGitHubClient_CreateFileFail(t *testing.T) {
 
 	for i, tc := range cases {
 		c := testGitHubClient()
-		if _, err := c.CreateFile(IntegrationTestOwner, tc.repo, tc.branch, tc.path, tc.message, []byte(tc.content)); err == nil {
+		if _, err := c.CreateFile(context.Background(), IntegrationTestOwner, tc.repo, tc.branch, tc.path, tc.message, []byte(tc.content)); err == nil {
 			t.Fatalf("#%d CreateFile: error is not supposed to be nil", i)
 		}
 	}
@@ -259,7 +260,7 @@ func TestGitHubClient_CreateFileFail(t *testing.T) {
 
 func TestGitHubClient_DeleteFileFail(t *testing.T) {
 	c := testGitHubClient()
-	f, err := c.GetFile(IntegrationTestOwner, IntegrationTestRepo, "master", "main.go")
+	f, err := c.GetFile(context.Background(), IntegrationTestOwner, IntegrationTestRepo, "master", "main.go")
 
 	if err != nil {
 		t.Fatalf("GetFile: unexpected error occured: %s", err)
@@ -275,7 +276,7 @@ func TestGitHubClient_DeleteFileFail(t *testing.T) {
 	}
 
 	for i, tc := range cases {
-		if err := c.DeleteFile(IntegrationTestOwner, tc.repo, tc.branch, tc.path, tc.sha, tc.message); err == nil {
+		if err := c.DeleteFile(context.Background(), IntegrationTestOwner, tc.repo, tc.branch, tc.path, tc.sha, tc.message); err == nil {
 			t.Fatalf("#%d DeleteFile: error is not supposed to be nil", i)
 		}
 	}
@@ -286,14 +287,14 @@ func TestGitHubClient_CreateAndDeleteFile(t *testing.T) {
 
 	// Create a test branch
 	branch := "test_create_and_delete_file"
-	if err := c.CreateBranch(IntegrationTestOwner, IntegrationTestRepo, "master", branch); err != nil {
+	if err := c.CreateBranch(context.Background(), IntegrationTestOwner, IntegrationTestRepo, "master", branch); err != nil {
 		t.Fatalf("unexpected error occured while creating a test branch: %s", err)
 	}
 
-	defer c.DeleteLatestRef(IntegrationTestOwner, IntegrationTestRepo, branch)
+	defer c.DeleteLatestRef(context.Background(), IntegrationTestOwner, IntegrationTestRepo, branch)
 
 	// Create a file
-	rc, err := c.CreateFile(IntegrationTestOwner, IntegrationTestRepo, branch, "new_file.go", "This is a new file!", []byte(`fmt.Println("test!")`))
+	rc, err := c.CreateFile(context.Background(), IntegrationTestOwner, IntegrationTestRepo, branch, "new_file.go", "This is a new file!", []byte(`fmt.Println("test!")`))
 
 	if err != nil {
 		t.Fatalf("unexpected error occured while creating a file: %s", err)
@@ -303,14 +304,14 @@ func TestGitHubClient_CreateAndDeleteFile(t *testing.T) {
 	time.Sleep(3 * time.Second)
 
 	// Delete a file
-	if err := c.DeleteFile(IntegrationTestOwner, IntegrationTestRepo, branch, "new_file.go", *rc.Content.SHA, "This is a new file!"); err != nil {
+	if err := c.DeleteFile(context.Background(), IntegrationTestOwner, IntegrationTestRepo, branch, "new_file.go", *rc.Content.SHA, "This is a new file!"); err != nil {
 		t.Fatalf("unexpected error occured while Deleting a file: %s", err)
 	}
 }
 
 func TestUpdateFileFail(t *testing.T) {
 	c := testGitHubClient()
-	f, err := c.GetFile(IntegrationTestOwner, IntegrationTestRepo, "master", "main.go")
+	f, err := c.GetFile(context.Background(), IntegrationTestOwner, IntegrationTestRepo, "master", "main.go")
 
 	if err != nil {
 		t.Fatalf("GetFile: unexpected error occured: %s", err)
@@ -325,7 +326,7 @@ func TestUpdateFileFail(t *testing.T) {
 	}
 
 	for i, tc := range cases {
-		if err := c.UpdateFile(IntegrationTestOwner, tc.repo, tc.branch, tc.path, tc.sha, tc.message, []byte(tc.content)); err == nil {
+		if err := c.UpdateFile(context.Background(), IntegrationTestOwner, tc.repo, tc.branch, tc.path, tc.sha, tc.message, []byte(tc.content)); err == nil {
 			t.Fatalf("#%d UpdateFile: error is not supposed to be nil", i)
 		}
 	}
@@ -337,7 +338,7 @@ func TestUpdateFileSuccess(t *testing.T) {
 	testBranch := "test_update_file"
 
 	// Create new branches for this test
-	err := c.CreateBranch(IntegrationTestOwner, IntegrationTestRepo, "master", testBranch)
+	err := c.CreateBranch(context.Background(), IntegrationTestOwner, IntegrationTestRepo, "master", testBranch)
 
 	if err != nil {
 		t.Fatalf("CreateBranch: unexpected error occured: %s", err)
@@ -345,28 +346,28 @@ func TestUpdateFileSuccess(t *testing.T) {
 
 	// Delete the branches created for this test
 	defer func() {
-		err = c.DeleteLatestRef(IntegrationTestOwner, IntegrationTestRepo, testBranch)
+		err = c.DeleteLatestRef(context.Background(), IntegrationTestOwner, IntegrationTestRepo, testBranch)
 		if err != nil {
 			t.Fatalf("DeleteLatestRef: unexpected error occured: %s", err)
 		}
 	}()
 
 	// Get main.go on the test branch
-	f, err := c.GetFile(IntegrationTestOwner, IntegrationTestRepo, testBranch, "main.go")
+	f, err := c.GetFile(context.Background(), IntegrationTestOwner, IntegrationTestRepo, testBranch, "main.go")
 
 	if err != nil {
 		t.Fatalf("GetFile: unexpected error occured: %s", err)
 	}
 
 	// Update main.go on the test branch
-	err = c.UpdateFile(IntegrationTestOwner, IntegrationTestRepo, testBranch, "main.go", *f.SHA, "Update main.go", []byte("test!"))
+	err = c.UpdateFile(context.Background(), IntegrationTestOwner, IntegrationTestRepo, testBranch, "main.go", *f.SHA, "Update main.go", []byte("test!"))
 
 	if err != nil {
 		t.Fatalf("UpdateFile: unexpected error occured: %s", err)
 	}
 
 	// Get updated main.go on the test branch
-	f, err = c.GetFile(IntegrationTestOwner, IntegrationTestRepo, testBranch, "main.go")
+	f, err = c.GetFile(context.Background(), IntegrationTestOwner, IntegrationTestRepo, testBranch, "main.go")
 
 	if err != nil {
 		t.Fatalf("GetFile: unexpected error occured: %s", err)
@@ -394,7 +395,7 @@ func TestGitHubClient_DeleteRepositoryFail(t *testing.T) {
 	for i, tc := range cases {
 		c := testGitHubClient()
 
-		if err := c.DeleteRepository(IntegrationTestOwner, tc.name); err == nil {
+		if err := c.DeleteRepository(context.Background(), IntegrationTestOwner, tc.name); err == nil {
 			t.Fatalf("#%d DeleteRepository: error is not supposed to be nil", i)
 		}
 	}
@@ -405,7 +406,7 @@ func TestGitHubClient_CreateAndDeleteRepository(t *testing.T) {
 	repo := "test_create_repo"
 
 	// Create Repo
-	_, err := c.CreateRepository("", repo, "This is a test!", "", false)
+	_, err := c.CreateRepository(context.Background(), "", repo, "This is a test!", "", false)
 
 	if err != nil {
 		t.Fatalf("unexpected error occured while creating a GitHub repository: %s", err)
@@ -415,7 +416,7 @@ func TestGitHubClient_CreateAndDeleteRepository(t *testing.T) {
 	time.Sleep(3 * time.Second)
 
 	// Delete Repo
-	err = c.DeleteRepository(IntegrationTestOwner, repo)
+	err = c.DeleteRepository(context.Background(), IntegrationTestOwner, repo)
 
 	if err != nil {
 		t.Fatalf("unexpected error occured while deleting a GitHub repository: %s", err)
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -15,7 +16,7 @@ const (
 )
 
 func TestNewGitHubClient(t *testing.T) {
-	c := NewGitHubClient("test")
+	c := NewGitHubClient("test", nil)
 	if c == nil {
 		t.Fatalf("#NewGitHubClient returns empty GitHubClient")
 	}
@@ -27,15 +28,21 @@ func TestGitHubClient_GetLatestRelease(t *testing.T) {
 
 	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/releases/latest", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, http.MethodGet)
-		fmt.Fprint(w, `{"id":1,"name":"Release v0.0.1","Draft":false}`)
+		fmt.Fprint(w, `{"id":1,"name":"Release v0.0.1","tag_name":"v0.0.1","Draft":false,"tarball_url":"https://api.github.com/repos/shuheiktgw/ghbr/tarball/v0.0.1"}`)
 	})
 
-	rr, err := client.GetLatestRelease(TestOwner, TestRepo)
+	rr, err := client.GetLatestRelease(context.Background(), TestOwner, TestRepo)
 	if err != nil {
 		t.Fatalf("#GetLatestRelease returns unexpected error: %v", err)
 	}
 
-	release := github.RepositoryRelease{ID: github.Int64(1), Name: github.String("Release v0.0.1"), Draft: github.Bool(false)}
+	release := github.RepositoryRelease{
+		ID:         github.Int64(1),
+		Name:       github.String("Release v0.0.1"),
+		TagName:    github.String("v0.0.1"),
+		Draft:      github.Bool(false),
+		TarballURL: github.String("https://github.com/shuheiktgw/ghbr/archive/refs/tags/v0.0.1.tar.gz"),
+	}
 	if !reflect.DeepEqual(rr, &release) {
 		t.Errorf("#GetLatestRelease returned %+v, want %+v", rr, release)
 	}
@@ -60,7 +67,7 @@ func TestGitHubClient_CreateBranch(t *testing.T) {
 		fmt.Fprintf(w, `{"object":{"sha":"%s"}}`, sha)
 	})
 
-	err := client.CreateBranch(TestOwner, TestRepo, originBranch, newBranch)
+	err := client.CreateBranch(context.Background(), TestOwner, TestRepo, originBranch, newBranch)
 	if err != nil {
 		t.Fatalf("#CreateBranch returns unexpected error: %v", err)
 	}
@@ -76,7 +83,7 @@ func TestGitHubClient_DeleteLatestRef(t *testing.T) {
 		testMethod(t, r, http.MethodDelete)
 	})
 
-	err := client.DeleteLatestRef(TestOwner, TestRepo, branch)
+	err := client.DeleteLatestRef(context.Background(), TestOwner, TestRepo, branch)
 	if err != nil {
 		t.Fatalf("#DeleteLatestRef returns unexpected error: %v", err)
 	}
@@ -97,7 +104,7 @@ func TestGitHubClient_CreatePullRequest(t *testing.T) {
 		fmt.Fprintf(w, `{"title":"%s","body":"%s"}`, title, body)
 	})
 
-	pr, err := client.CreatePullRequest(TestOwner, TestRepo, title, head, base, body)
+	pr, err := client.CreatePullRequest(context.Background(), TestOwner, TestRepo, title, head, base, body)
 	if err != nil {
 		t.Fatalf("#CreatePullRequest returns unexpected error: %v", err)
 	}
@@ -108,6 +115,27 @@ func TestGitHubClient_CreatePullRequest(t *testing.T) {
 	}
 }
 
+func TestGitHubClient_ListPullRequests(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	mux.HandleFunc(fmt.Sprintf("/repos/%v/%v/pulls", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		testFormValues(t, r, values{"state": "open", "base": "master", "per_page": "100"})
+		fmt.Fprint(w, `[{"number":1}]`)
+	})
+
+	prs, err := client.ListPullRequests(context.Background(), TestOwner, TestRepo, "master")
+	if err != nil {
+		t.Fatalf("#ListPullRequests returns unexpected error: %v", err)
+	}
+
+	want := []*github.PullRequest{{Number: github.Int(1)}}
+	if !reflect.DeepEqual(prs, want) {
+		t.Errorf("#ListPullRequests returned %+v, want %+v", prs, want)
+	}
+}
+
 func TestGitHubClient_MergePullRequest(t *testing.T) {
 	client, mux, _, tearDown := setup()
 	defer tearDown()
@@ -119,12 +147,33 @@ func TestGitHubClient_MergePullRequest(t *testing.T) {
 		fmt.Fprint(w, `{"sha":"abcdefg","merged":true}`)
 	})
 
-	err := client.MergePullRequest(TestOwner, TestRepo, number)
+	err := client.MergePullRequest(context.Background(), TestOwner, TestRepo, number)
 	if err != nil {
 		t.Fatalf("#MergePullRequest returns unexpected error: %v", err)
 	}
 }
 
+func TestGitHubClient_GetPullRequest(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	number := 1
+
+	mux.HandleFunc(fmt.Sprintf("/repos/%v/%v/pulls/%d", TestOwner, TestRepo, number), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"number":1,"merged":true}`)
+	})
+
+	pr, err := client.GetPullRequest(context.Background(), TestOwner, TestRepo, number)
+	if err != nil {
+		t.Fatalf("#GetPullRequest returns unexpected error: %v", err)
+	}
+
+	if pr.GetNumber() != number || !pr.GetMerged() {
+		t.Errorf("#GetPullRequest returned %+v, want a merged Pull Request #%d", pr, number)
+	}
+}
+
 func TestGitHubClient_ClosePullRequest(t *testing.T) {
 	client, mux, _, tearDown := setup()
 	defer tearDown()
@@ -132,12 +181,12 @@ func TestGitHubClient_ClosePullRequest(t *testing.T) {
 	number := 1
 
 	mux.HandleFunc(fmt.Sprintf("/repos/%v/%v/pulls/%d", TestOwner, TestRepo, number), func(w http.ResponseWriter, r *http.Request) {
-		testBody(t, r, `{"state":"close"}`+"\n")
+		testBody(t, r, `{"state":"closed"}`+"\n")
 		testMethod(t, r, http.MethodPatch)
 		fmt.Fprint(w, `{"number":1}`)
 	})
 
-	err := client.ClosePullRequest(TestOwner, TestRepo, number)
+	err := client.ClosePullRequest(context.Background(), TestOwner, TestRepo, number)
 	if err != nil {
 		t.Fatalf("#ClosePullRequest returns unexpected error: %v", err)
 	}
@@ -156,7 +205,7 @@ func TestGitHubClient_GetFile(t *testing.T) {
 		fmt.Fprintf(w, `{"path":"%s"}`, path)
 	})
 
-	rc, err := client.GetFile(TestOwner, TestRepo, branch, path)
+	rc, err := client.GetFile(context.Background(), TestOwner, TestRepo, branch, path)
 	if err != nil {
 		t.Fatalf("#GetFile returns unexpected error: %v", err)
 	}
@@ -182,7 +231,7 @@ func TestGitHubClient_CreateFile(t *testing.T) {
 		fmt.Fprintf(w, `{"content":{"path":"%s","content":"%s"}}`, path, content)
 	})
 
-	rc, err := client.CreateFile(TestOwner, TestRepo, branch, path, message, []byte(content))
+	rc, err := client.CreateFile(context.Background(), TestOwner, TestRepo, branch, path, message, []byte(content))
 	if err != nil {
 		t.Fatalf("#CreateFile returns unexpected error: %v", err)
 	}
@@ -209,7 +258,7 @@ func TestGitHubClient_UpdateFile(t *testing.T) {
 		fmt.Fprintf(w, `{"content":{"path":"%s","content":"%s"}}`, path, content)
 	})
 
-	err := client.UpdateFile(TestOwner, TestRepo, branch, path, sha, message, []byte(content))
+	err := client.UpdateFile(context.Background(), TestOwner, TestRepo, branch, path, sha, message, []byte(content))
 	if err != nil {
 		t.Fatalf("#UpdateFile returns unexpected error: %v", err)
 	}
@@ -230,7 +279,7 @@ func TestGitHubClient_DeleteFile(t *testing.T) {
 		fmt.Fprintf(w, `{"content":{"path":"%s"}}`, path)
 	})
 
-	err := client.DeleteFile(TestOwner, TestRepo, branch, path, sha, message)
+	err := client.DeleteFile(context.Background(), TestOwner, TestRepo, branch, path, sha, message)
 	if err != nil {
 		t.Fatalf("#DeleteFile returns unexpected error: %v", err)
 	}
@@ -251,7 +300,7 @@ func TestGitHubClient_CreateRepository(t *testing.T) {
 		fmt.Fprintf(w, `{"name":"%s","description":"%s","homepage":"%s"}`, name, description, homepage)
 	})
 
-	repo, err := client.CreateRepository(TestOwner, name, description, homepage, private)
+	repo, err := client.CreateRepository(context.Background(), TestOwner, name, description, homepage, private)
 	if err != nil {
 		t.Fatalf("#CreateRepository returns unexpected error: %v", err)
 	}
@@ -262,6 +311,26 @@ func TestGitHubClient_CreateRepository(t *testing.T) {
 	}
 }
 
+func TestGitHubClient_GetRepository(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	mux.HandleFunc(fmt.Sprintf("/repos/%v/%v", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprintf(w, `{"name":"%s","description":"This is a test"}`, TestRepo)
+	})
+
+	repo, err := client.GetRepository(context.Background(), TestOwner, TestRepo)
+	if err != nil {
+		t.Fatalf("#GetRepository returns unexpected error: %v", err)
+	}
+
+	want := &github.Repository{Name: github.String(TestRepo), Description: github.String("This is a test")}
+	if !reflect.DeepEqual(repo, want) {
+		t.Errorf("#GetRepository returned %+v, want %+v", repo, want)
+	}
+}
+
 func TestGitHubClient_DeleteRepository(t *testing.T) {
 	client, mux, _, tearDown := setup()
 	defer tearDown()
@@ -273,8 +342,20 @@ func TestGitHubClient_DeleteRepository(t *testing.T) {
 		testMethod(t, r, http.MethodDelete)
 	})
 
-	err := client.DeleteRepository(org, name)
+	err := client.DeleteRepository(context.Background(), org, name)
 	if err != nil {
 		t.Fatalf("#DeleteRepository returns unexpected error: %v", err)
 	}
 }
+
+func TestNewGitHubEnterpriseClient(t *testing.T) {
+	c := NewGitHubEnterpriseClient("https://github.example.com/", "test", nil)
+
+	if got, want := c.Client.BaseURL.String(), "https://github.example.com/api/v3/"; got != want {
+		t.Errorf("#NewGitHubEnterpriseClient set BaseURL %s, want %s", got, want)
+	}
+
+	if got, want := c.RepositoryURL(TestOwner, TestRepo), "https://github.example.com/shuheiktgw/ghbr"; got != want {
+		t.Errorf("#RepositoryURL returned %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// defaultGitLabURL is the API URL of gitlab.com
+const defaultGitLabURL = "https://gitlab.com/api/v4"
+
+// EnvGitLabToken is used as the token with --forge gitlab unless -t is passed
+const EnvGitLabToken = "GITLAB_TOKEN"
+
+// Merging a Merge Request is retried while GitLab is checking whether it can be merged
+const (
+	gitlabMergeRetries       = 5
+	gitlabMergeRetryInterval = 2 * time.Second
+)
+
+// GitLabClient is a client to interact with GitLab API, projects are treated as repositories and Merge Requests as Pull Requests
+type GitLabClient struct {
+	api *restClient
+
+	// webURL is the URL of GitLab for humans
+	webURL string
+
+	mergeRetryInterval time.Duration
+}
+
+// NewGitLabClient creates and initializes a new GitLabClient for the API at the baseURL like https://gitlab.com/api/v4,
+// API requests are logged through the logger unless it is nil
+func NewGitLabClient(baseURL, token string, logger *Logger) *GitLabClient {
+	return &GitLabClient{
+		api:                newRESTClient(baseURL, http.Header{"Private-Token": {token}}, logger),
+		webURL:             strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/api/v4"),
+		mergeRetryInterval: gitlabMergeRetryInterval,
+	}
+}
+
+// gitlabProjectPath returns the API path of the project owner/repo
+func gitlabProjectPath(owner, repo string) string {
+	return "projects/" + url.PathEscape(owner+"/"+repo)
+}
+
+type gitlabRelease struct {
+	TagName string `json:"tag_name"`
+	Name    string `json:"name"`
+	Assets  struct {
+		Sources []struct {
+			Format string `json:"format"`
+			URL    string `json:"url"`
+		} `json:"sources"`
+		Links []struct {
+			Name           string `json:"name"`
+			URL            string `json:"url"`
+			DirectAssetURL string `json:"direct_asset_url"`
+		} `json:"links"`
+	} `json:"assets"`
+}
+
+// release converts the release into the one of GitHub, links are treated as assets
+func (r *gitlabRelease) release() *github.RepositoryRelease {
+	rr := &github.RepositoryRelease{TagName: github.String(r.TagName), Name: github.String(r.Name)}
+
+	for _, l := range r.Assets.Links {
+		u := l.DirectAssetURL
+		if len(u) == 0 {
+			u = l.URL
+		}
+
+		rr.Assets = append(rr.Assets, github.ReleaseAsset{Name: github.String(l.Name), BrowserDownloadURL: github.String(u)})
+	}
+
+	for _, s := range r.Assets.Sources {
+		switch s.Format {
+		case "tar.gz":
+			rr.TarballURL = github.String(s.URL)
+		case "zip":
+			rr.ZipballURL = github.String(s.URL)
+		}
+	}
+
+	return rr
+}
+
+type gitlabMergeRequest struct {
+	IID          int    `json:"iid"`
+	Title        string `json:"title"`
+	State        string `json:"state"`
+	WebURL       string `json:"web_url"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+}
+
+// pullRequest converts the Merge Request into a Pull Request, whose number is the iid
+func (m *gitlabMergeRequest) pullRequest() *github.PullRequest {
+	state := "closed"
+	if m.State == "opened" {
+		state = "open"
+	}
+
+	return &github.PullRequest{
+		Number:  github.Int(m.IID),
+		Title:   github.String(m.Title),
+		State:   github.String(state),
+		Merged:  github.Bool(m.State == "merged"),
+		HTMLURL: github.String(m.WebURL),
+		Head:    &github.PullRequestBranch{Ref: github.String(m.SourceBranch)},
+		Base:    &github.PullRequestBranch{Ref: github.String(m.TargetBranch)},
+	}
+}
+
+type gitlabProject struct {
+	Path              string `json:"path"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	Description       string `json:"description"`
+	DefaultBranch     string `json:"default_branch"`
+	WebURL            string `json:"web_url"`
+	Visibility        string `json:"visibility"`
+}
+
+// repository converts the project into a repository
+func (p *gitlabProject) repository() *github.Repository {
+	return &github.Repository{
+		Name:          github.String(p.Path),
+		FullName:      github.String(p.PathWithNamespace),
+		Description:   github.String(p.Description),
+		DefaultBranch: github.String(p.DefaultBranch),
+		HTMLURL:       github.String(p.WebURL),
+		Private:       github.Bool(p.Visibility == "private"),
+	}
+}
+
+// GetLatestRelease returns the latest release of the given project
+func (g *GitLabClient) GetLatestRelease(ctx context.Context, owner, repo string) (*github.RepositoryRelease, error) {
+	var releases []gitlabRelease
+	query := url.Values{"order_by": {"released_at"}, "sort": {"desc"}, "per_page": {"1"}}
+
+	if err := g.api.do(ctx, http.MethodGet, gitlabProjectPath(owner, repo)+"/releases", query, nil, &releases); err != nil {
+		return nil, errors.Wrapf(err, "GitLab #GetLatestRelease failed: owner: %s, repo: %s", owner, repo)
+	}
+
+	if len(releases) == 0 {
+		return nil, &APIError{Method: http.MethodGet, URL: g.RepositoryURL(owner, repo), StatusCode: http.StatusNotFound, Message: "no release found"}
+	}
+
+	return releases[0].release(), nil
+}
+
+// CreateBranch creates a new branch from the heads of the origin
+func (g *GitLabClient) CreateBranch(ctx context.Context, owner, repo, origin, new string) error {
+	in := map[string]string{"branch": new, "ref": origin}
+
+	if err := g.api.do(ctx, http.MethodPost, gitlabProjectPath(owner, repo)+"/repository/branches", nil, in, nil); err != nil {
+		// GitLab responds with 400 for an existing branch, for which GitHub does with 422
+		if e, ok := err.(*APIError); ok && e.StatusCode == http.StatusBadRequest && strings.Contains(e.Message, "already exists") {
+			e.StatusCode = http.StatusUnprocessableEntity
+		}
+
+		return errors.Wrapf(err, "GitLab #CreateBranch failed: owner: %s, repo: %s, branch: %s", owner, repo, new)
+	}
+
+	return nil
+}
+
+// DeleteLatestRef deletes the branch
+func (g *GitLabClient) DeleteLatestRef(ctx context.Context, owner, repo, branch string) error {
+	if err := g.api.do(ctx, http.MethodDelete, gitlabProjectPath(owner, repo)+"/repository/branches/"+url.PathEscape(branch), nil, nil, nil); err != nil {
+		return errors.Wrapf(err, "GitLab #DeleteLatestRef failed: owner: %s, repo: %s, branch: %s", owner, repo, branch)
+	}
+
+	return nil
+}
+
+// CreatePullRequest opens a Merge Request from the head to the base
+func (g *GitLabClient) CreatePullRequest(ctx context.Context, owner, repo, title, head, base, body string) (*github.PullRequest, error) {
+	in := map[string]string{"source_branch": head, "target_branch": base, "title": title, "description": body}
+
+	var mr gitlabMergeRequest
+	if err := g.api.do(ctx, http.MethodPost, gitlabProjectPath(owner, repo)+"/merge_requests", nil, in, &mr); err != nil {
+		return nil, errors.Wrapf(err, "GitLab #CreatePullRequest failed: owner: %s, repo: %s, head: %s, base: %s", owner, repo, head, base)
+	}
+
+	return mr.pullRequest(), nil
+}
+
+// ListPullRequests lists open Merge Requests to the base
+func (g *GitLabClient) ListPullRequests(ctx context.Context, owner, repo, base string) ([]*github.PullRequest, error) {
+	var mrs []gitlabMergeRequest
+
+	// Pages are requested until an empty one, since more than 100 Merge Requests may be open
+	for page := 1; ; page++ {
+		var ms []gitlabMergeRequest
+		query := url.Values{"state": {"opened"}, "target_branch": {base}, "per_page": {"100"}, "page": {strconv.Itoa(page)}}
+
+		if err := g.api.do(ctx, http.MethodGet, gitlabProjectPath(owner, repo)+"/merge_requests", query, nil, &ms); err != nil {
+			return nil, errors.Wrapf(err, "GitLab #ListPullRequests failed: owner: %s, repo: %s, base: %s", owner, repo, base)
+		}
+
+		if len(ms) == 0 {
+			break
+		}
+
+		mrs = append(mrs, ms...)
+	}
+
+	prs := make([]*github.PullRequest, len(mrs))
+	for i := range mrs {
+		prs[i] = mrs[i].pullRequest()
+	}
+
+	return prs, nil
+}
+
+// GetPullRequest gets the Merge Request with the iid
+func (g *GitLabClient) GetPullRequest(ctx context.Context, owner, repo string, number int) (*github.PullRequest, error) {
+	var mr gitlabMergeRequest
+	if err := g.api.do(ctx, http.MethodGet, fmt.Sprintf("%s/merge_requests/%d", gitlabProjectPath(owner, repo), number), nil, nil, &mr); err != nil {
+		return nil, errors.Wrapf(err, "GitLab #GetPullRequest failed: owner: %s, repo: %s, number: %d", owner, repo, number)
+	}
+
+	return mr.pullRequest(), nil
+}
+
+// MergePullRequest merges the Merge Request with the iid, which is retried while GitLab is checking whether it can be merged
+func (g *GitLabClient) MergePullRequest(ctx context.Context, owner, repo string, number int) error {
+	path := fmt.Sprintf("%s/merge_requests/%d/merge", gitlabProjectPath(owner, repo), number)
+
+	err := retryMerge(ctx, gitlabMergeRetries, g.mergeRetryInterval, func() error {
+		return g.api.do(ctx, http.MethodPut, path, nil, nil, nil)
+	}, http.StatusMethodNotAllowed, http.StatusNotAcceptable, http.StatusUnprocessableEntity)
+	if err == nil {
+		return nil
+	}
+
+	return errors.Wrapf(err, "GitLab #MergePullRequest failed: owner: %s, repo: %s, number: %d", owner, repo, number)
+}
+
+// ClosePullRequest closes the Merge Request with the iid
+func (g *GitLabClient) ClosePullRequest(ctx context.Context, owner, repo string, number int) error {
+	in := map[string]string{"state_event": "close"}
+
+	if err := g.api.do(ctx, http.MethodPut, fmt.Sprintf("%s/merge_requests/%d", gitlabProjectPath(owner, repo), number), nil, in, nil); err != nil {
+		return errors.Wrapf(err, "GitLab #ClosePullRequest failed: owner: %s, repo: %s, number: %d", owner, repo, number)
+	}
+
+	return nil
+}
+
+// GetFile gets the file on the branch, its SHA is the id of the last commit changing it
+func (g *GitLabClient) GetFile(ctx context.Context, owner, repo, branch, path string) (*github.RepositoryContent, error) {
+	var f struct {
+		FileName     string `json:"file_name"`
+		FilePath     string `json:"file_path"`
+		Encoding     string `json:"encoding"`
+		Content      string `json:"content"`
+		LastCommitID string `json:"last_commit_id"`
+	}
+
+	if err := g.api.do(ctx, http.MethodGet, gitlabProjectPath(owner, repo)+"/repository/files/"+url.PathEscape(path), url.Values{"ref": {branch}}, nil, &f); err != nil {
+		return nil, errors.Wrapf(err, "GitLab #GetFile failed: owner: %s, repo: %s, branch: %s, path: %s", owner, repo, branch, path)
+	}
+
+	return &github.RepositoryContent{
+		Type:     github.String("file"),
+		Name:     github.String(f.FileName),
+		Path:     github.String(f.FilePath),
+		Encoding: github.String(f.Encoding),
+		Content:  github.String(f.Content),
+		SHA:      github.String(f.LastCommitID),
+	}, nil
+}
+
+// GetDirectory lists files in the directory on the branch
+func (g *GitLabClient) GetDirectory(ctx context.Context, owner, repo, branch, path string) ([]*github.RepositoryContent, error) {
+	var tree []struct {
+		Name string `json:"name"`
+		Path string `json:"path"`
+		Type string `json:"type"`
+	}
+
+	query := url.Values{"path": {path}, "ref": {branch}, "per_page": {"100"}}
+	if err := g.api.do(ctx, http.MethodGet, gitlabProjectPath(owner, repo)+"/repository/tree", query, nil, &tree); err != nil {
+		return nil, errors.Wrapf(err, "GitLab #GetDirectory failed: owner: %s, repo: %s, branch: %s, path: %s", owner, repo, branch, path)
+	}
+
+	// Some versions of GitLab list nothing instead of responding with 404 for a missing directory, which git cannot have empty
+	if len(tree) == 0 {
+		return nil, &APIError{Method: http.MethodGet, URL: g.RepositoryURL(owner, repo), StatusCode: http.StatusNotFound, Message: fmt.Sprintf("%s not found on %s", path, branch)}
+	}
+
+	dir := make([]*github.RepositoryContent, len(tree))
+	for i, t := range tree {
+		typ := "file"
+		if t.Type == "tree" {
+			typ = "dir"
+		}
+
+		dir[i] = &github.RepositoryContent{Type: github.String(typ), Name: github.String(t.Name), Path: github.String(t.Path)}
+	}
+
+	return dir, nil
+}
+
+// gitlabFileRequest is a request to create, update or delete a file
+type gitlabFileRequest struct {
+	Branch        string `json:"branch"`
+	CommitMessage string `json:"commit_message"`
+	Encoding      string `json:"encoding,omitempty"`
+	Content       string `json:"content,omitempty"`
+	LastCommitID  string `json:"last_commit_id,omitempty"`
+}
+
+// CreateFile commits a new file to the branch
+func (g *GitLabClient) CreateFile(ctx context.Context, owner, repo, branch, path, message string, content []byte) (*github.RepositoryContentResponse, error) {
+	in := gitlabFileRequest{Branch: branch, CommitMessage: message, Encoding: "base64", Content: base64.StdEncoding.EncodeToString(content)}
+
+	if err := g.api.do(ctx, http.MethodPost, gitlabProjectPath(owner, repo)+"/repository/files/"+url.PathEscape(path), nil, in, nil); err != nil {
+		return nil, errors.Wrapf(err, "GitLab #CreateFile failed: owner: %s, repo: %s, branch: %s, path: %s", owner, repo, branch, path)
+	}
+
+	return &github.RepositoryContentResponse{Content: &github.RepositoryContent{Path: github.String(path)}}, nil
+}
+
+// UpdateFile commits the content to the branch, it fails if the file is changed after the commit whose id is sha
+func (g *GitLabClient) UpdateFile(ctx context.Context, owner, repo, branch, path, sha, message string, content []byte) error {
+	in := gitlabFileRequest{Branch: branch, CommitMessage: message, Encoding: "base64", Content: base64.StdEncoding.EncodeToString(content), LastCommitID: sha}
+
+	if err := g.api.do(ctx, http.MethodPut, gitlabProjectPath(owner, repo)+"/repository/files/"+url.PathEscape(path), nil, in, nil); err != nil {
+		return errors.Wrapf(err, "GitLab #UpdateFile failed: owner: %s, repo: %s, branch: %s, path: %s", owner, repo, branch, path)
+	}
+
+	return nil
+}
+
+// DeleteFile deletes the file from the branch, it fails if the file is changed after the commit whose id is sha
+func (g *GitLabClient) DeleteFile(ctx context.Context, owner, repo, branch, path, sha, message string) error {
+	in := gitlabFileRequest{Branch: branch, CommitMessage: message, LastCommitID: sha}
+
+	if err := g.api.do(ctx, http.MethodDelete, gitlabProjectPath(owner, repo)+"/repository/files/"+url.PathEscape(path), nil, in, nil); err != nil {
+		return errors.Wrapf(err, "GitLab #DeleteFile failed: owner: %s, repo: %s, branch: %s, path: %s", owner, repo, branch, path)
+	}
+
+	return nil
+}
+
+// CreateRepository creates a new project under the group org, or the authenticated user if org is empty.
+// GitLab projects do not have homepages, so the homepage is ignored
+func (g *GitLabClient) CreateRepository(ctx context.Context, org, name, description, homepage string, private bool) (*github.Repository, error) {
+	visibility := "public"
+	if private {
+		visibility = "private"
+	}
+
+	in := map[string]interface{}{"name": name, "path": name, "description": description, "visibility": visibility}
+
+	if len(org) != 0 {
+		var ns struct {
+			ID int `json:"id"`
+		}
+
+		if err := g.api.do(ctx, http.MethodGet, "namespaces/"+url.PathEscape(org), nil, nil, &ns); err != nil {
+			return nil, errors.Wrapf(err, "GitLab #CreateRepository failed to get the namespace: org: %s", org)
+		}
+
+		in["namespace_id"] = ns.ID
+	}
+
+	var p gitlabProject
+	if err := g.api.do(ctx, http.MethodPost, "projects", nil, in, &p); err != nil {
+		return nil, errors.Wrapf(err, "GitLab #CreateRepository failed: org: %s, repository name: %s", org, name)
+	}
+
+	return p.repository(), nil
+}
+
+// GetRepository gets the project
+func (g *GitLabClient) GetRepository(ctx context.Context, owner, name string) (*github.Repository, error) {
+	var p gitlabProject
+	if err := g.api.do(ctx, http.MethodGet, gitlabProjectPath(owner, name), nil, nil, &p); err != nil {
+		return nil, errors.Wrapf(err, "GitLab #GetRepository failed: owner: %s, repository name: %s", owner, name)
+	}
+
+	return p.repository(), nil
+}
+
+// GetLogin returns the username of the authenticated user
+func (g *GitLabClient) GetLogin(ctx context.Context) (string, error) {
+	var u struct {
+		Username string `json:"username"`
+	}
+
+	if err := g.api.do(ctx, http.MethodGet, "user", nil, nil, &u); err != nil {
+		return "", errors.Wrap(err, "GitLab #GetLogin failed")
+	}
+
+	return u.Username, nil
+}
+
+// DeleteRepository deletes the project
+func (g *GitLabClient) DeleteRepository(ctx context.Context, owner, name string) error {
+	if err := g.api.do(ctx, http.MethodDelete, gitlabProjectPath(owner, name), nil, nil, nil); err != nil {
+		return errors.Wrapf(err, "GitLab #DeleteRepository failed: owner: %s, repository name: %s", owner, name)
+	}
+
+	return nil
+}
+
+// RepositoryURL returns the URL of the project on GitLab
+func (g *GitLabClient) RepositoryURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s", g.webURL, owner, repo)
+}
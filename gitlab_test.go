@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func TestGitLabClient_GetLatestRelease(t *testing.T) {
+	client, mux, _, tearDown := setupGitLab()
+	defer tearDown()
+
+	mux.HandleFunc(fmt.Sprintf("/api/v4/projects/%s/%s/releases", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		testHeader(t, r, "Private-Token", "abcdefg")
+
+		if !strings.HasPrefix(r.RequestURI, fmt.Sprintf("/api/v4/projects/%s%%2F%s/releases?", TestOwner, TestRepo)) {
+			t.Errorf("request URI is %s, want the project path escaped", r.RequestURI)
+		}
+
+		fmt.Fprint(w, `[{"tag_name":"v0.0.1","name":"Release v0.0.1","assets":{`+
+			`"sources":[{"format":"zip","url":"https://gitlab.com/shuheiktgw/ghbr/-/archive/v0.0.1/ghbr-v0.0.1.zip"},{"format":"tar.gz","url":"https://gitlab.com/shuheiktgw/ghbr/-/archive/v0.0.1/ghbr-v0.0.1.tar.gz"}],`+
+			`"links":[{"name":"ghbr_darwin_amd64.zip","url":"https://example.com/ghbr_darwin_amd64.zip"},{"name":"ghbr_linux_amd64.zip","url":"https://example.com/1","direct_asset_url":"https://example.com/ghbr_linux_amd64.zip"}]}}]`)
+	})
+
+	rr, err := client.GetLatestRelease(context.Background(), TestOwner, TestRepo)
+	if err != nil {
+		t.Fatalf("#GetLatestRelease returns unexpected error: %v", err)
+	}
+
+	want := &github.RepositoryRelease{
+		TagName: github.String("v0.0.1"),
+		Name:    github.String("Release v0.0.1"),
+		Assets: []github.ReleaseAsset{
+			{Name: github.String("ghbr_darwin_amd64.zip"), BrowserDownloadURL: github.String("https://example.com/ghbr_darwin_amd64.zip")},
+			{Name: github.String("ghbr_linux_amd64.zip"), BrowserDownloadURL: github.String("https://example.com/ghbr_linux_amd64.zip")},
+		},
+		TarballURL: github.String("https://gitlab.com/shuheiktgw/ghbr/-/archive/v0.0.1/ghbr-v0.0.1.tar.gz"),
+		ZipballURL: github.String("https://gitlab.com/shuheiktgw/ghbr/-/archive/v0.0.1/ghbr-v0.0.1.zip"),
+	}
+
+	if !reflect.DeepEqual(rr, want) {
+		t.Errorf("#GetLatestRelease returned %+v, want %+v", rr, want)
+	}
+}
+
+func TestGitLabClient_GetLatestRelease_NoRelease(t *testing.T) {
+	client, mux, _, tearDown := setupGitLab()
+	defer tearDown()
+
+	mux.HandleFunc(fmt.Sprintf("/api/v4/projects/%s/%s/releases", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	})
+
+	if _, err := client.GetLatestRelease(context.Background(), TestOwner, TestRepo); !isNotFound(err) {
+		t.Errorf("#GetLatestRelease returned %v, want 404", err)
+	}
+}
+
+func TestGitLabClient_CreateBranch_AlreadyExists(t *testing.T) {
+	client, mux, _, tearDown := setupGitLab()
+	defer tearDown()
+
+	mux.HandleFunc(fmt.Sprintf("/api/v4/projects/%s/%s/repository/branches", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		testBody(t, r, `{"branch":"develop","ref":"master"}`)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"message":"Branch already exists"}`)
+	})
+
+	err := client.CreateBranch(context.Background(), TestOwner, TestRepo, "master", "develop")
+	if !isUnprocessable(err) {
+		t.Errorf("#CreateBranch returned %v, want 422", err)
+	}
+}
+
+func TestGitLabClient_GetFile_UpdateFile(t *testing.T) {
+	client, mux, _, tearDown := setupGitLab()
+	defer tearDown()
+
+	content := base64.StdEncoding.EncodeToString([]byte("version 'v0.0.1'\n"))
+
+	mux.HandleFunc(fmt.Sprintf("/api/v4/projects/%s/%s/repository/files/Formula/testApp.rb", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			testFormValues(t, r, values{"ref": "master"})
+			fmt.Fprintf(w, `{"file_name":"testApp.rb","file_path":"Formula/testApp.rb","encoding":"base64","content":"%s","last_commit_id":"abcdefg"}`, content)
+		case http.MethodPut:
+			want := fmt.Sprintf(`{"branch":"develop","commit_message":"Bumps up to v0.0.2","encoding":"base64","content":"%s","last_commit_id":"abcdefg"}`,
+				base64.StdEncoding.EncodeToString([]byte("version 'v0.0.2'\n")))
+			testBody(t, r, want)
+			fmt.Fprint(w, `{"file_path":"Formula/testApp.rb","branch":"develop"}`)
+		default:
+			t.Errorf("unexpected request: %s", r.Method)
+		}
+	})
+
+	rc, err := client.GetFile(context.Background(), TestOwner, TestRepo, "master", "Formula/testApp.rb")
+	if err != nil {
+		t.Fatalf("#GetFile returns unexpected error: %v", err)
+	}
+
+	if got, _ := rc.GetContent(); got != "version 'v0.0.1'\n" {
+		t.Errorf("#GetFile returned the content %q, want %q", got, "version 'v0.0.1'\n")
+	}
+
+	if err := client.UpdateFile(context.Background(), TestOwner, TestRepo, "develop", "Formula/testApp.rb", rc.GetSHA(), "Bumps up to v0.0.2", []byte("version 'v0.0.2'\n")); err != nil {
+		t.Errorf("#UpdateFile returns unexpected error: %v", err)
+	}
+}
+
+func TestGitLabClient_CreatePullRequest(t *testing.T) {
+	client, mux, _, tearDown := setupGitLab()
+	defer tearDown()
+
+	mux.HandleFunc(fmt.Sprintf("/api/v4/projects/%s/%s/merge_requests", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		testBody(t, r, `{"description":"Bumps up to v0.0.2","source_branch":"develop","target_branch":"master","title":"Bumps up to v0.0.2"}`)
+		fmt.Fprint(w, `{"iid":3,"title":"Bumps up to v0.0.2","state":"opened","web_url":"https://gitlab.com/shuheiktgw/ghbr/-/merge_requests/3","source_branch":"develop","target_branch":"master"}`)
+	})
+
+	pr, err := client.CreatePullRequest(context.Background(), TestOwner, TestRepo, "Bumps up to v0.0.2", "develop", "master", "Bumps up to v0.0.2")
+	if err != nil {
+		t.Fatalf("#CreatePullRequest returns unexpected error: %v", err)
+	}
+
+	want := &github.PullRequest{
+		Number:  github.Int(3),
+		Title:   github.String("Bumps up to v0.0.2"),
+		State:   github.String("open"),
+		Merged:  github.Bool(false),
+		HTMLURL: github.String("https://gitlab.com/shuheiktgw/ghbr/-/merge_requests/3"),
+		Head:    &github.PullRequestBranch{Ref: github.String("develop")},
+		Base:    &github.PullRequestBranch{Ref: github.String("master")},
+	}
+
+	if !reflect.DeepEqual(pr, want) {
+		t.Errorf("#CreatePullRequest returned %+v, want %+v", pr, want)
+	}
+}
+
+func TestGitLabClient_ListPullRequests(t *testing.T) {
+	client, mux, _, tearDown := setupGitLab()
+	defer tearDown()
+
+	requested := 0
+	mux.HandleFunc(fmt.Sprintf("/api/v4/projects/%s/%s/merge_requests", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		requested++
+		testMethod(t, r, http.MethodGet)
+		testFormValues(t, r, values{"state": "opened", "target_branch": "master", "per_page": "100", "page": strconv.Itoa(requested)})
+
+		// More than 100 Merge Requests are open, so they span two pages
+		n := map[int]int{1: 100, 2: 1}[requested]
+
+		var mrs []string
+		for i := 0; i < n; i++ {
+			mrs = append(mrs, fmt.Sprintf(`{"iid":%d,"state":"opened","source_branch":"feature%d","target_branch":"master"}`, (requested-1)*100+i+1, i))
+		}
+
+		fmt.Fprintf(w, "[%s]", strings.Join(mrs, ","))
+	})
+
+	prs, err := client.ListPullRequests(context.Background(), TestOwner, TestRepo, "master")
+	if err != nil {
+		t.Fatalf("#ListPullRequests returns unexpected error: %v", err)
+	}
+
+	if requested != 3 || len(prs) != 101 || prs[100].GetNumber() != 101 || prs[100].GetHead().GetRef() != "feature0" {
+		t.Errorf("#ListPullRequests requested %d pages and returned %d Merge Requests, want 3 and 101", requested, len(prs))
+	}
+}
+
+func TestGitLabClient_MergePullRequest_Retry(t *testing.T) {
+	client, mux, _, tearDown := setupGitLab()
+	defer tearDown()
+
+	attempts := 0
+	mux.HandleFunc(fmt.Sprintf("/api/v4/projects/%s/%s/merge_requests/3/merge", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPut)
+
+		// GitLab responds with 405 until it finishes checking whether the Merge Request can be merged
+		if attempts++; attempts < 3 {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			fmt.Fprint(w, `{"message":"405 Method Not Allowed"}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"iid":3,"state":"merged"}`)
+	})
+
+	if err := client.MergePullRequest(context.Background(), TestOwner, TestRepo, 3); err != nil {
+		t.Fatalf("#MergePullRequest returns unexpected error: %v", err)
+	}
+
+	if attempts != 3 {
+		t.Errorf("#MergePullRequest requested %d times, want 3", attempts)
+	}
+}
+
+func TestGitLabClient_CreateRepository_WithOrg(t *testing.T) {
+	client, mux, _, tearDown := setupGitLab()
+	defer tearDown()
+
+	mux.HandleFunc("/api/v4/namespaces/ghbr-org", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"id":42,"path":"ghbr-org"}`)
+	})
+
+	mux.HandleFunc("/api/v4/projects", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		testBody(t, r, `{"description":"Homebrew formula for shuheiktgw/ghbr","name":"homebrew-ghbr","namespace_id":42,"path":"homebrew-ghbr","visibility":"private"}`)
+		fmt.Fprint(w, `{"path":"homebrew-ghbr","path_with_namespace":"ghbr-org/homebrew-ghbr","default_branch":"master","web_url":"https://gitlab.com/ghbr-org/homebrew-ghbr","visibility":"private"}`)
+	})
+
+	repo, err := client.CreateRepository(context.Background(), "ghbr-org", "homebrew-ghbr", "Homebrew formula for shuheiktgw/ghbr", "https://gitlab.com/shuheiktgw/ghbr", true)
+	if err != nil {
+		t.Fatalf("#CreateRepository returns unexpected error: %v", err)
+	}
+
+	if got, want := repo.GetFullName(), "ghbr-org/homebrew-ghbr"; got != want {
+		t.Errorf("#CreateRepository returned %s, want %s", got, want)
+	}
+
+	if !repo.GetPrivate() {
+		t.Errorf("#CreateRepository returned a public repository, want a private one")
+	}
+}
+
+func TestGhbr_UpdateFormulaWithMerge_GitLab(t *testing.T) {
+	client, mux, serverURL, tearDown := setupGitLab()
+	defer tearDown()
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{Forge: client, outStream: outStream}
+
+	project := fmt.Sprintf("/api/v4/projects/%s/homebrew-testApp", TestOwner)
+
+	// The tap does not have Formula directory
+	mux.HandleFunc(project+"/repository/tree", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	})
+
+	content := base64.StdEncoding.EncodeToString([]byte(`
+version "v0.0.1"
+url "https://gitlab.com/shuheiktgw/testApp/-/releases/v0.0.1/downloads/testApp_v0.0.1_darwin_amd64.zip"
+sha256 "0001123456789012345678901234567890123456789012345678901234567890"
+`))
+
+	updated := base64.StdEncoding.EncodeToString([]byte(`
+version "v0.0.2"
+url "https://gitlab.com/shuheiktgw/testApp/-/releases/v0.0.2/downloads/testApp_v0.0.2_darwin_amd64.zip"
+sha256 "0002123456789012345678901234567890123456789012345678901234567890"
+`))
+
+	mux.HandleFunc(project+"/repository/files/testApp.rb", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprintf(w, `{"file_path":"testApp.rb","encoding":"base64","content":"%s","last_commit_id":"formulaV0.0.1"}`, content)
+		case http.MethodPut:
+			testBody(t, r, fmt.Sprintf(`{"branch":"bumps_up_to_v0.0.2","commit_message":"Bumps up to v0.0.2","encoding":"base64","content":"%s","last_commit_id":"formulaV0.0.1"}`, updated))
+			fmt.Fprint(w, `{}`)
+		}
+	})
+
+	mux.HandleFunc(project+"/repository/branches", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		testBody(t, r, `{"branch":"bumps_up_to_v0.0.2","ref":"master"}`)
+		fmt.Fprint(w, `{"name":"bumps_up_to_v0.0.2"}`)
+	})
+
+	mux.HandleFunc(project+"/repository/branches/bumps_up_to_v0.0.2", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodDelete)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc(project+"/merge_requests", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		fmt.Fprintf(w, `{"iid":1,"state":"opened","web_url":"%s/shuheiktgw/homebrew-testApp/-/merge_requests/1"}`, serverURL)
+	})
+
+	mux.HandleFunc(project+"/merge_requests/1/merge", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPut)
+		fmt.Fprint(w, `{"iid":1,"state":"merged"}`)
+	})
+
+	release := LatestRelease{
+		version: "v0.0.2",
+		url:     "https://gitlab.com/shuheiktgw/testApp/-/releases/v0.0.2/downloads/testApp_v0.0.2_darwin_amd64.zip",
+		hash:    "0002123456789012345678901234567890123456789012345678901234567890",
+	}
+
+	if err := ghbr.UpdateFormula(context.Background(), Tap{}, TestOwner, "testApp", "master", false, true, FormulaOptions{}, &release); err != nil {
+		t.Fatalf("#UpdateFormula returns unexpected error: %s", err)
+	}
+
+	expectedOutput := "[ghbr] ===> Checking the current formula\n" +
+		"[ghbr] ===> Creating a new feature branch\n" +
+		"[ghbr] ===> Updating the formula file\n" +
+		"[ghbr] ===> Creating a Pull Request\n" +
+		"[ghbr] ===> Merging the Pull Request\n" +
+		"[ghbr] ===> Deleting the branch\n" +
+		"\n\n" +
+		"Yay! Now your formula is up-to-date!\n\n"
+
+	if got := outStream.String(); got != expectedOutput {
+		t.Errorf("#UpdateFormula outputed %+v, want %+v", got, expectedOutput)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// rollbackTimeout is the time allowed for each rollback step
+const rollbackTimeout = 30 * time.Second
+
+// journal records a compensating action for each completed step of an operation,
+// so that the operation is undone in reverse order when a later step fails
+type journal struct {
+	logger *Logger
+	steps  []journalStep
+}
+
+// journalStep is a compensating action, action describes what undo does like "delete the branch bumps_up_to_v0.0.2"
+type journalStep struct {
+	action string
+	undo   func(ctx context.Context) error
+}
+
+// newJournal creates an empty journal for an operation of the Ghbr
+func (g *Ghbr) newJournal() *journal {
+	return &journal{logger: g.logger}
+}
+
+// record registers the undo of a step which has just completed
+func (j *journal) record(action string, undo func(ctx context.Context) error) {
+	j.steps = append(j.steps, journalStep{action: action, undo: undo})
+}
+
+// clear forgets the recorded steps once the operation passes the point where it cannot be undone
+func (j *journal) clear() {
+	j.steps = nil
+}
+
+// finish undoes the recorded steps in reverse order if *err is not nil, and replaces *err with a RollbackError if any of them fails.
+// It is meant to be deferred with the named error result of the operation. Each undo runs with a fresh context
+// so that the operation is rolled back even after its context is canceled or timed out
+func (j *journal) finish(err *error) {
+	if *err == nil {
+		j.clear()
+		return
+	}
+
+	if failures := j.rollback(); len(failures) != 0 {
+		*err = &RollbackError{Err: *err, Failures: failures}
+	}
+}
+
+// rollback undoes the recorded steps in reverse order and returns errors of the undos which failed
+func (j *journal) rollback() []error {
+	var failures []error
+	for i := len(j.steps) - 1; i >= 0; i-- {
+		s := j.steps[i]
+		j.logger.Verbosef("rolling back: %s", s.action)
+
+		ctx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+		if err := s.undo(ctx); err != nil {
+			failures = append(failures, errors.Wrapf(err, "failed to %s", s.action))
+		}
+		cancel()
+	}
+
+	j.clear()
+
+	return failures
+}
+
+// RollbackError is an error of an operation which could not be rolled back completely,
+// the changes listed in Failures are left and need to be cleaned up manually
+type RollbackError struct {
+	Err      error
+	Failures []error
+
+	// Hint tells how to retry the rollback if it is not empty
+	Hint string
+}
+
+func (e *RollbackError) Error() string {
+	failures := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		failures[i] = fmt.Sprintf("  - %s", f)
+	}
+
+	msg := fmt.Sprintf("%s\n\nghbr also failed to roll back the following changes, please clean them up manually:\n%s", e.Err, strings.Join(failures, "\n"))
+	if len(e.Hint) != 0 {
+		msg += "\n\n" + e.Hint
+	}
+
+	return msg
+}
+
+// Cause returns the error of the original failure so that errors.Cause sees through a RollbackError
+func (e *RollbackError) Cause() error {
+	return e.Err
+}
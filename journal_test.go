@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+func TestJournal_Finish(t *testing.T) {
+	failed := errors.New("failed")
+
+	cases := []struct {
+		err      error
+		failures []string
+		want     []string
+	}{
+		{err: nil, want: nil},
+		{err: failed, want: []string{"third", "second", "first"}},
+		{err: failed, failures: []string{"second"}, want: []string{"third", "second", "first"}},
+	}
+
+	for i, tc := range cases {
+		var undone []string
+		j := (&Ghbr{}).newJournal()
+
+		for _, action := range []string{"first", "second", "third"} {
+			action := action
+			j.record(action, func(ctx context.Context) error {
+				if ctx.Err() != nil {
+					t.Errorf("#%d undo of %s ran with a done context", i, action)
+				}
+
+				undone = append(undone, action)
+				for _, f := range tc.failures {
+					if f == action {
+						return errors.New("undo failed")
+					}
+				}
+
+				return nil
+			})
+		}
+
+		err := tc.err
+		j.finish(&err)
+
+		if !reflect.DeepEqual(undone, tc.want) {
+			t.Errorf("#%d finish undid %v, want %v", i, undone, tc.want)
+		}
+
+		if pkgerrors.Cause(err) != tc.err {
+			t.Errorf("#%d finish returned %v, want the cause %v", i, err, tc.err)
+		}
+
+		if len(j.steps) != 0 {
+			t.Errorf("#%d finish left %d steps", i, len(j.steps))
+		}
+
+		if len(tc.failures) == 0 {
+			if _, ok := err.(*RollbackError); ok {
+				t.Errorf("#%d finish returned a RollbackError without rollback failures: %v", i, err)
+			}
+			continue
+		}
+
+		want := "failed\n\nghbr also failed to roll back the following changes, please clean them up manually:\n  - failed to second: undo failed"
+		if err.Error() != want {
+			t.Errorf("#%d finish returned %q, want %q", i, err.Error(), want)
+		}
+	}
+}
+
+func TestJournal_Clear(t *testing.T) {
+	j := (&Ghbr{}).newJournal()
+	j.record("undo", func(ctx context.Context) error {
+		t.Errorf("#finish undid a cleared step")
+		return nil
+	})
+
+	j.clear()
+
+	err := errors.New("failed")
+	j.finish(&err)
+
+	if !strings.Contains(err.Error(), "failed") {
+		t.Errorf("#finish returned %v, want %v", err, "failed")
+	}
+}
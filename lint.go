@@ -0,0 +1,235 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"github.com/spf13/cobra"
+)
+
+// Severities of a lint problem, only errors prevent ghbr from writing a formula
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+var (
+	classRegex   = regexp.MustCompile(`^class\s+(\w+)\s*<\s*Formula\b`)
+	stanzaRegex  = regexp.MustCompile(`^  (\w+)\b`)
+	heredocRegex = regexp.MustCompile(`<<[-~]?['"]?([A-Za-z_]\w*)['"]?`)
+	sha256Regex  = regexp.MustCompile(`^\s*sha256\s+['"]([^'"]*)['"]`)
+	sha256Hex    = regexp.MustCompile(`^[0-9A-Fa-f]{64}$`)
+	httpURLRegex = regexp.MustCompile(`^\s*(url|homepage|head|mirror)\s+['"](http://[^'"]*)['"]`)
+	gitURLRegex  = regexp.MustCompile(`^  url\s.*(\.git['"]|\b(tag|revision|branch|using):)`)
+)
+
+// uniqueStanzas are top level stanzas which must appear at most once in a formula
+var uniqueStanzas = map[string]bool{
+	"desc":      true,
+	"homepage":  true,
+	"version":   true,
+	"url":       true,
+	"sha256":    true,
+	"license":   true,
+	"head":      true,
+	"livecheck": true,
+	"bottle":    true,
+	"revision":  true,
+}
+
+// deprecatedConstructs are constructs Homebrew no longer supports along with how to fix them
+var deprecatedConstructs = []struct {
+	regex   *regexp.Regexp
+	message string
+}{
+	{regexp.MustCompile(`^\s*bottle\s+:unneeded\b`), "`bottle :unneeded` is deprecated, remove it"},
+	{regexp.MustCompile(`^\s*sha1\s`), "`sha1` is deprecated, use `sha256` instead"},
+	{regexp.MustCompile(`^\s*md5\s`), "`md5` is deprecated, use `sha256` instead"},
+	{regexp.MustCompile(`^\s*devel\s+do\b`), "`devel` blocks are deprecated, remove it"},
+	{regexp.MustCompile(`\bdepends_on\s+:x11\b`), "`depends_on :x11` is deprecated, depend on `libx11` instead"},
+	{regexp.MustCompile(`\bENV\.x11\b`), "`ENV.x11` is deprecated, depend on `libx11` instead"},
+}
+
+// lintProblem is a problem found in a formula, line is 1-based and 0 if the problem is not bound to any line
+type lintProblem struct {
+	line              int
+	severity, message string
+}
+
+// String returns the problem in "line: severity: message" form, the line is omitted if it is 0
+func (p lintProblem) String() string {
+	if p.line == 0 {
+		return fmt.Sprintf(" %s: %s", p.severity, p.message)
+	}
+
+	return fmt.Sprintf("%d: %s: %s", p.line, p.severity, p.message)
+}
+
+// lintFormula checks the content of the formula file at the path without Homebrew, the path is
+// used only to check the class name, which must be strcase.ToCamel of the file name
+func lintFormula(content, path string) []lintProblem {
+	var problems []lintProblem
+	add := func(line int, severity, format string, a ...interface{}) {
+		problems = append(problems, lintProblem{line: line, severity: severity, message: fmt.Sprintf(format, a...)})
+	}
+
+	var class string
+	var git, install bool
+	stanzas := make(map[string]int)
+
+	// heredoc is the terminator of the heredoc the current line is in, e.g. caveats
+	var heredoc string
+
+	for i, l := range strings.Split(content, "\n") {
+		line := i + 1
+
+		if len(heredoc) != 0 {
+			if strings.TrimSpace(l) == heredoc {
+				heredoc = ""
+			}
+			continue
+		}
+
+		if ms := heredocRegex.FindStringSubmatch(l); ms != nil {
+			heredoc = ms[1]
+		}
+
+		if ms := classRegex.FindStringSubmatch(l); ms != nil && len(class) == 0 {
+			class = ms[1]
+			if want := strcase.ToCamel(strings.TrimSuffix(filepath.Base(path), ".rb")); class != want {
+				add(line, LintError, "class name %s does not match the file name %s, want %s", class, filepath.Base(path), want)
+			}
+		}
+
+		if ms := stanzaRegex.FindStringSubmatch(l); ms != nil && uniqueStanzas[ms[1]] {
+			if _, ok := stanzas[ms[1]]; ok {
+				add(line, LintError, "duplicate `%s` stanza, it must be defined only once", ms[1])
+			} else {
+				stanzas[ms[1]] = line
+			}
+		}
+
+		if gitURLRegex.MatchString(l) {
+			git = true
+		}
+
+		if installRegex.MatchString(l) {
+			install = true
+		}
+
+		if ms := sha256Regex.FindStringSubmatch(l); ms != nil && !sha256Hex.MatchString(ms[1]) {
+			add(line, LintError, "invalid sha256 %q, it must be 64 hexadecimal characters", ms[1])
+		}
+
+		if ms := httpURLRegex.FindStringSubmatch(l); ms != nil {
+			add(line, LintWarning, "`%s` %s is not https", ms[1], ms[2])
+		}
+
+		for _, d := range deprecatedConstructs {
+			if d.regex.MatchString(l) {
+				add(line, LintWarning, d.message)
+			}
+		}
+	}
+
+	if len(class) == 0 {
+		add(0, LintError, "missing class inheriting Formula")
+	}
+
+	required := []string{"homepage", "url"}
+	if !git {
+		required = append(required, "sha256")
+	}
+
+	for _, s := range required {
+		if _, ok := stanzas[s]; !ok {
+			add(0, LintError, "missing `%s` stanza", s)
+		}
+	}
+
+	if !install {
+		add(0, LintError, "missing `def install`")
+	}
+
+	return problems
+}
+
+// lint checks the formula before it is written and prints warnings, it returns a HandledError
+// if the formula has errors except known ones, which are the problems the formula already had before the update
+func (g *Ghbr) lint(content, path string, known []lintProblem) error {
+	existing := make(map[string]bool)
+	for _, p := range known {
+		existing[p.severity+p.message] = true
+	}
+
+	var errs []string
+	for _, p := range lintFormula(content, path) {
+		if existing[p.severity+p.message] {
+			continue
+		}
+
+		if p.severity == LintWarning {
+			g.report().Warn("%s:%s", path, p)
+			continue
+		}
+
+		errs = append(errs, fmt.Sprintf("%s:%s", path, p))
+	}
+
+	if len(errs) != 0 {
+		return &HandledError{Message: fmt.Sprintf("ghbr aborted writing the formula since it has problems:\n\n%s\n", strings.Join(errs, "\n"))}
+	}
+
+	return nil
+}
+
+func NewLintCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "lint <formula file>...",
+		Short: "Check formula files without Homebrew",
+		Long: "Check formula files for required stanzas, the class name, sha256, https urls, duplicate stanzas and deprecated constructs without Homebrew.\n" +
+			"It fails if any of the files has errors, warnings are only printed.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return cmdError{error: errors.New("missing formula files\n\nPlease pass paths of formula files, e.g. `ghbr lint Formula/ghbr.rb`\n"), exitCode: ExitCodeParseFlagsError}
+			}
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLint(cmd.OutOrStdout(), args)
+		},
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+}
+
+func runLint(w io.Writer, paths []string) error {
+	var errs int
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range lintFormula(string(b), path) {
+			if p.severity == LintError {
+				errs++
+			}
+
+			fmt.Fprintf(w, "%s:%s\n", path, p)
+		}
+	}
+
+	if errs != 0 {
+		return &HandledError{Message: fmt.Sprintf("%d errors found in %d formula files", errs, len(paths))}
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const lintValidFormula = `require 'formula'
+
+class TestApp < Formula
+  homepage 'https://github.com/shuheiktgw/testApp'
+  version 'v0.0.1'
+
+  url 'https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip'
+  sha256 '0001123456789012345678901234567890123456789012345678901234567890'
+  head 'https://github.com/shuheiktgw/testApp.git', branch: 'master'
+
+  resource 'plugin' do
+    url 'https://github.com/shuheiktgw/plugin/releases/download/v1.0.0/plugin.tar.gz'
+    sha256 '0002123456789012345678901234567890123456789012345678901234567890'
+  end
+
+  def install
+    bin.install 'testApp'
+  end
+
+  def caveats
+    <<-'EOF'
+  url 'http://example.com'
+EOF
+  end
+end
+`
+
+func TestLintFormula(t *testing.T) {
+	cases := []struct {
+		path, content string
+		want          []lintProblem
+	}{
+		{
+			path:    "Formula/testApp.rb",
+			content: lintValidFormula,
+		},
+		{
+			path:    "otherApp.rb",
+			content: lintValidFormula,
+			want:    []lintProblem{{line: 3, severity: LintError, message: "class name TestApp does not match the file name otherApp.rb, want OtherApp"}},
+		},
+		{
+			path:    "testApp.rb",
+			content: strings.Replace(lintValidFormula, "0001123456789012345678901234567890123456789012345678901234567890", "abcdefg", 1),
+			want:    []lintProblem{{line: 8, severity: LintError, message: `invalid sha256 "abcdefg", it must be 64 hexadecimal characters`}},
+		},
+		{
+			path:    "testApp.rb",
+			content: strings.Replace(lintValidFormula, "https://github.com/shuheiktgw/plugin", "http://github.com/shuheiktgw/plugin", 1),
+			want:    []lintProblem{{line: 12, severity: LintWarning, message: "`url` http://github.com/shuheiktgw/plugin/releases/download/v1.0.0/plugin.tar.gz is not https"}},
+		},
+		{
+			path:    "testApp.rb",
+			content: strings.Replace(lintValidFormula, "  version 'v0.0.1'\n", "  version 'v0.0.1'\n  version 'v0.0.2'\n  bottle :unneeded\n", 1),
+			want: []lintProblem{
+				{line: 6, severity: LintError, message: "duplicate `version` stanza, it must be defined only once"},
+				{line: 7, severity: LintWarning, message: "`bottle :unneeded` is deprecated, remove it"},
+			},
+		},
+		{
+			path:    "testApp.rb",
+			content: "version 'v0.0.1'\n",
+			want: []lintProblem{
+				{severity: LintError, message: "missing class inheriting Formula"},
+				{severity: LintError, message: "missing `homepage` stanza"},
+				{severity: LintError, message: "missing `url` stanza"},
+				{severity: LintError, message: "missing `sha256` stanza"},
+				{severity: LintError, message: "missing `def install`"},
+			},
+		},
+		{
+			path:    "testApp.rb",
+			content: "class TestApp < Formula\n  homepage 'https://example.com'\n  url 'https://example.com/testApp.git', tag: 'v0.0.1'\n\n  def install\n  end\nend\n",
+		},
+	}
+
+	for i, tc := range cases {
+		if got := lintFormula(tc.content, tc.path); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("#%d lintFormula returned %+v, want %+v", i, got, tc.want)
+		}
+	}
+}
+
+func TestLintFormula_Template(t *testing.T) {
+	f := formula{
+		ClassName:         "TestApp",
+		OriginalRepo:      "shuheiktgw/testApp",
+		Homepage:          "https://github.com/shuheiktgw/testApp",
+		Version:           "v0.0.1",
+		URL:               "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip",
+		Hash:              "0001123456789012345678901234567890123456789012345678901234567890",
+		Caveats:           "  url 'http://example.com'",
+		DependsOn:         []string{"depends_on 'go' => :build"},
+		Install:           []string{"bin.install 'testApp'"},
+		Livecheck:         true,
+		LivecheckStrategy: "github_latest",
+		Head:              &formulaHead{URL: "https://github.com/shuheiktgw/testApp.git", Branch: "master"},
+		Resources:         []formulaResource{{Name: "plugin", URL: "https://example.com/plugin.tar.gz", Hash: "0002123456789012345678901234567890123456789012345678901234567890"}},
+	}
+
+	content, err := f.render("")
+	if err != nil {
+		t.Fatalf("#render returns unexpected error: %s", err)
+	}
+
+	if got := lintFormula(content, "testApp.rb"); len(got) != 0 {
+		t.Errorf("lintFormula returned %+v for the built-in template, want no problem", got)
+	}
+}
+
+func TestGhbr_Lint(t *testing.T) {
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{outStream: outStream}
+
+	content := "version 'v0.0.2'\nurl 'http://example.com/testApp.zip'\nsha256 'abcdefg'\n"
+	known := lintFormula("version 'v0.0.1'\n", "testApp.rb")
+
+	err := ghbr.lint(content, "testApp.rb", known)
+	want := "ghbr aborted writing the formula since it has problems:\n\ntestApp.rb:3: error: invalid sha256 \"abcdefg\", it must be 64 hexadecimal characters\n"
+	if e, ok := err.(*HandledError); !ok || e.Message != want {
+		t.Errorf("#lint returned %v, want %q", err, want)
+	}
+
+	if got, want := outStream.String(), "[ghbr] ===> testApp.rb:2: warning: `url` http://example.com/testApp.zip is not https\n"; got != want {
+		t.Errorf("#lint outputed %q, want %q", got, want)
+	}
+}
+
+func TestGhbr_Lint_KnownProblemMoved(t *testing.T) {
+	ghbr := Ghbr{outStream: ioutil.Discard}
+
+	// The duplicate stanza the formula already had is still known after lines are inserted before it
+	before := "class TestApp < Formula\n  url 'https://example.com/a.zip'\n  url 'https://example.com/b.zip'\nend\n"
+	after := "class TestApp < Formula\n  desc 'Test'\n  url 'https://example.com/a.zip'\n  url 'https://example.com/b.zip'\nend\n"
+
+	known := lintFormula(before, "testApp.rb")
+
+	err := ghbr.lint(after, "testApp.rb", known)
+	if e, ok := err.(*HandledError); ok && strings.Contains(e.Message, "duplicate") {
+		t.Errorf("#lint reported the known duplicate stanza: %s", e.Message)
+	}
+}
+
+func TestLint(t *testing.T) {
+	f, err := ioutil.TempFile("", "testApp.rb")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	defer os.Remove(f.Name())
+
+	f.WriteString("version 'v0.0.1'\n")
+	f.Close()
+
+	outStream := new(bytes.Buffer)
+	cmd := NewLintCmd()
+	cmd.SetOutput(outStream)
+	cmd.SetArgs([]string{f.Name()})
+
+	err = cmd.Execute()
+	if e, ok := err.(*HandledError); !ok || e.Message != "5 errors found in 1 formula files" {
+		t.Errorf("#lint returned %v, want 5 errors", err)
+	}
+
+	if got := outStream.String(); !strings.Contains(got, f.Name()+": error: missing `def install`\n") {
+		t.Errorf("#lint outputed %s, want the missing install", got)
+	}
+
+	cmd = NewLintCmd()
+	cmd.SetArgs([]string{})
+	if e, ok := cmd.Execute().(cmdError); !ok || e.exitCode != ExitCodeParseFlagsError {
+		t.Errorf("#lint without files returned %v, want exit code %d", e, ExitCodeParseFlagsError)
+	}
+}
@@ -0,0 +1,840 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"github.com/tcnksm/go-gitconfig"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	format "gopkg.in/src-d/go-git.v4/plumbing/format/config"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// localPullRequestSection is the section of the git config of a local tap where its Pull Requests are saved,
+// e.g. [ghbr-pull "1"] with the head, base, title and state of #1
+const localPullRequestSection = "ghbr-pull"
+
+// Default author of commits to a local tap if neither --author nor user.name and user.email in .gitconfig is set
+const (
+	defaultAuthorName  = "ghbr"
+	defaultAuthorEmail = "ghbr@localhost"
+)
+
+var authorRegex = regexp.MustCompile(`^\s*([^<>]*?)\s*<([^<>]+)>\s*$`)
+
+// localError is an error of a local tap, with the status code GitHub would respond with in the same situation
+type localError struct {
+	status  int
+	message string
+}
+
+func (e *localError) Error() string {
+	return e.message
+}
+
+// LocalClient updates a tap repository on the local file system, either a working tree or a bare repository, without any API.
+// Branches are updated without checking them out, and Pull Requests are saved to the git config of the repository
+// and merged by fast-forwarding their bases. Changed branches are pushed to the remote unless it is empty
+type LocalClient struct {
+	path   string
+	remote string
+
+	authorName, authorEmail string
+
+	logger *Logger
+
+	// mu serializes accesses to the repository since ghbr sync reads formulae concurrently
+	mu sync.Mutex
+}
+
+// NewLocalClient creates a new LocalClient for the repository at the path, which commits as the author and pushes to the remote
+// unless it is empty. Pushes are logged through the logger unless it is nil
+func NewLocalClient(path, authorName, authorEmail, remote string, logger *Logger) *LocalClient {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	return &LocalClient{path: path, remote: remote, authorName: authorName, authorEmail: authorEmail, logger: logger}
+}
+
+// parseAuthor parses the author in `Name <email>` form, user.name and user.email in .gitconfig are used if it is empty
+func parseAuthor(author string) (name, email string, err error) {
+	if len(author) == 0 {
+		name, _ = gitconfig.Username()
+		email, _ = gitconfig.Email()
+
+		if len(name) == 0 {
+			name = defaultAuthorName
+		}
+
+		if len(email) == 0 {
+			email = defaultAuthorEmail
+		}
+
+		return name, email, nil
+	}
+
+	ms := authorRegex.FindStringSubmatch(author)
+	if ms == nil || len(ms[1]) == 0 {
+		return "", "", fmt.Errorf("invalid author: %s\n\n"+
+			"Please set it in `Name <email>` form via `--author` option\n", author)
+	}
+
+	return ms[1], ms[2], nil
+}
+
+// validateLocalTapFlags validates --tap-path and the flags which only work with it
+func validateLocalTapFlags(path, remote, author string) error {
+	if len(path) == 0 {
+		if len(remote) != 0 || len(author) != 0 {
+			return errors.New("--push and --author can be used only with --tap-path\n")
+		}
+
+		return nil
+	}
+
+	_, _, err := parseAuthor(author)
+
+	return err
+}
+
+func (l *LocalClient) open() (*git.Repository, error) {
+	r, err := git.PlainOpen(l.path)
+	if err == git.ErrRepositoryNotExists {
+		return nil, &localError{status: http.StatusNotFound, message: fmt.Sprintf("%s is not a git repository", l.path)}
+	}
+
+	return r, err
+}
+
+// branch returns the commit at the head of the branch
+func (l *LocalClient) branch(r *git.Repository, branch string) (*object.Commit, error) {
+	ref, err := r.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err == plumbing.ErrReferenceNotFound {
+		return nil, &localError{status: http.StatusNotFound, message: fmt.Sprintf("branch %s does not exist in %s", branch, l.path)}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.CommitObject(ref.Hash())
+}
+
+// signature returns the author and the committer of a new commit
+func (l *LocalClient) signature() object.Signature {
+	return object.Signature{Name: l.authorName, Email: l.authorEmail, When: time.Now()}
+}
+
+// GetLatestRelease always fails since a local tap does not have releases
+func (l *LocalClient) GetLatestRelease(ctx context.Context, owner, repo string) (*github.RepositoryRelease, error) {
+	return nil, errors.Errorf("%s is a local repository, which does not have releases", l.path)
+}
+
+// CreateBranch creates a new branch from the head of the origin
+func (l *LocalClient) CreateBranch(ctx context.Context, owner, repo, origin, new string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r, err := l.open()
+	if err != nil {
+		return err
+	}
+
+	c, err := l.branch(r, origin)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.Reference(plumbing.NewBranchReferenceName(new), false); err == nil {
+		return &localError{status: http.StatusUnprocessableEntity, message: fmt.Sprintf("branch %s already exists in %s", new, l.path)}
+	}
+
+	return l.updateBranch(ctx, r, new, plumbing.ZeroHash, c.Hash)
+}
+
+// DeleteLatestRef deletes the branch, which is deleted from the remote as well
+func (l *LocalClient) DeleteLatestRef(ctx context.Context, owner, repo, branch string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r, err := l.open()
+	if err != nil {
+		return err
+	}
+
+	name := plumbing.NewBranchReferenceName(branch)
+	if _, err := r.Reference(name, false); err != nil {
+		return &localError{status: http.StatusNotFound, message: fmt.Sprintf("branch %s does not exist in %s", branch, l.path)}
+	}
+
+	if head, err := r.Storer.Reference(plumbing.HEAD); err == nil && head.Target() == name {
+		return &localError{status: http.StatusUnprocessableEntity, message: fmt.Sprintf("branch %s is checked out in %s", branch, l.path)}
+	}
+
+	if err := l.deleteRemoteBranch(ctx, r, name); err != nil {
+		return err
+	}
+
+	return r.Storer.RemoveReference(name)
+}
+
+// CreatePullRequest saves a Pull Request from the head to the base
+func (l *LocalClient) CreatePullRequest(ctx context.Context, owner, repo, title, head, base, body string) (*github.PullRequest, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r, err := l.open()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range []string{head, base} {
+		if _, err := l.branch(r, b); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := r.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	number := 1
+	for _, ss := range cfg.Raw.Section(localPullRequestSection).Subsections {
+		pr := l.pullRequest(ss)
+		if pr.GetState() == "open" && pr.GetHead().GetRef() == head && pr.GetBase().GetRef() == base {
+			return nil, &localError{status: http.StatusUnprocessableEntity, message: fmt.Sprintf("a Pull Request already exists for %s", head)}
+		}
+
+		if pr.GetNumber() >= number {
+			number = pr.GetNumber() + 1
+		}
+	}
+
+	ss := cfg.Raw.Section(localPullRequestSection).Subsection(strconv.Itoa(number))
+	ss.SetOption("head", head).SetOption("base", base).SetOption("title", title).SetOption("state", "open")
+
+	if err := r.Storer.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	return l.pullRequest(ss), nil
+}
+
+// ListPullRequests lists open Pull Requests to the base
+func (l *LocalClient) ListPullRequests(ctx context.Context, owner, repo, base string) ([]*github.PullRequest, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r, err := l.open()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := r.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	var prs []*github.PullRequest
+	for _, ss := range cfg.Raw.Section(localPullRequestSection).Subsections {
+		if pr := l.pullRequest(ss); pr.GetState() == "open" && pr.GetBase().GetRef() == base {
+			prs = append(prs, pr)
+		}
+	}
+
+	return prs, nil
+}
+
+// GetPullRequest gets the Pull Request with the number
+func (l *LocalClient) GetPullRequest(ctx context.Context, owner, repo string, number int) (*github.PullRequest, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r, err := l.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return l.getPullRequest(r, number)
+}
+
+// MergePullRequest merges the Pull Request with the number by fast-forwarding its base to its head
+func (l *LocalClient) MergePullRequest(ctx context.Context, owner, repo string, number int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r, err := l.open()
+	if err != nil {
+		return err
+	}
+
+	pr, err := l.getPullRequest(r, number)
+	if err != nil {
+		return err
+	}
+
+	if pr.GetState() != "open" {
+		return &localError{status: http.StatusMethodNotAllowed, message: fmt.Sprintf("Pull Request #%d is not open", number)}
+	}
+
+	head, err := l.branch(r, pr.GetHead().GetRef())
+	if err != nil {
+		return err
+	}
+
+	base, err := l.branch(r, pr.GetBase().GetRef())
+	if err != nil {
+		return err
+	}
+
+	if ok, err := base.IsAncestor(head); err != nil || !ok {
+		return &localError{status: http.StatusMethodNotAllowed, message: fmt.Sprintf("%s cannot be fast-forwarded to %s, please rebase it", pr.GetBase().GetRef(), pr.GetHead().GetRef())}
+	}
+
+	if err := l.updateBranch(ctx, r, pr.GetBase().GetRef(), base.Hash, head.Hash); err != nil {
+		return err
+	}
+
+	return l.setPullRequestState(r, number, "merged")
+}
+
+// ClosePullRequest closes the Pull Request with the number
+func (l *LocalClient) ClosePullRequest(ctx context.Context, owner, repo string, number int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r, err := l.open()
+	if err != nil {
+		return err
+	}
+
+	if _, err := l.getPullRequest(r, number); err != nil {
+		return err
+	}
+
+	return l.setPullRequestState(r, number, "closed")
+}
+
+func (l *LocalClient) getPullRequest(r *git.Repository, number int) (*github.PullRequest, error) {
+	cfg, err := r.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	if !cfg.Raw.Section(localPullRequestSection).HasSubsection(strconv.Itoa(number)) {
+		return nil, &localError{status: http.StatusNotFound, message: fmt.Sprintf("Pull Request #%d does not exist in %s", number, l.path)}
+	}
+
+	return l.pullRequest(cfg.Raw.Section(localPullRequestSection).Subsection(strconv.Itoa(number))), nil
+}
+
+func (l *LocalClient) setPullRequestState(r *git.Repository, number int, state string) error {
+	cfg, err := r.Config()
+	if err != nil {
+		return err
+	}
+
+	cfg.Raw.Section(localPullRequestSection).Subsection(strconv.Itoa(number)).SetOption("state", state)
+
+	return r.Storer.SetConfig(cfg)
+}
+
+// pullRequest converts the Pull Request saved in the config subsection, whose state is either open, closed or merged
+func (l *LocalClient) pullRequest(ss *format.Subsection) *github.PullRequest {
+	number, _ := strconv.Atoi(ss.Name)
+	head, state := ss.Option("head"), ss.Option("state")
+
+	pr := &github.PullRequest{
+		Number:  github.Int(number),
+		Title:   github.String(ss.Option("title")),
+		State:   github.String(state),
+		Merged:  github.Bool(state == "merged"),
+		HTMLURL: github.String(fmt.Sprintf("branch %s of %s", head, l.path)),
+		Head:    &github.PullRequestBranch{Ref: github.String(head)},
+		Base:    &github.PullRequestBranch{Ref: github.String(ss.Option("base"))},
+	}
+
+	if state == "merged" {
+		pr.State = github.String("closed")
+	}
+
+	return pr
+}
+
+// GetFile gets the file on the branch, its SHA is the hash of the blob
+func (l *LocalClient) GetFile(ctx context.Context, owner, repo, branch, path string) (*github.RepositoryContent, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r, err := l.open()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := l.file(r, branch, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if f == nil {
+		return nil, &localError{status: http.StatusNotFound, message: fmt.Sprintf("%s does not exist on %s", path, branch)}
+	}
+
+	content, err := f.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	return &github.RepositoryContent{
+		Type:     github.String("file"),
+		Name:     github.String(filepath.Base(path)),
+		Path:     github.String(path),
+		SHA:      github.String(f.Hash.String()),
+		Encoding: github.String("base64"),
+		Content:  github.String(base64.StdEncoding.EncodeToString([]byte(content))),
+	}, nil
+}
+
+// file returns the file at the path on the branch, or nil if it does not exist
+func (l *LocalClient) file(r *git.Repository, branch, path string) (*object.File, error) {
+	c, err := l.branch(r, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := tree.File(path)
+	if err == object.ErrFileNotFound {
+		return nil, nil
+	}
+
+	return f, err
+}
+
+// GetDirectory lists files in the directory on the branch
+func (l *LocalClient) GetDirectory(ctx context.Context, owner, repo, branch, path string) ([]*github.RepositoryContent, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r, err := l.open()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := l.branch(r, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := tree.Tree(path)
+	if err == object.ErrDirectoryNotFound {
+		return nil, &localError{status: http.StatusNotFound, message: fmt.Sprintf("%s does not exist on %s", path, branch)}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	contents := make([]*github.RepositoryContent, len(dir.Entries))
+	for i, e := range dir.Entries {
+		typ := "file"
+		if e.Mode == filemode.Dir {
+			typ = "dir"
+		}
+
+		contents[i] = &github.RepositoryContent{Type: github.String(typ), Name: github.String(e.Name), Path: github.String(path + "/" + e.Name), SHA: github.String(e.Hash.String())}
+	}
+
+	return contents, nil
+}
+
+// CreateFile commits a new file to the branch, which is created if the repository does not have any commit yet
+func (l *LocalClient) CreateFile(ctx context.Context, owner, repo, branch, path, message string, content []byte) (*github.RepositoryContentResponse, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sha, err := l.commitFile(ctx, branch, path, message, content, func(current *object.File) error {
+		if current != nil {
+			return &localError{status: http.StatusUnprocessableEntity, message: fmt.Sprintf("%s already exists on %s", path, branch)}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &github.RepositoryContentResponse{Content: &github.RepositoryContent{Path: github.String(path), SHA: github.String(sha.String())}}, nil
+}
+
+// UpdateFile commits the content of the file whose SHA is sha to the branch
+func (l *LocalClient) UpdateFile(ctx context.Context, owner, repo, branch, path, sha, message string, content []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err := l.commitFile(ctx, branch, path, message, content, l.checkSHA(branch, path, sha))
+
+	return err
+}
+
+// DeleteFile deletes the file whose SHA is sha from the branch
+func (l *LocalClient) DeleteFile(ctx context.Context, owner, repo, branch, path, sha, message string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err := l.commitFile(ctx, branch, path, message, nil, l.checkSHA(branch, path, sha))
+
+	return err
+}
+
+// checkSHA returns a check that the current file has the SHA, which GitHub responds with 409 Conflict to if it does not
+func (l *LocalClient) checkSHA(branch, path, sha string) func(*object.File) error {
+	return func(current *object.File) error {
+		if current == nil {
+			return &localError{status: http.StatusNotFound, message: fmt.Sprintf("%s does not exist on %s", path, branch)}
+		}
+
+		if current.Hash.String() != sha {
+			return &localError{status: http.StatusConflict, message: fmt.Sprintf("%s on %s does not match %s", path, branch, sha)}
+		}
+
+		return nil
+	}
+}
+
+// commitFile commits the content to the file at the path on the branch and returns the hash of its blob, the file is deleted if the content is nil.
+// check is called with the current file, which is nil if it does not exist, to make sure it is the expected one
+func (l *LocalClient) commitFile(ctx context.Context, branch, path, message string, content []byte, check func(*object.File) error) (plumbing.Hash, error) {
+	r, err := l.open()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	var parent *object.Commit
+	var tree *object.Tree
+
+	parent, err = l.branch(r, branch)
+	switch {
+	case err == nil:
+		if tree, err = parent.Tree(); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	case isNotFound(err) && l.empty(r):
+		// The first commit of an empty repository creates the branch
+		parent = nil
+	default:
+		return plumbing.ZeroHash, err
+	}
+
+	var current *object.File
+	if tree != nil {
+		if current, err = tree.File(path); err != nil && err != object.ErrFileNotFound {
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	if err := check(current); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	blob := plumbing.ZeroHash
+	if content != nil {
+		if blob, err = storeBlob(r, content); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	root, err := writeTree(r, tree, strings.Split(path, "/"), blob)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if root.IsZero() {
+		if root, err = storeObject(r, &object.Tree{}); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	sig := l.signature()
+	commit := &object.Commit{Author: sig, Committer: sig, Message: message, TreeHash: root}
+
+	old := plumbing.ZeroHash
+	if parent != nil {
+		old = parent.Hash
+		commit.ParentHashes = []plumbing.Hash{parent.Hash}
+	}
+
+	h, err := storeObject(r, commit)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return blob, l.updateBranch(ctx, r, branch, old, h)
+}
+
+// empty returns true if the repository does not have any branch
+func (l *LocalClient) empty(r *git.Repository) bool {
+	branches, err := r.Branches()
+	if err != nil {
+		return false
+	}
+
+	defer branches.Close()
+
+	_, err = branches.Next()
+
+	return err != nil
+}
+
+// updateBranch moves the branch from the old commit to the new one, which is created if the old one is zero, and pushes it to the remote.
+// If the branch is checked out, the working tree is reset to the new commit, which fails if it has uncommitted changes
+func (l *LocalClient) updateBranch(ctx context.Context, r *git.Repository, branch string, old, new plumbing.Hash) error {
+	name := plumbing.NewBranchReferenceName(branch)
+
+	w, err := r.Worktree()
+	checkedOut := false
+	if err == nil {
+		head, err := r.Storer.Reference(plumbing.HEAD)
+		checkedOut = err == nil && head.Target() == name
+	}
+
+	if checkedOut {
+		status, err := w.Status()
+		if err != nil {
+			return err
+		}
+
+		if !status.IsClean() {
+			return &localError{status: http.StatusConflict, message: fmt.Sprintf("%s has uncommitted changes on %s, please commit or stash them first", l.path, branch)}
+		}
+	}
+
+	var oldRef *plumbing.Reference
+	if !old.IsZero() {
+		oldRef = plumbing.NewHashReference(name, old)
+	}
+
+	if err := r.Storer.CheckAndSetReference(plumbing.NewHashReference(name, new), oldRef); err != nil {
+		return errors.Wrapf(err, "failed to update %s in %s", branch, l.path)
+	}
+
+	if err := l.push(ctx, r, name.String()+":"+name.String()); err != nil {
+		// Restore the branch so that it stays the same as the remote one
+		if oldRef == nil {
+			r.Storer.RemoveReference(name)
+		} else {
+			r.Storer.SetReference(oldRef)
+		}
+
+		return err
+	}
+
+	if checkedOut {
+		return w.Reset(&git.ResetOptions{Commit: new, Mode: git.HardReset})
+	}
+
+	return nil
+}
+
+// deleteRemoteBranch deletes the branch from the remote if it exists there
+func (l *LocalClient) deleteRemoteBranch(ctx context.Context, r *git.Repository, name plumbing.ReferenceName) error {
+	if len(l.remote) == 0 {
+		return nil
+	}
+
+	remote, err := r.Remote(l.remote)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find remote %s in %s", l.remote, l.path)
+	}
+
+	refs, err := remote.List(&git.ListOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list branches of remote %s", l.remote)
+	}
+
+	for _, ref := range refs {
+		if ref.Name() == name {
+			return l.push(ctx, r, ":"+name.String())
+		}
+	}
+
+	return nil
+}
+
+// push pushes the refspec to the remote unless it is empty
+func (l *LocalClient) push(ctx context.Context, r *git.Repository, refspec string) error {
+	if len(l.remote) == 0 {
+		return nil
+	}
+
+	l.logger.Verbosef("pushing %s to %s", refspec, l.remote)
+
+	err := r.PushContext(ctx, &git.PushOptions{RemoteName: l.remote, RefSpecs: []config.RefSpec{config.RefSpec(refspec)}})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return errors.Wrapf(err, "failed to push %s to %s", refspec, l.remote)
+	}
+
+	return nil
+}
+
+// storeBlob stores the content as a blob and returns its hash
+func storeBlob(r *git.Repository, content []byte) (plumbing.Hash, error) {
+	obj := r.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+
+	w, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if _, err := w.Write(content); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if err := w.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return r.Storer.SetEncodedObject(obj)
+}
+
+// storeObject stores the tree or the commit and returns its hash
+func storeObject(r *git.Repository, o interface {
+	Encode(plumbing.EncodedObject) error
+}) (plumbing.Hash, error) {
+	obj := r.Storer.NewEncodedObject()
+	if err := o.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return r.Storer.SetEncodedObject(obj)
+}
+
+// writeTree stores a copy of the tree whose file at the path is replaced by the blob, or removed if the blob is zero,
+// and returns its hash. The tree may be nil for an empty one, and zero is returned if the new tree is empty
+func writeTree(r *git.Repository, tree *object.Tree, path []string, blob plumbing.Hash) (plumbing.Hash, error) {
+	var entries []object.TreeEntry
+	if tree != nil {
+		entries = append(entries, tree.Entries...)
+	}
+
+	i := 0
+	for ; i < len(entries) && entries[i].Name != path[0]; i++ {
+	}
+
+	entry := object.TreeEntry{Name: path[0], Mode: filemode.Regular, Hash: blob}
+	if i < len(entries) {
+		entry.Mode = entries[i].Mode
+	}
+
+	if len(path) > 1 {
+		var sub *object.Tree
+		if i < len(entries) {
+			if entries[i].Mode != filemode.Dir {
+				return plumbing.ZeroHash, errors.Errorf("%s is not a directory", path[0])
+			}
+
+			var err error
+			if sub, err = r.TreeObject(entries[i].Hash); err != nil {
+				return plumbing.ZeroHash, err
+			}
+		}
+
+		h, err := writeTree(r, sub, path[1:], blob)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		entry.Mode, entry.Hash = filemode.Dir, h
+	}
+
+	switch {
+	case entry.Hash.IsZero() && i < len(entries):
+		entries = append(entries[:i], entries[i+1:]...)
+	case entry.Hash.IsZero():
+	case i < len(entries):
+		entries[i] = entry
+	default:
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		return plumbing.ZeroHash, nil
+	}
+
+	// Git sorts entries by their names, where those of directories end with a slash
+	sort.Slice(entries, func(i, j int) bool { return treeEntryKey(entries[i]) < treeEntryKey(entries[j]) })
+
+	return storeObject(r, &object.Tree{Entries: entries})
+}
+
+func treeEntryKey(e object.TreeEntry) string {
+	if e.Mode == filemode.Dir {
+		return e.Name + "/"
+	}
+
+	return e.Name
+}
+
+// CreateRepository always fails since ghbr does not initialize local repositories
+func (l *LocalClient) CreateRepository(ctx context.Context, org, name, description, homepage string, private bool) (*github.Repository, error) {
+	return nil, errors.Errorf("%s is not a git repository, please create it with `git init` first", l.path)
+}
+
+// GetRepository gets the repository, whose default branch is the one HEAD points to
+func (l *LocalClient) GetRepository(ctx context.Context, owner, name string) (*github.Repository, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r, err := l.open()
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &github.Repository{Name: github.String(strings.TrimSuffix(filepath.Base(l.path), ".git")), HTMLURL: github.String(l.path)}
+	if head, err := r.Storer.Reference(plumbing.HEAD); err == nil && head.Type() == plumbing.SymbolicReference {
+		repo.DefaultBranch = github.String(head.Target().Short())
+	}
+
+	return repo, nil
+}
+
+// GetLogin returns the name of the author
+func (l *LocalClient) GetLogin(ctx context.Context) (string, error) {
+	return l.authorName, nil
+}
+
+// DeleteRepository always fails since ghbr does not delete local repositories
+func (l *LocalClient) DeleteRepository(ctx context.Context, owner, name string) error {
+	return errors.Errorf("ghbr does not delete %s, which is a local repository", l.path)
+}
+
+// RepositoryURL returns the path of the repository
+func (l *LocalClient) RepositoryURL(owner, repo string) string {
+	return l.path
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// setupLocal initializes a repository in a temporary directory along with a LocalClient committing to it,
+// the repository is bare or has testApp.rb on master
+func setupLocal(t *testing.T, bare bool, remote string) (client *LocalClient, dir string, tearDown func()) {
+	dir, err := ioutil.TempDir("", "ghbr")
+	if err != nil {
+		t.Fatalf("Error creating a temporary directory: %s", err)
+	}
+
+	r, err := git.PlainInit(dir, bare)
+	if err != nil {
+		t.Fatalf("Error initializing a repository: %s", err)
+	}
+
+	if len(remote) != 0 {
+		if _, err := r.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remote}}); err != nil {
+			t.Fatalf("Error adding a remote: %s", err)
+		}
+	}
+
+	client = NewLocalClient(dir, "ghbr", "ghbr@example.com", "", nil)
+
+	if !bare {
+		if _, err := client.CreateFile(context.Background(), "", "", "master", "testApp.rb", "Initial commit", []byte("version \"v0.0.1\"\n")); err != nil {
+			t.Fatalf("Error creating the initial commit: %s", err)
+		}
+	}
+
+	return client, dir, func() { os.RemoveAll(dir) }
+}
+
+// testLocalFile returns the content of the file on the branch of the repository at the dir
+func testLocalFile(t *testing.T, dir, branch, path string) string {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatalf("Error opening %s: %s", dir, err)
+	}
+
+	ref, err := r.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		t.Fatalf("Error getting %s: %s", branch, err)
+	}
+
+	c, err := r.CommitObject(ref.Hash())
+	if err != nil {
+		t.Fatalf("Error getting %s: %s", branch, err)
+	}
+
+	f, err := c.File(path)
+	if err != nil {
+		t.Fatalf("Error getting %s on %s: %s", path, branch, err)
+	}
+
+	content, err := f.Contents()
+	if err != nil {
+		t.Fatalf("Error reading %s on %s: %s", path, branch, err)
+	}
+
+	return content
+}
+
+func TestLocalClient_GetFileAndUpdateFile(t *testing.T) {
+	client, dir, tearDown := setupLocal(t, false, "")
+	defer tearDown()
+
+	ctx := context.Background()
+
+	rc, err := client.GetFile(ctx, "", "", "master", "testApp.rb")
+	if err != nil {
+		t.Fatalf("GetFile returned unexpected error: %s", err)
+	}
+
+	if got, err := decodeContent(rc); err != nil || got != "version \"v0.0.1\"\n" {
+		t.Errorf("GetFile returned %q, %v", got, err)
+	}
+
+	if err := client.UpdateFile(ctx, "", "", "master", "testApp.rb", "wrongSHA", "Bumps up to v0.0.2", []byte("version \"v0.0.2\"\n")); statusCode(err) != 409 {
+		t.Errorf("UpdateFile with a wrong SHA returned %v, want 409 Conflict", err)
+	}
+
+	if err := client.UpdateFile(ctx, "", "", "master", "testApp.rb", rc.GetSHA(), "Bumps up to v0.0.2", []byte("version \"v0.0.2\"\n")); err != nil {
+		t.Fatalf("UpdateFile returned unexpected error: %s", err)
+	}
+
+	if got, want := testLocalFile(t, dir, "master", "testApp.rb"), "version \"v0.0.2\"\n"; got != want {
+		t.Errorf("testApp.rb on master is %q, want %q", got, want)
+	}
+
+	// master is checked out, so the working tree follows it
+	if b, err := ioutil.ReadFile(filepath.Join(dir, "testApp.rb")); err != nil || string(b) != "version \"v0.0.2\"\n" {
+		t.Errorf("testApp.rb in the working tree is %q, %v", b, err)
+	}
+
+	if _, err := client.GetFile(ctx, "", "", "master", "Formula/testApp.rb"); !isNotFound(err) {
+		t.Errorf("GetFile of a missing file returned %v, want 404 Not Found", err)
+	}
+}
+
+func TestLocalClient_CreateFileInDirectory(t *testing.T) {
+	client, dir, tearDown := setupLocal(t, true, "")
+	defer tearDown()
+
+	ctx := context.Background()
+
+	if _, err := client.CreateFile(ctx, "", "", "master", "Formula/testApp.rb", "Creates testApp", []byte("testApp")); err != nil {
+		t.Fatalf("CreateFile returned unexpected error: %s", err)
+	}
+
+	if _, err := client.CreateFile(ctx, "", "", "master", "Formula/testApp.rb", "Creates testApp", []byte("testApp")); !isUnprocessable(err) {
+		t.Errorf("CreateFile of an existing file returned %v, want 422 Unprocessable Entity", err)
+	}
+
+	if _, err := client.CreateFile(ctx, "", "", "master", "README.md", "Creates README", []byte("README")); err != nil {
+		t.Fatalf("CreateFile returned unexpected error: %s", err)
+	}
+
+	dirs, err := client.GetDirectory(ctx, "", "", "master", "Formula")
+	if err != nil {
+		t.Fatalf("GetDirectory returned unexpected error: %s", err)
+	}
+
+	if len(dirs) != 1 || dirs[0].GetPath() != "Formula/testApp.rb" {
+		t.Errorf("GetDirectory returned %+v, want Formula/testApp.rb", dirs)
+	}
+
+	if got := testLocalFile(t, dir, "master", "Formula/testApp.rb"); got != "testApp" {
+		t.Errorf("Formula/testApp.rb on master is %q, want testApp", got)
+	}
+
+	repo, err := client.GetRepository(ctx, "", "")
+	if err != nil {
+		t.Fatalf("GetRepository returned unexpected error: %s", err)
+	}
+
+	if repo.GetDefaultBranch() != "master" {
+		t.Errorf("GetRepository returned default branch %s, want master", repo.GetDefaultBranch())
+	}
+}
+
+func TestLocalClient_PullRequest(t *testing.T) {
+	client, dir, tearDown := setupLocal(t, false, "")
+	defer tearDown()
+
+	ctx := context.Background()
+
+	if err := client.CreateBranch(ctx, "", "", "master", "feature"); err != nil {
+		t.Fatalf("CreateBranch returned unexpected error: %s", err)
+	}
+
+	if err := client.CreateBranch(ctx, "", "", "master", "feature"); !isUnprocessable(err) {
+		t.Errorf("CreateBranch of an existing branch returned %v, want 422 Unprocessable Entity", err)
+	}
+
+	rc, err := client.GetFile(ctx, "", "", "feature", "testApp.rb")
+	if err != nil {
+		t.Fatalf("GetFile returned unexpected error: %s", err)
+	}
+
+	if err := client.UpdateFile(ctx, "", "", "feature", "testApp.rb", rc.GetSHA(), "Bumps up to v0.0.2", []byte("version \"v0.0.2\"\n")); err != nil {
+		t.Fatalf("UpdateFile returned unexpected error: %s", err)
+	}
+
+	pr, err := client.CreatePullRequest(ctx, "", "", "Bumps up to v0.0.2", "feature", "master", "")
+	if err != nil {
+		t.Fatalf("CreatePullRequest returned unexpected error: %s", err)
+	}
+
+	if _, err := client.CreatePullRequest(ctx, "", "", "Bumps up to v0.0.2", "feature", "master", ""); !isUnprocessable(err) {
+		t.Errorf("CreatePullRequest of an existing one returned %v, want 422 Unprocessable Entity", err)
+	}
+
+	if prs, err := client.ListPullRequests(ctx, "", "", "master"); err != nil || len(prs) != 1 || prs[0].GetNumber() != pr.GetNumber() {
+		t.Errorf("ListPullRequests returned %+v, %v", prs, err)
+	}
+
+	if err := client.MergePullRequest(ctx, "", "", pr.GetNumber()); err != nil {
+		t.Fatalf("MergePullRequest returned unexpected error: %s", err)
+	}
+
+	if got := testLocalFile(t, dir, "master", "testApp.rb"); got != "version \"v0.0.2\"\n" {
+		t.Errorf("testApp.rb on master is %q after merging", got)
+	}
+
+	if merged, err := client.GetPullRequest(ctx, "", "", pr.GetNumber()); err != nil || !merged.GetMerged() || merged.GetState() != "closed" {
+		t.Errorf("GetPullRequest returned %+v, %v, want a merged one", merged, err)
+	}
+
+	if err := client.DeleteLatestRef(ctx, "", "", "feature"); err != nil {
+		t.Fatalf("DeleteLatestRef returned unexpected error: %s", err)
+	}
+
+	if err := client.DeleteLatestRef(ctx, "", "", "feature"); !isNotFound(err) {
+		t.Errorf("DeleteLatestRef of a deleted branch returned %v, want 404 Not Found", err)
+	}
+}
+
+func TestGhbr_UpdateFormulaWithMerge_Local(t *testing.T) {
+	_, remoteDir, tearDownRemote := setupLocal(t, true, "")
+	defer tearDownRemote()
+
+	client, dir, tearDown := setupLocal(t, false, remoteDir)
+	defer tearDown()
+
+	ctx := context.Background()
+
+	content := `
+version "v0.0.1"
+url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip"
+sha256 "0001123456789012345678901234567890123456789012345678901234567890"
+`
+
+	rc, err := client.GetFile(ctx, "", "", "master", "testApp.rb")
+	if err != nil {
+		t.Fatalf("GetFile returned unexpected error: %s", err)
+	}
+
+	if err := client.UpdateFile(ctx, "", "", "master", "testApp.rb", rc.GetSHA(), "Releases v0.0.1", []byte(content)); err != nil {
+		t.Fatalf("UpdateFile returned unexpected error: %s", err)
+	}
+
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatalf("Error opening %s: %s", dir, err)
+	}
+
+	if err := r.Push(&git.PushOptions{RemoteName: "origin", RefSpecs: []config.RefSpec{"refs/heads/master:refs/heads/master"}}); err != nil {
+		t.Fatalf("Error pushing master: %s", err)
+	}
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{TapForge: NewLocalClient(dir, "ghbr", "ghbr@example.com", "origin", nil), outStream: outStream}
+
+	release := LatestRelease{
+		version: "v0.0.2",
+		url:     "https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp_v0.0.2_darwin_amd64.zip",
+		hash:    "0002123456789012345678901234567890123456789012345678901234567890",
+	}
+
+	if err := ghbr.UpdateFormula(context.Background(), Tap{}, TestOwner, "testApp", "master", false, true, FormulaOptions{}, &release); err != nil {
+		t.Fatalf("#UpdateFormula returns unexpected error: %s", err)
+	}
+
+	want := `
+version "v0.0.2"
+url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.2/testApp_v0.0.2_darwin_amd64.zip"
+sha256 "0002123456789012345678901234567890123456789012345678901234567890"
+`
+
+	for _, d := range []string{dir, remoteDir} {
+		if got := testLocalFile(t, d, "master", "testApp.rb"); got != want {
+			t.Errorf("testApp.rb on master of %s is %q, want %q", d, got, want)
+		}
+
+		r, err := git.PlainOpen(d)
+		if err != nil {
+			t.Fatalf("Error opening %s: %s", d, err)
+		}
+
+		if _, err := r.Reference(plumbing.NewBranchReferenceName("bumps_up_to_v0.0.2"), false); err != plumbing.ErrReferenceNotFound {
+			t.Errorf("bumps_up_to_v0.0.2 of %s is not deleted: %v", d, err)
+		}
+	}
+
+	expectedOutput := "[ghbr] ===> Checking the current formula\n" +
+		"[ghbr] ===> Creating a new feature branch\n" +
+		"[ghbr] ===> Updating the formula file\n" +
+		"[ghbr] ===> Creating a Pull Request\n" +
+		"[ghbr] ===> Merging the Pull Request\n" +
+		"[ghbr] ===> Deleting the branch\n" +
+		"\n\n" +
+		"Yay! Now your formula is up-to-date!\n\n"
+
+	if got := outStream.String(); got != expectedOutput {
+		t.Errorf("#UpdateFormula outputed %+v, want %+v", got, expectedOutput)
+	}
+}
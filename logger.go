@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Log levels, LogNormal is used unless --quiet, --verbose or --debug is passed
+const (
+	LogQuiet = iota
+	LogNormal
+	LogVerbose
+	LogDebug
+)
+
+// redacted replaces secrets in logs
+const redacted = "********"
+
+// maxLoggedBody is the max size of an error response body logged in debug mode
+const maxLoggedBody = 1024
+
+// Logger writes diagnostic logs, secrets like the token are redacted from every line.
+// A nil Logger logs nothing
+type Logger struct {
+	level   int
+	w       io.Writer
+	secrets []string
+}
+
+// NewLogger creates a new Logger writing logs at the level or lower to the w
+func NewLogger(level int, w io.Writer, secrets ...string) *Logger {
+	l := &Logger{level: level, w: w}
+
+	for _, s := range secrets {
+		if len(s) != 0 {
+			l.secrets = append(l.secrets, s)
+		}
+	}
+
+	return l
+}
+
+// Quiet returns true if the progress should not be printed
+func (l *Logger) Quiet() bool {
+	return l != nil && l.level == LogQuiet
+}
+
+// Verbosef logs details of what ghbr does in verbose or debug mode
+func (l *Logger) Verbosef(format string, a ...interface{}) {
+	l.logf(LogVerbose, "verbose", format, a...)
+}
+
+// Debugf logs every API request and response in debug mode
+func (l *Logger) Debugf(format string, a ...interface{}) {
+	l.logf(LogDebug, "debug", format, a...)
+}
+
+func (l *Logger) logf(level int, label, format string, a ...interface{}) {
+	if l == nil || l.level < level {
+		return
+	}
+
+	fmt.Fprintf(l.w, "[ghbr] %s: %s\n", label, l.redact(fmt.Sprintf(format, a...)))
+}
+
+// redact replaces the secrets in the s
+func (l *Logger) redact(s string) string {
+	for _, secret := range l.secrets {
+		s = strings.Replace(s, secret, redacted, -1)
+	}
+
+	return s
+}
+
+// loggingTransport logs API requests and responses through the logger
+type loggingTransport struct {
+	base   http.RoundTripper
+	logger *Logger
+}
+
+// newLoggingTransport wraps the base with a loggingTransport, the base itself is returned if the logger is nil
+func newLoggingTransport(base http.RoundTripper, logger *Logger) http.RoundTripper {
+	if logger == nil {
+		return base
+	}
+
+	return &loggingTransport{base: base, logger: logger}
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	u := redactURL(req.URL.String())
+
+	if err != nil {
+		t.logger.Verbosef("%s %s failed: %s", req.Method, u, err)
+		return nil, err
+	}
+
+	if rl := rateLimit(res.Header); rl != "-" {
+		t.logger.Verbosef("%s %s: %s, rate limit: %s", req.Method, u, res.Status, rl)
+	} else {
+		t.logger.Verbosef("%s %s: %s", req.Method, u, res.Status)
+	}
+
+	t.logger.Debugf("%s %s: %s in %s, request id: %s", req.Method, u, res.Status, elapsed, orDash(res.Header.Get("X-GitHub-Request-Id")))
+
+	if res.StatusCode >= http.StatusBadRequest && t.logger.level >= LogDebug {
+		b, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		res.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+		if len(b) > maxLoggedBody {
+			b = append(b[:maxLoggedBody:maxLoggedBody], "..."...)
+		}
+
+		t.logger.Debugf("%s %s: response body: %s", req.Method, u, b)
+	}
+
+	return res, nil
+}
+
+// redactURL replaces the values of query parameters which may contain credentials in the u
+func redactURL(u string) string {
+	i := strings.Index(u, "?")
+	if i < 0 {
+		return u
+	}
+
+	params := strings.Split(u[i+1:], "&")
+	for j, p := range params {
+		kv := strings.SplitN(p, "=", 2)
+		switch strings.ToLower(kv[0]) {
+		case "access_token", "token", "client_secret", "x-amz-signature", "x-amz-credential", "x-amz-security-token":
+			params[j] = kv[0] + "=" + redacted
+		}
+	}
+
+	return u[:i+1] + strings.Join(params, "&")
+}
+
+// rateLimit formats the rate limit headers of a GitHub API response, "-" is returned if they are missing
+func rateLimit(h http.Header) string {
+	remaining, limit := h.Get("X-RateLimit-Remaining"), h.Get("X-RateLimit-Limit")
+	if len(remaining) == 0 || len(limit) == 0 {
+		return "-"
+	}
+
+	s := fmt.Sprintf("%s/%s remaining", remaining, limit)
+
+	if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		s += fmt.Sprintf(", resets at %s", time.Unix(reset, 0).UTC().Format(time.RFC3339))
+	}
+
+	return s
+}
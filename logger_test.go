@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	cases := []struct {
+		level int
+		want  string
+	}{
+		{level: LogQuiet, want: ""},
+		{level: LogNormal, want: ""},
+		{level: LogVerbose, want: "[ghbr] verbose: token is ********\n"},
+		{level: LogDebug, want: "[ghbr] verbose: token is ********\n[ghbr] debug: token is ********\n"},
+	}
+
+	for i, tc := range cases {
+		w := new(bytes.Buffer)
+		l := NewLogger(tc.level, w, "secret", "")
+
+		l.Verbosef("token is %s", "secret")
+		l.Debugf("token is %s", "secret")
+
+		if got := w.String(); got != tc.want {
+			t.Errorf("#%d Logger logged %q, want %q", i, got, tc.want)
+		}
+	}
+
+	// A nil Logger logs nothing
+	var l *Logger
+	l.Verbosef("test")
+	if l.Quiet() {
+		t.Errorf("#Quiet of a nil Logger returned true, want false")
+	}
+}
+
+func TestLoggingTransport(t *testing.T) {
+	w := new(bytes.Buffer)
+	logger := NewLogger(LogDebug, w, "abcdefg")
+
+	_, mux, serverURL, tearDown := setup()
+	defer tearDown()
+
+	client := NewGitHubClient("abcdefg", logger)
+	client.Client.BaseURL, _ = url.Parse(serverURL + "/")
+
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/releases/latest", TestOwner, TestRepo), func(w http.ResponseWriter, r *http.Request) {
+		testHeader(t, r, "Authorization", "Bearer abcdefg")
+
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		w.Header().Set("X-RateLimit-Reset", "1500000000")
+		w.Header().Set("X-GitHub-Request-Id", "ABCD:1234")
+		http.Error(w, `{"message":"Not Found","token":"abcdefg"}`, http.StatusNotFound)
+	})
+
+	_, err := client.GetLatestRelease(context.Background(), TestOwner, TestRepo)
+	if !isNotFound(err) {
+		t.Fatalf("#GetLatestRelease returned %v, want not found", err)
+	}
+
+	got := w.String()
+	for _, want := range []string{
+		fmt.Sprintf("[ghbr] verbose: GET %s/repos/%s/%s/releases/latest: 404 Not Found, rate limit: 4999/5000 remaining, resets at 2017-07-14T02:40:00Z\n", serverURL, TestOwner, TestRepo),
+		"request id: ABCD:1234\n",
+		`response body: {"message":"Not Found","token":"********"}`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("#RoundTrip logged %s, want %q", got, want)
+		}
+	}
+
+	if strings.Contains(got, "abcdefg") {
+		t.Errorf("#RoundTrip logged the token: %s", got)
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	cases := []struct {
+		url, want string
+	}{
+		{url: "https://api.github.com/repos/a/b", want: "https://api.github.com/repos/a/b"},
+		{url: "https://api.github.com/repos/a/b?ref=master&access_token=abc", want: "https://api.github.com/repos/a/b?ref=master&access_token=********"},
+		{url: "https://s3.amazonaws.com/a.zip?X-Amz-Signature=abc", want: "https://s3.amazonaws.com/a.zip?X-Amz-Signature=********"},
+	}
+
+	for i, tc := range cases {
+		if got := redactURL(tc.url); got != tc.want {
+			t.Errorf("#%d redactURL returned %q, want %q", i, got, tc.want)
+		}
+	}
+}
+
+func TestTextReporter_Quiet(t *testing.T) {
+	w := new(bytes.Buffer)
+	r := newReporter(OutputText, w, true)
+
+	r.Step("check_release", "Checking the latest release")
+	r.Message("Yay!\n")
+	r.Warn("testApp.rb:3: warning: %s", "test")
+
+	if got, want := w.String(), "[ghbr] ===> testApp.rb:3: warning: test\n"; got != want {
+		t.Errorf("quiet textReporter outputed %q, want %q", got, want)
+	}
+}
+
+func TestTextReporter_QuietResult(t *testing.T) {
+	cases := []struct {
+		result Result
+		want   string
+	}{
+		{result: Result{Repository: "https://github.com/shuheiktgw/homebrew-testApp", PullRequest: &PullRequestResult{Number: 1, URL: "https://github.com/shuheiktgw/homebrew-testApp/pull/1"}}, want: "https://github.com/shuheiktgw/homebrew-testApp/pull/1\n"},
+		{result: Result{Repository: "https://github.com/shuheiktgw/homebrew-testApp", UpToDate: true}, want: "up-to-date\n"},
+		{result: Result{Repository: "https://github.com/shuheiktgw/homebrew-testApp", Created: true}, want: "https://github.com/shuheiktgw/homebrew-testApp\n"},
+	}
+
+	for i, tc := range cases {
+		w := new(bytes.Buffer)
+		newReporter(OutputText, w, true).Result(&tc.result)
+
+		if got := w.String(); got != tc.want {
+			t.Errorf("#%d quiet textReporter outputed %q, want %q", i, got, tc.want)
+		}
+	}
+
+	w := new(bytes.Buffer)
+	newReporter(OutputText, w, false).Result(&Result{UpToDate: true})
+	if got := w.String(); got != "" {
+		t.Errorf("textReporter outputed %q, want nothing since the progress tells the result", got)
+	}
+}
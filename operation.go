@@ -0,0 +1,438 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// EnvXDGStateHome is the base directory of state files, operations are saved under $XDG_STATE_HOME/ghbr
+const EnvXDGStateHome = "XDG_STATE_HOME"
+
+// Steps of an operation, which are also reported as the names of the steps
+const (
+	stepCreateBranch      = "create_branch"
+	stepUpdateFile        = "update_file"
+	stepCreatePullRequest = "create_pull_request"
+	stepMergePullRequest  = "merge_pull_request"
+	stepDeleteBranch      = "delete_branch"
+)
+
+// operation is a persisted journal of opening a Pull Request to a tap, which is saved before and after every step
+// so that `ghbr resume` and `ghbr abort` can continue or roll it back after ghbr is killed in the middle of it
+type operation struct {
+	Owner     string            `json:"owner"`
+	Repo      string            `json:"repo"`
+	Base      string            `json:"base"`
+	Branch    string            `json:"branch"`
+	Title     string            `json:"title"`
+	Body      string            `json:"body"`
+	Updates   []operationUpdate `json:"updates"`
+	Merge     bool              `json:"merge"`
+	StartedAt time.Time         `json:"started_at"`
+
+	// Step is the step in progress, or the last one if the operation is killed between steps
+	Step string `json:"step"`
+
+	BranchCreated  bool   `json:"branch_created"`
+	PullRequest    int    `json:"pull_request,omitempty"`
+	PullRequestURL string `json:"pull_request_url,omitempty"`
+	Merged         bool   `json:"merged"`
+
+	// resumed is true if the operation is resumed, whose step in progress may have completed before ghbr was killed
+	resumed bool
+}
+
+// operationUpdate is an update of a file committed to the branch, SHA is the one of the file the update is based on
+type operationUpdate struct {
+	Label   string `json:"label"`
+	Path    string `json:"path"`
+	SHA     string `json:"sha"`
+	Content string `json:"content"`
+	Done    bool   `json:"done"`
+}
+
+// defaultStateDir returns $XDG_STATE_HOME/ghbr, or ~/.local/state/ghbr if XDG_STATE_HOME is not set
+func defaultStateDir() string {
+	if dir := os.Getenv(EnvXDGStateHome); len(dir) != 0 {
+		return filepath.Join(dir, "ghbr")
+	}
+
+	if home := os.Getenv("HOME"); len(home) != 0 {
+		return filepath.Join(home, ".local", "state", "ghbr")
+	}
+
+	return ""
+}
+
+// operationPath returns the path the operation on the tap repository is saved to
+func (g *Ghbr) operationPath(owner, repo string) string {
+	return filepath.Join(g.stateDir, owner, repo+".json")
+}
+
+// saveOperation saves the op, nothing is saved if the state directory is not set
+func (g *Ghbr) saveOperation(op *operation) error {
+	if len(g.stateDir) == 0 {
+		return nil
+	}
+
+	path := g.operationPath(op.Owner, op.Repo)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrap(err, "failed to save the operation")
+	}
+
+	b, err := json.MarshalIndent(op, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that a crash does not leave a broken journal
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return errors.Wrap(err, "failed to save the operation")
+	}
+
+	return errors.Wrap(os.Rename(tmp, path), "failed to save the operation")
+}
+
+// loadOperation returns the incomplete operation on the tap repository, or nil if there is none
+func (g *Ghbr) loadOperation(owner, repo string) (*operation, error) {
+	if len(g.stateDir) == 0 {
+		return nil, nil
+	}
+
+	path := g.operationPath(owner, repo)
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the operation")
+	}
+
+	var op operation
+	if err := json.Unmarshal(b, &op); err != nil {
+		return nil, errors.Wrapf(err, "failed to load the operation, remove %s to start over", path)
+	}
+
+	return &op, nil
+}
+
+// removeOperation removes the saved op once it is completed or rolled back
+func (g *Ghbr) removeOperation(op *operation) {
+	if len(g.stateDir) == 0 {
+		return
+	}
+
+	if err := os.Remove(g.operationPath(op.Owner, op.Repo)); err != nil && !os.IsNotExist(err) {
+		g.logger.Verbosef("failed to remove the operation: %s", err)
+	}
+}
+
+// startStep saves the op with the step in progress
+func (g *Ghbr) startStep(op *operation, step string) error {
+	op.Step = step
+	return g.saveOperation(op)
+}
+
+// runOperation runs the steps of the op which have not completed yet. If any of them fails, the completed ones are rolled back,
+// and the op is kept only if the rollback fails too so that `ghbr abort` can retry it
+func (g *Ghbr) runOperation(ctx context.Context, op *operation) (_ *github.PullRequest, err error) {
+	defer func() {
+		if err != nil {
+			g.operationJournal(op).finish(&err)
+		}
+
+		if e, ok := err.(*RollbackError); ok && len(g.stateDir) != 0 {
+			e.Hint = "Run `ghbr abort` to retry rolling them back"
+			return
+		}
+
+		g.removeOperation(op)
+	}()
+
+	// Create a new feature branch
+	if !op.BranchCreated {
+		g.report().Step(stepCreateBranch, "Creating a new feature branch")
+		if err := g.startStep(op, stepCreateBranch); err != nil {
+			return nil, err
+		}
+
+		// The branch may have been created right before ghbr was killed
+		if err := g.tapForge().CreateBranch(ctx, op.Owner, op.Repo, op.Base, op.Branch); err != nil && !(op.resumed && isUnprocessable(err)) {
+			return nil, err
+		}
+
+		op.BranchCreated = true
+		if err := g.saveOperation(op); err != nil {
+			return nil, err
+		}
+	}
+
+	// Update files on the feature branch
+	for i := range op.Updates {
+		u := &op.Updates[i]
+		if u.Done {
+			continue
+		}
+
+		g.report().Step(stepUpdateFile, "Updating %s", u.Label)
+		if err := g.startStep(op, stepUpdateFile); err != nil {
+			return nil, err
+		}
+
+		done, sha := false, u.SHA
+		if op.resumed {
+			// The file may have been updated right before ghbr was killed
+			rc, err := g.tapForge().GetFile(ctx, op.Owner, op.Repo, op.Branch, u.Path)
+			if err != nil {
+				return nil, err
+			}
+
+			content, err := decodeContent(rc)
+			if err != nil {
+				return nil, err
+			}
+
+			done, sha = content == u.Content, rc.GetSHA()
+		}
+
+		if !done {
+			if err := g.tapForge().UpdateFile(ctx, op.Owner, op.Repo, op.Branch, u.Path, sha, op.Title, []byte(u.Content)); err != nil {
+				return nil, err
+			}
+		}
+
+		u.Done = true
+		if err := g.saveOperation(op); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create a PR from the feature branch to its origin
+	if op.PullRequest == 0 {
+		g.report().Step(stepCreatePullRequest, "Creating a Pull Request")
+		if err := g.startStep(op, stepCreatePullRequest); err != nil {
+			return nil, err
+		}
+
+		var pr *github.PullRequest
+		if op.resumed {
+			// The Pull Request may have been created right before ghbr was killed
+			if pr, err = g.findPullRequest(ctx, op); err != nil {
+				return nil, err
+			}
+		}
+
+		if pr == nil {
+			if pr, err = g.tapForge().CreatePullRequest(ctx, op.Owner, op.Repo, op.Title, op.Branch, op.Base, op.Body); err != nil {
+				return nil, err
+			}
+		}
+
+		op.PullRequest, op.PullRequestURL = pr.GetNumber(), pr.GetHTMLURL()
+		if err := g.saveOperation(op); err != nil {
+			return nil, err
+		}
+
+		g.logger.Verbosef("opened the Pull Request #%d from %s to %s: %s", op.PullRequest, op.Branch, op.Base, op.PullRequestURL)
+	}
+
+	pr := &github.PullRequest{Number: github.Int(op.PullRequest), HTMLURL: github.String(op.PullRequestURL)}
+
+	if !op.Merge {
+		return pr, nil
+	}
+
+	// Merge the PR
+	if !op.Merged {
+		g.report().Step(stepMergePullRequest, "Merging the Pull Request")
+		if err := g.startStep(op, stepMergePullRequest); err != nil {
+			return nil, err
+		}
+
+		merged := false
+		if op.resumed {
+			// The Pull Request may have been merged right before ghbr was killed
+			current, err := g.tapForge().GetPullRequest(ctx, op.Owner, op.Repo, op.PullRequest)
+			if err != nil {
+				return nil, err
+			}
+
+			merged = current.GetMerged()
+		}
+
+		if !merged {
+			if err := g.tapForge().MergePullRequest(ctx, op.Owner, op.Repo, op.PullRequest); err != nil {
+				return nil, err
+			}
+		}
+
+		op.Merged = true
+		if err := g.saveOperation(op); err != nil {
+			return nil, err
+		}
+	}
+
+	g.report().Step(stepDeleteBranch, "Deleting the branch")
+	if err := g.startStep(op, stepDeleteBranch); err != nil {
+		return nil, err
+	}
+
+	if err := g.deleteBranch(ctx, op); err != nil {
+		return nil, err
+	}
+
+	return pr, nil
+}
+
+// operationJournal returns a journal to undo the completed steps of the op. The merged Pull Request cannot be undone,
+// so only its branch is deleted
+func (g *Ghbr) operationJournal(op *operation) *journal {
+	j := g.newJournal()
+
+	if op.BranchCreated {
+		j.record("delete the branch "+op.Branch, func(ctx context.Context) error {
+			return g.deleteBranch(ctx, op)
+		})
+	}
+
+	if op.PullRequest != 0 && !op.Merged {
+		j.record(fmt.Sprintf("close the Pull Request #%d", op.PullRequest), func(ctx context.Context) error {
+			return g.tapForge().ClosePullRequest(ctx, op.Owner, op.Repo, op.PullRequest)
+		})
+	}
+
+	return j
+}
+
+// deleteBranch deletes the branch of the op, which is already deleted if the forge returns 422 or 404
+func (g *Ghbr) deleteBranch(ctx context.Context, op *operation) error {
+	if err := g.tapForge().DeleteLatestRef(ctx, op.Owner, op.Repo, op.Branch); err != nil && !isUnprocessable(err) && !isNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// findPullRequest returns the open Pull Request from the branch of the op, or nil if there is none
+func (g *Ghbr) findPullRequest(ctx context.Context, op *operation) (*github.PullRequest, error) {
+	prs, err := g.tapForge().ListPullRequests(ctx, op.Owner, op.Repo, op.Base)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pr := range prs {
+		if pr.GetHead().GetRef() == op.Branch {
+			return pr, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// lastOperation returns the incomplete operation on the tap, a HandledError is returned if there is none
+func (g *Ghbr) lastOperation(tap Tap, owner, app string) (*operation, error) {
+	formulaOwner, repo := tap.owner(owner), tap.name(app)
+
+	op, err := g.loadOperation(formulaOwner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if op == nil {
+		return nil, &HandledError{Message: fmt.Sprintf("no incomplete operation found on %s/%s", formulaOwner, repo)}
+	}
+
+	return op, nil
+}
+
+// Resume continues the last incomplete operation on the tap from the step ghbr was killed in the middle of
+func (g *Ghbr) Resume(ctx context.Context, tap Tap, owner, app string) error {
+	op, err := g.lastOperation(tap, owner, app)
+	if err != nil {
+		return err
+	}
+
+	g.report().Message("Resuming \"%s\" on %s/%s started at %s\n\n", op.Title, op.Owner, op.Repo, op.StartedAt.Local().Format(time.RFC3339))
+
+	op.resumed = true
+	pr, err := g.runOperation(ctx, op)
+	if err != nil {
+		return err
+	}
+
+	g.report().Message("\n\n")
+
+	if op.Merge {
+		g.report().Message("Yay! The Pull Request #%d has been merged!\n\n", pr.GetNumber())
+		return nil
+	}
+
+	g.report().Message("Yay! The Pull Request #%d is ready!\n\n", pr.GetNumber())
+	g.report().Message("Access %s and merge the Pull Request\n\n", pr.GetHTMLURL())
+
+	return nil
+}
+
+// Abort rolls back the last incomplete operation on the tap, it closes the Pull Request and deletes the branch if they are created.
+// A HandledError is returned if the Pull Request has been merged, which cannot be rolled back
+func (g *Ghbr) Abort(ctx context.Context, tap Tap, owner, app string) error {
+	op, err := g.lastOperation(tap, owner, app)
+	if err != nil {
+		return err
+	}
+
+	// The step in progress may have completed right before ghbr was killed
+	switch {
+	case op.Step == stepCreateBranch && !op.BranchCreated:
+		op.BranchCreated = true
+	case op.Step == stepCreatePullRequest && op.PullRequest == 0:
+		pr, err := g.findPullRequest(ctx, op)
+		if err != nil {
+			return err
+		}
+
+		if pr != nil {
+			op.PullRequest = pr.GetNumber()
+		}
+	case op.Step == stepMergePullRequest && !op.Merged:
+		pr, err := g.tapForge().GetPullRequest(ctx, op.Owner, op.Repo, op.PullRequest)
+		if err != nil {
+			return err
+		}
+
+		op.Merged = pr.GetMerged()
+	}
+
+	g.report().Step("abort", "Rolling back \"%s\" on %s/%s started at %s", op.Title, op.Owner, op.Repo, op.StartedAt.Local().Format(time.RFC3339))
+
+	if failures := g.operationJournal(op).rollback(); len(failures) != 0 {
+		return &RollbackError{
+			Err:      errors.Errorf("failed to abort \"%s\" on %s/%s", op.Title, op.Owner, op.Repo),
+			Failures: failures,
+			Hint:     "Run `ghbr abort` again to retry rolling them back",
+		}
+	}
+
+	g.removeOperation(op)
+
+	// The merged Pull Request cannot be undone, only its branch is deleted
+	if op.Merged {
+		return &HandledError{Message: fmt.Sprintf("the Pull Request #%d has already been merged into %s of %s/%s, so \"%s\" cannot be rolled back\n\n"+
+			"Please revert it on the forge if you do not want it: %s", op.PullRequest, op.Base, op.Owner, op.Repo, op.Title, op.PullRequestURL)}
+	}
+
+	g.report().Message("\n\n")
+	g.report().Message("The operation has been rolled back\n\n")
+
+	return nil
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testOperation() *operation {
+	return &operation{
+		Owner:     TestOwner,
+		Repo:      "homebrew-testApp",
+		Base:      "master",
+		Branch:    "bumps_up_to_v0.0.2",
+		Title:     "Bumps up to v0.0.2",
+		Body:      "Bumps up to v0.0.2",
+		Updates:   []operationUpdate{{Label: "the formula file", Path: "testApp.rb", SHA: "formulaV0.0.1", Content: "new", Done: true}},
+		Merge:     true,
+		StartedAt: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func testStateDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "ghbr")
+	if err != nil {
+		t.Fatalf("failed to create a temporary directory: %s", err)
+	}
+
+	return dir
+}
+
+func TestDefaultStateDir(t *testing.T) {
+	defer os.Setenv(EnvXDGStateHome, os.Getenv(EnvXDGStateHome))
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+
+	os.Setenv(EnvXDGStateHome, "/state")
+	if got, want := defaultStateDir(), "/state/ghbr"; got != want {
+		t.Errorf("#defaultStateDir returned %s, want %s", got, want)
+	}
+
+	os.Setenv(EnvXDGStateHome, "")
+	os.Setenv("HOME", "/home/test")
+	if got, want := defaultStateDir(), "/home/test/.local/state/ghbr"; got != want {
+		t.Errorf("#defaultStateDir returned %s, want %s", got, want)
+	}
+}
+
+func TestGhbr_PullRequest_SavesOperation(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	dir := testStateDir(t)
+	defer os.RemoveAll(dir)
+
+	ghbr := Ghbr{Forge: client, outStream: new(bytes.Buffer), stateDir: dir}
+
+	// Mock CreateBranch request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/git/refs/heads/master", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"object":{"sha":"abcdefg"}}`)
+	})
+
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/git/refs", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"object":{"sha":"abcdefg"}}`)
+	})
+
+	// Mock UpdateFile request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/contents/testApp.rb", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPut)
+	})
+
+	// Mock CreatePullRequest request, the operation is saved with the completed steps by then
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/pulls", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		op, err := ghbr.loadOperation(TestOwner, "homebrew-testApp")
+		if err != nil {
+			t.Fatalf("#loadOperation returned unexpected error: %s", err)
+		}
+
+		if op == nil || !op.BranchCreated || !op.Updates[0].Done || op.Step != stepCreatePullRequest {
+			t.Errorf("#pullRequest saved %+v, want the operation creating the Pull Request", op)
+		}
+
+		fmt.Fprint(w, `{"number":100,"html_url":"https://github.com/shuheiktgw/homebrew-testApp/pull/100"}`)
+	})
+
+	updates := []fileUpdate{{label: "the formula file", path: "testApp.rb", sha: "formulaV0.0.1", content: "new"}}
+	pr, err := ghbr.pullRequest(context.Background(), TestOwner, "homebrew-testApp", "master", "bumps_up_to_v0.0.2", "Bumps up to v0.0.2", "Bumps up to v0.0.2", updates, false)
+	if err != nil {
+		t.Fatalf("#pullRequest returned unexpected error: %s", err)
+	}
+
+	if pr.GetNumber() != 100 || pr.GetHTMLURL() != "https://github.com/shuheiktgw/homebrew-testApp/pull/100" {
+		t.Errorf("#pullRequest returned %+v, want the Pull Request #100", pr)
+	}
+
+	if _, err := os.Stat(ghbr.operationPath(TestOwner, "homebrew-testApp")); !os.IsNotExist(err) {
+		t.Errorf("#pullRequest left the completed operation: %v", err)
+	}
+}
+
+func TestGhbr_PullRequest_Incomplete(t *testing.T) {
+	dir := testStateDir(t)
+	defer os.RemoveAll(dir)
+
+	ghbr := Ghbr{outStream: new(bytes.Buffer), stateDir: dir}
+	if err := ghbr.saveOperation(testOperation()); err != nil {
+		t.Fatalf("#saveOperation returned unexpected error: %s", err)
+	}
+
+	_, err := ghbr.pullRequest(context.Background(), TestOwner, "homebrew-testApp", "master", "bumps_up_to_v0.0.3", "Bumps up to v0.0.3", "Bumps up to v0.0.3", nil, false)
+	if _, ok := err.(*HandledError); !ok || !strings.Contains(err.Error(), "`ghbr resume`") {
+		t.Errorf("#pullRequest returned %v, want a HandledError telling to resume the last operation", err)
+	}
+}
+
+func TestGhbr_Resume(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	dir := testStateDir(t)
+	defer os.RemoveAll(dir)
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{Forge: client, outStream: outStream, stateDir: dir}
+
+	// ghbr was killed while creating the Pull Request, which had been created actually
+	op := testOperation()
+	op.BranchCreated, op.Step = true, stepCreatePullRequest
+	if err := ghbr.saveOperation(op); err != nil {
+		t.Fatalf("#saveOperation returned unexpected error: %s", err)
+	}
+
+	// Mock ListPullRequests request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/pulls", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `[{"number":99,"head":{"ref":"another"}},{"number":100,"head":{"ref":"bumps_up_to_v0.0.2"}}]`)
+	})
+
+	// Mock GetPullRequest request, it has been merged by someone else
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/pulls/100", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"number":100,"merged":true}`)
+	})
+
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/pulls/100/merge", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("#Resume merged the merged Pull Request")
+	})
+
+	// Mock DeleteLatestRef request
+	var deleted bool
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/git/refs/heads/bumps_up_to_v0.0.2", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodDelete)
+		deleted = true
+	})
+
+	if err := ghbr.Resume(context.Background(), Tap{}, TestOwner, "testApp"); err != nil {
+		t.Fatalf("#Resume returned unexpected error: %s", err)
+	}
+
+	if !deleted {
+		t.Errorf("#Resume did not delete the branch")
+	}
+
+	if got, want := outStream.String(), "Yay! The Pull Request #100 has been merged!"; !strings.Contains(got, want) {
+		t.Errorf("#Resume outputed %q, want it to contain %q", got, want)
+	}
+
+	if _, err := os.Stat(ghbr.operationPath(TestOwner, "homebrew-testApp")); !os.IsNotExist(err) {
+		t.Errorf("#Resume left the completed operation: %v", err)
+	}
+
+	// Nothing to resume anymore
+	if err := ghbr.Resume(context.Background(), Tap{}, TestOwner, "testApp"); err == nil || !strings.Contains(err.Error(), "no incomplete operation found") {
+		t.Errorf("#Resume returned %v, want no incomplete operation found", err)
+	}
+}
+
+func TestGhbr_Abort(t *testing.T) {
+	cases := []struct {
+		deleteStatus int
+		wantErr      string
+	}{
+		{deleteStatus: http.StatusNoContent},
+		{deleteStatus: http.StatusForbidden, wantErr: "Run `ghbr abort` again to retry rolling them back"},
+	}
+
+	for i, tc := range cases {
+		client, mux, _, tearDown := setup()
+
+		dir := testStateDir(t)
+		ghbr := Ghbr{Forge: client, outStream: new(bytes.Buffer), stateDir: dir}
+
+		// ghbr was killed while creating the Pull Request, which had been created actually
+		op := testOperation()
+		op.BranchCreated, op.Step = true, stepCreatePullRequest
+		if err := ghbr.saveOperation(op); err != nil {
+			t.Fatalf("#%d saveOperation returned unexpected error: %s", i, err)
+		}
+
+		// Mock ListPullRequests request
+		mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/pulls", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `[{"number":100,"head":{"ref":"bumps_up_to_v0.0.2"}}]`)
+		})
+
+		// Mock ClosePullRequest request
+		var closed bool
+		mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/pulls/100", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+			testMethod(t, r, http.MethodPatch)
+			testBody(t, r, `{"state":"closed"}`+"\n")
+			closed = true
+			fmt.Fprint(w, `{"number":100}`)
+		})
+
+		// Mock DeleteLatestRef request
+		mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/git/refs/heads/bumps_up_to_v0.0.2", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+			testMethod(t, r, http.MethodDelete)
+			w.WriteHeader(tc.deleteStatus)
+		})
+
+		err := ghbr.Abort(context.Background(), Tap{}, TestOwner, "testApp")
+		tearDown()
+
+		if !closed {
+			t.Errorf("#%d Abort did not close the Pull Request", i)
+		}
+
+		_, statErr := os.Stat(ghbr.operationPath(TestOwner, "homebrew-testApp"))
+		os.RemoveAll(dir)
+
+		if len(tc.wantErr) == 0 {
+			if err != nil {
+				t.Errorf("#%d Abort returned unexpected error: %s", i, err)
+			}
+
+			if !os.IsNotExist(statErr) {
+				t.Errorf("#%d Abort left the rolled back operation: %v", i, statErr)
+			}
+
+			continue
+		}
+
+		if _, ok := err.(*RollbackError); !ok || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("#%d Abort returned %v, want a RollbackError containing %q", i, err, tc.wantErr)
+		}
+
+		if statErr != nil {
+			t.Errorf("#%d Abort removed the operation which failed to be rolled back: %v", i, statErr)
+		}
+	}
+}
+
+func TestGhbr_Abort_Merged(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	dir := testStateDir(t)
+	defer os.RemoveAll(dir)
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{Forge: client, outStream: outStream, stateDir: dir}
+
+	// ghbr was killed while merging the Pull Request, which had been merged actually
+	op := testOperation()
+	op.BranchCreated, op.Step = true, stepMergePullRequest
+	op.PullRequest, op.PullRequestURL = 100, "https://github.com/shuheiktgw/homebrew-testApp/pull/100"
+	if err := ghbr.saveOperation(op); err != nil {
+		t.Fatalf("#saveOperation returned unexpected error: %s", err)
+	}
+
+	// Mock GetPullRequest request, the merged Pull Request is not closed
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/pulls/100", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"number":100,"merged":true}`)
+	})
+
+	// Mock DeleteLatestRef request
+	var deleted bool
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/git/refs/heads/bumps_up_to_v0.0.2", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodDelete)
+		deleted = true
+	})
+
+	err := ghbr.Abort(context.Background(), Tap{}, TestOwner, "testApp")
+	if _, ok := err.(*HandledError); !ok || !strings.Contains(err.Error(), "has already been merged") {
+		t.Errorf("#Abort returned %v, want a HandledError telling the Pull Request has been merged", err)
+	}
+
+	if !deleted {
+		t.Errorf("#Abort did not delete the branch")
+	}
+
+	if strings.Contains(outStream.String(), "rolled back") {
+		t.Errorf("#Abort outputed %q, which tells the merged operation has been rolled back", outStream.String())
+	}
+
+	if _, err := os.Stat(ghbr.operationPath(TestOwner, "homebrew-testApp")); !os.IsNotExist(err) {
+		t.Errorf("#Abort left the operation which cannot be rolled back: %v", err)
+	}
+}
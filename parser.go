@@ -4,8 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path"
 	"regexp"
+	"sort"
+	"strings"
 
+	"github.com/common-nighthawk/go-figure"
 	"github.com/spf13/cobra"
 	"github.com/tcnksm/go-gitconfig"
 )
@@ -24,8 +28,37 @@ func setRepositoryFlag(cmd *cobra.Command, dest *string) {
 	cmd.Flags().StringVarP(dest, "repository", "r", defaultRepo(), "GitHub repository")
 }
 
+func setTapFlag(cmd *cobra.Command, dest *string) {
+	cmd.Flags().StringVar(dest, "tap", "", "Tap repository hosting a formula in owner/name or name form (default homebrew-<repository>)")
+}
+
+func setFormulaPathFlag(cmd *cobra.Command, dest *string) {
+	cmd.Flags().StringVar(dest, "formula-path", "", "Path of a formula file in the tap (default Formula/<repository>.rb if the tap has Formula directory, otherwise <repository>.rb)")
+}
+
+// setProjectPreRunE validates the flags of the command with the validate after applying .ghbr.yml and environment variables to them
+func setProjectPreRunE(cmd *cobra.Command, validate func() error) {
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		c, err := loadProjectConfig(ConfigFileName)
+		if err != nil {
+			return cmdError{error: err, exitCode: ExitCodeParseFlagsError}
+		}
+
+		if err := applySettings(cmd, c, nil); err != nil {
+			return cmdError{error: err, exitCode: ExitCodeParseFlagsError}
+		}
+
+		if err := validate(); err != nil {
+			return cmdError{error: err, exitCode: ExitCodeParseFlagsError}
+		}
+
+		return nil
+	}
+}
+
 func validateToken(token string) error {
-	if len(token) == 0 {
+	// GitHub Apps authenticate without tokens
+	if len(token) == 0 && (globalOpts.app == nil || globalOpts.forge != ForgeGitHub) {
 		return fmt.Errorf("missing GitHub personal access token\n\n"+
 			"Please set it via `-t` option, %s environment variable or github.token in .gitconfig\n", EnvGitHubToken)
 	}
@@ -53,6 +86,82 @@ func validateRepository(repo string) error {
 	return nil
 }
 
+func validateTap(tap string) error {
+	if len(tap) == 0 {
+		return nil
+	}
+
+	ss := strings.Split(tap, "/")
+	if len(ss) > 2 || len(ss[0]) == 0 || len(ss[len(ss)-1]) == 0 {
+		return fmt.Errorf("invalid tap: %s\n\n"+
+			"Please set it in `owner/name` or `name` form via `--tap` option, e.g. `--tap shuheiktgw/homebrew-tools`\n", tap)
+	}
+
+	return nil
+}
+
+func validateOutput(output string) error {
+	switch output {
+	case OutputText, OutputJSON, OutputJSONL:
+		return nil
+	}
+
+	return fmt.Errorf("invalid output: %s\n\n"+
+		"Please set one of `text`, `json` or `jsonl` via `--output` option\n", output)
+}
+
+// parseTap returns the Tap from the validated options, the owner in the tap option takes precedence over the org
+func parseTap(org, tap, path string) Tap {
+	t := Tap{Owner: org, Path: path}
+
+	if i := strings.Index(tap, "/"); i >= 0 {
+		t.Owner, t.Name = tap[:i], tap[i+1:]
+	} else {
+		t.Name = tap
+	}
+
+	return t
+}
+
+func validateCask(cask, fromSource bool) error {
+	if cask && fromSource {
+		return errors.New("--cask and --from-source cannot be used together\n\n" +
+			"Casks install prebuilt macOS apps, so please unset either of them\n")
+	}
+
+	return nil
+}
+
+func validateCaveats(caveats string) error {
+	if caveats == "none" || caveats == "figure" || strings.HasPrefix(caveats, "file:") || strings.HasPrefix(caveats, "text:") {
+		return nil
+	}
+
+	return fmt.Errorf("invalid caveats option: %s\n\n"+
+		"Please set one of `none`, `figure`, `file:<path>` or `text:<string>` via `--caveats` option\n", caveats)
+}
+
+func validateFont(font string) error {
+	var fonts []string
+	for _, name := range figure.AssetNames() {
+		if path.Dir(name) != "fonts" || path.Ext(name) != ".flf" {
+			continue
+		}
+
+		f := strings.TrimSuffix(path.Base(name), ".flf")
+		if f == font {
+			return nil
+		}
+
+		fonts = append(fonts, f)
+	}
+
+	sort.Strings(fonts)
+
+	return fmt.Errorf("unknown font: %s\n\n"+
+		"Please set one of the following fonts from go-figure via `-f` option\n%s\n", font, strings.Join(fonts, ", "))
+}
+
 func defaultToken() string {
 	// First search for GITHUB_TOKEN environment variable
 	t := os.Getenv(EnvGitHubToken)
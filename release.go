@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
+
 	"github.com/spf13/cobra"
 )
 
 type releaseOptions struct {
 	token, org, owner, repo, branch string
-	force, merge                    bool
+	tap, formulaPath, asset, output string
+	force, merge, fromSource, cask  bool
+	dependencies                    []string
+	resources                       []Resource
 }
 
 var releaseOpts releaseOptions
@@ -17,7 +22,10 @@ func NewReleaseCmd(generator GhbrGenerator) *cobra.Command {
 		Aliases: []string{"update", "bumpup"},
 		Short:   "Update your Homebrew formula to point to the latest release",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runRelease(generator)
+			ctx, cancel := commandContext(globalOpts.timeout)
+			defer cancel()
+
+			return canceledError(ctx, runRelease(ctx, generator))
 		},
 		SilenceErrors: true,
 		SilenceUsage:  true,
@@ -30,26 +38,71 @@ func NewReleaseCmd(generator GhbrGenerator) *cobra.Command {
 
 func setReleasePreRunE(cmd *cobra.Command) {
 	setReleaseFlags(cmd)
-	err := validateReleaseFlags()
 
+	// Validate flags after they are parsed
 	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
-		if err != nil {
+		if err := loadReleaseConfig(cmd); err != nil {
 			return cmdError{error: err, exitCode: ExitCodeParseFlagsError}
 		}
 
-		return err
+		if err := validateReleaseFlags(); err != nil {
+			return cmdError{error: err, exitCode: ExitCodeParseFlagsError}
+		}
+
+		return nil
 	}
 }
 
-func runRelease(generator GhbrGenerator) error {
+func runRelease(ctx context.Context, generator GhbrGenerator) error {
 	g := generator(releaseOpts.token)
+	g.setOutput(releaseOpts.output)
+	tap := parseTap(releaseOpts.org, releaseOpts.tap, releaseOpts.formulaPath)
+
+	if releaseOpts.cask {
+		lr, err := g.GetLatestCaskRelease(ctx, releaseOpts.owner, releaseOpts.repo, releaseOpts.asset)
+		if err != nil {
+			return err
+		}
+
+		return g.UpdateCask(ctx, tap, releaseOpts.owner, releaseOpts.repo, releaseOpts.branch, releaseOpts.force, releaseOpts.merge, lr)
+	}
 
-	lr, err := g.GetLatestRelease(releaseOpts.owner, releaseOpts.repo)
+	var lr *LatestRelease
+	var err error
+
+	if releaseOpts.fromSource {
+		lr, err = g.GetLatestSourceRelease(ctx, releaseOpts.owner, releaseOpts.repo)
+	} else {
+		lr, err = g.GetLatestRelease(ctx, releaseOpts.owner, releaseOpts.repo, releaseOpts.asset, false)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	opts := FormulaOptions{Dependencies: releaseOpts.dependencies, Resources: releaseOpts.resources}
+
+	return g.UpdateFormula(ctx, tap, releaseOpts.owner, releaseOpts.repo, releaseOpts.branch, releaseOpts.force, releaseOpts.merge, opts, lr)
+}
+
+// loadReleaseConfig sets options which are not set via flags from environment variables and the project configuration
+func loadReleaseConfig(cmd *cobra.Command) error {
+	c, err := loadProjectConfig(ConfigFileName)
 	if err != nil {
 		return err
 	}
 
-	return g.UpdateFormula(releaseOpts.org, releaseOpts.owner, releaseOpts.repo, releaseOpts.branch, releaseOpts.force, releaseOpts.merge, lr)
+	if err := applySettings(cmd, c, nil); err != nil {
+		return err
+	}
+
+	if !cmd.Flags().Changed("depends-on") {
+		releaseOpts.dependencies = c.Dependencies
+	}
+
+	releaseOpts.resources = c.Resources
+
+	return nil
 }
 
 func setReleaseFlags(cmd *cobra.Command) {
@@ -57,7 +110,13 @@ func setReleaseFlags(cmd *cobra.Command) {
 	setTokenFlag(cmd, &releaseOpts.token)
 
 	// Set org flag
-	cmd.Flags().StringVarP(&createOpts.org, "org", "g", "", "GitHub organization hosting a formula on")
+	cmd.Flags().StringVarP(&releaseOpts.org, "org", "g", "", "GitHub organization hosting a formula on")
+
+	// Set tap flag
+	setTapFlag(cmd, &releaseOpts.tap)
+
+	// Set formula-path flag
+	setFormulaPathFlag(cmd, &releaseOpts.formulaPath)
 
 	// Set owner flag
 	setOwnerFlag(cmd, &releaseOpts.owner)
@@ -68,11 +127,26 @@ func setReleaseFlags(cmd *cobra.Command) {
 	// Set branch flag
 	cmd.Flags().StringVarP(&releaseOpts.branch, "branch", "b", "master", "GitHub branch")
 
+	// Set asset flag
+	cmd.Flags().StringVar(&releaseOpts.asset, "asset", "", "Glob pattern of the released asset a formula points to, Darwin AMD64 one is used by default")
+
 	// Set force flag
 	cmd.Flags().BoolVarP(&releaseOpts.force, "force", "f", false, "Forcefully update a formula file, even if it's up-to-date")
 
 	// Set merge flag
 	cmd.Flags().BoolVarP(&releaseOpts.merge, "merge", "m", false, "Merge a Pull Request or not")
+
+	// Set from-source flag
+	cmd.Flags().BoolVar(&releaseOpts.fromSource, "from-source", false, "Update a formula to point to the source tarball of the latest release")
+
+	// Set depends-on flag
+	cmd.Flags().StringSliceVar(&releaseOpts.dependencies, "depends-on", nil, "Runtime dependency of a formula, which replaces the current ones, can be specified multiple times")
+
+	// Set cask flag
+	cmd.Flags().BoolVar(&releaseOpts.cask, "cask", false, "Update a cask instead of a formula")
+
+	// Set output flag
+	cmd.Flags().StringVar(&releaseOpts.output, "output", OutputText, "Output format, one of text, json for the result in a JSON or jsonl for an event per step in JSON lines")
 }
 
 func validateReleaseFlags() error {
@@ -91,5 +165,25 @@ func validateReleaseFlags() error {
 		return err
 	}
 
+	// Tap
+	if err := validateTap(releaseOpts.tap); err != nil {
+		return err
+	}
+
+	// Release URL
+	if err := validateReleaseURL(globalOpts.releaseURL); err != nil {
+		return err
+	}
+
+	// Cask
+	if err := validateCask(releaseOpts.cask, releaseOpts.fromSource); err != nil {
+		return err
+	}
+
+	// Output
+	if err := validateOutput(releaseOpts.output); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -17,6 +17,32 @@ func TestRelease(t *testing.T) {
 	args := strings.Split(arg, " ")
 	cmd.SetArgs(args[1:])
 
+	assetURL := mockRelease(t, client, mux)
+
+	err := cmd.Execute()
+	if err != nil {
+		t.Fatalf("#release returns unexpected error: %s", err)
+	}
+
+	expectedOutput := "[ghbr] ===> Checking the latest release\n" +
+		"[ghbr] ===> Downloading Darwin AMD64 release\n" +
+		"[ghbr] ===> Calculating a checksum of the release\n" +
+		"[ghbr] ===> Checking the current formula\n" +
+		fmt.Sprintf("[ghbr] ===> testApp.rb:3: warning: `url` %s is not https\n", assetURL) +
+		"[ghbr] ===> Creating a new feature branch\n" +
+		"[ghbr] ===> Updating the formula file\n" +
+		"[ghbr] ===> Creating a Pull Request\n" +
+		"\n\n" +
+		"Yay! Now your formula is ready to update!\n\n" +
+		"Access https://github.com/shuheiktgw/homebrew-testApp/pullls/100 and merge the Pull Request\n\n"
+
+	if got := outStream.String(); got != expectedOutput {
+		t.Errorf("#create outputed %+v, want %+v", got, expectedOutput)
+	}
+}
+
+// mockRelease mocks requests to bump up the formula of testApp to v0.0.2 and returns the url of the released asset
+func mockRelease(t *testing.T, client *GitHubClient, mux *http.ServeMux) string {
 	assetPath := fmt.Sprintf("/%s/%s/releases/download/v0.0.2/ghbr_v0.0.2_darwin_amd64.zip", TestOwner, "testApp")
 	assetURL := fmt.Sprintf("%s/%s", client.Client.BaseURL, assetPath)
 
@@ -64,23 +90,84 @@ sha256 "0001123456789012345678901234567890123456789012345678901234567890"
 		fmt.Fprintf(w, `{"number":100, "html_url":"https://github.com/shuheiktgw/homebrew-testApp/pullls/100"}`)
 	})
 
-	err := cmd.Execute()
-	if err != nil {
-		t.Fatalf("#release returns unexpected error: %s", err)
+	return assetURL
+}
+
+func TestRelease_Output(t *testing.T) {
+	cases := []struct {
+		output string
+		want   func(assetURL string) string
+	}{
+		{
+			output: OutputJSON,
+			want: func(assetURL string) string {
+				return fmt.Sprintf(`{"kind":"formula","repository_url":"https://github.com/shuheiktgw/homebrew-testApp","created":false,"path":"testApp.rb",`+
+					`"pull_request":{"number":100,"url":"https://github.com/shuheiktgw/homebrew-testApp/pullls/100"},`+
+					`"old_version":"v0.0.1","new_version":"v0.0.2",`+
+					`"old_sha256":"0001123456789012345678901234567890123456789012345678901234567890",`+
+					`"new_sha256":"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",`+
+					`"url":"%[1]s","merged":false,"up_to_date":false,"warnings":["testApp.rb:3: warning: `+"`url`"+` %[1]s is not https"]}`+"\n", assetURL)
+			},
+		},
+		{
+			output: OutputJSONL,
+			want: func(assetURL string) string {
+				return `{"type":"step","step":"check_release","message":"Checking the latest release"}` + "\n" +
+					`{"type":"step","step":"download_release","message":"Downloading Darwin AMD64 release"}` + "\n" +
+					`{"type":"step","step":"calculate_checksum","message":"Calculating a checksum of the release"}` + "\n" +
+					`{"type":"step","step":"check_current","message":"Checking the current formula"}` + "\n" +
+					fmt.Sprintf(`{"type":"warning","message":"testApp.rb:3: warning: `+"`url`"+` %s is not https"}`+"\n", assetURL) +
+					`{"type":"step","step":"create_branch","message":"Creating a new feature branch"}` + "\n" +
+					`{"type":"step","step":"update_file","message":"Updating the formula file"}` + "\n" +
+					`{"type":"step","step":"create_pull_request","message":"Creating a Pull Request"}` + "\n" +
+					fmt.Sprintf(`{"type":"result","result":{"kind":"formula","repository_url":"https://github.com/shuheiktgw/homebrew-testApp","created":false,"path":"testApp.rb",`+
+						`"pull_request":{"number":100,"url":"https://github.com/shuheiktgw/homebrew-testApp/pullls/100"},`+
+						`"old_version":"v0.0.1","new_version":"v0.0.2",`+
+						`"old_sha256":"0001123456789012345678901234567890123456789012345678901234567890",`+
+						`"new_sha256":"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",`+
+						`"url":"%s","merged":false,"up_to_date":false}}`+"\n", assetURL)
+			},
+		},
 	}
 
-	expectedOutput := "[ghbr] ===> Checking the latest release\n" +
-		"[ghbr] ===> Downloading Darwin AMD64 release\n" +
-		"[ghbr] ===> Calculating a checksum of the release\n" +
-		"[ghbr] ===> Checking the current formula\n" +
-		"[ghbr] ===> Creating a new feature branch\n" +
-		"[ghbr] ===> Updating the formula file\n" +
-		"[ghbr] ===> Creating a Pull Request\n" +
-		"\n\n" +
-		"Yay! Now your formula is ready to update!\n\n" +
-		"Access https://github.com/shuheiktgw/homebrew-testApp/pullls/100 and merge the Pull Request\n\n"
+	for i, tc := range cases {
+		generator, client, outStream, mux, tearDown := ghbrMockGenerator()
 
-	if got := outStream.String(); got != expectedOutput {
-		t.Errorf("#create outputed %+v, want %+v", got, expectedOutput)
+		cmd := NewReleaseCmd(generator)
+		cmd.SetArgs(strings.Split("-t test -o shuheiktgw -r testApp --output "+tc.output, " "))
+
+		assetURL := mockRelease(t, client, mux)
+
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("#%d release returns unexpected error: %s", i, err)
+		}
+
+		if got, want := outStream.String(), tc.want(assetURL); got != want {
+			t.Errorf("#%d release outputed %+v, want %+v", i, got, want)
+		}
+
+		tearDown()
+	}
+}
+
+func TestRelease_InvalidFlags(t *testing.T) {
+	cases := []string{
+		"ghbr release -t test -o shuheiktgw -r testApp --tap /homebrew-tools",
+		"ghbr release -t test -o shuheiktgw -r testApp --cask --from-source",
+	}
+
+	for _, arg := range cases {
+		generator, _, _, _, tearDown := ghbrMockGenerator()
+
+		cmd := NewReleaseCmd(generator)
+		args := strings.Split(arg, " ")
+		cmd.SetArgs(args[1:])
+
+		err := cmd.Execute()
+		if e, ok := err.(cmdError); !ok || e.exitCode != ExitCodeParseFlagsError {
+			t.Errorf("%s returns unexpected error: %v", arg, err)
+		}
+
+		tearDown()
 	}
 }
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Output formats of the progress and the result of create and release
+const (
+	OutputText  = "text"
+	OutputJSON  = "json"
+	OutputJSONL = "jsonl"
+)
+
+// Reporter reports the progress and the result of an operation of ghbr
+type Reporter interface {
+	// Step reports a step which is about to start, name identifies the kind of the step for machines
+	Step(name, format string, a ...interface{})
+
+	// Warn reports a problem which does not stop the operation
+	Warn(format string, a ...interface{})
+
+	// Message reports a message only for humans
+	Message(format string, a ...interface{})
+
+	// Result reports the result of the operation
+	Result(r *Result)
+}
+
+// Result is a result of creating or updating a formula or a cask
+type Result struct {
+	// Kind is either "formula" or "cask"
+	Kind string `json:"kind"`
+
+	// Repository is a URL of the tap repository
+	Repository string `json:"repository_url"`
+
+	// Created is true if the tap repository is created
+	Created bool `json:"created"`
+
+	Path string `json:"path"`
+
+	// PullRequest is nil unless the file is updated through a Pull Request
+	PullRequest *PullRequestResult `json:"pull_request,omitempty"`
+
+	OldVersion string `json:"old_version,omitempty"`
+	NewVersion string `json:"new_version"`
+	OldSHA256  string `json:"old_sha256,omitempty"`
+	NewSHA256  string `json:"new_sha256"`
+	URL        string `json:"url"`
+
+	// Merged is true if the Pull Request is merged
+	Merged bool `json:"merged"`
+
+	// UpToDate is true if the file is not updated since it already points to the release
+	UpToDate bool `json:"up_to_date"`
+
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// PullRequestResult is a Pull Request created by ghbr
+type PullRequestResult struct {
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+}
+
+// newReporter returns a Reporter writing to the w in the output format, the text one is returned for an unknown format.
+// If quiet is true, the text one reports only warnings and a line of the result
+func newReporter(output string, w io.Writer, quiet bool) Reporter {
+	switch output {
+	case OutputJSON:
+		return &jsonReporter{w: w}
+	case OutputJSONL:
+		return &jsonlReporter{enc: json.NewEncoder(w)}
+	default:
+		return &textReporter{w: w, quiet: quiet}
+	}
+}
+
+// textReporter reports the progress in prose, the result is reported only in a line when quiet
+// since the progress already tells it otherwise
+type textReporter struct {
+	w     io.Writer
+	quiet bool
+}
+
+func (r *textReporter) Step(name, format string, a ...interface{}) {
+	if r.quiet {
+		return
+	}
+
+	fmt.Fprintf(r.w, "[ghbr] ===> "+format+"\n", a...)
+}
+
+func (r *textReporter) Warn(format string, a ...interface{}) {
+	fmt.Fprintf(r.w, "[ghbr] ===> "+format+"\n", a...)
+}
+
+func (r *textReporter) Message(format string, a ...interface{}) {
+	if r.quiet {
+		return
+	}
+
+	fmt.Fprintf(r.w, format, a...)
+}
+
+func (r *textReporter) Result(result *Result) {
+	if !r.quiet {
+		return
+	}
+
+	switch {
+	case result.PullRequest != nil:
+		fmt.Fprintln(r.w, result.PullRequest.URL)
+	case result.UpToDate:
+		fmt.Fprintln(r.w, "up-to-date")
+	default:
+		fmt.Fprintln(r.w, result.Repository)
+	}
+}
+
+// jsonReporter reports only the result in a JSON along with warnings reported so far
+type jsonReporter struct {
+	w        io.Writer
+	warnings []string
+}
+
+func (r *jsonReporter) Step(name, format string, a ...interface{}) {}
+
+func (r *jsonReporter) Warn(format string, a ...interface{}) {
+	r.warnings = append(r.warnings, fmt.Sprintf(format, a...))
+}
+
+func (r *jsonReporter) Message(format string, a ...interface{}) {}
+
+func (r *jsonReporter) Result(result *Result) {
+	result.Warnings = r.warnings
+	json.NewEncoder(r.w).Encode(result)
+}
+
+// jsonlReporter reports an event per line in JSON, messages for humans are omitted
+type jsonlReporter struct {
+	enc *json.Encoder
+}
+
+// reportEvent is an event reported by jsonlReporter, Type is one of "step", "warning" or "result"
+type reportEvent struct {
+	Type    string  `json:"type"`
+	Step    string  `json:"step,omitempty"`
+	Message string  `json:"message,omitempty"`
+	Result  *Result `json:"result,omitempty"`
+}
+
+func (r *jsonlReporter) Step(name, format string, a ...interface{}) {
+	r.enc.Encode(reportEvent{Type: "step", Step: name, Message: fmt.Sprintf(format, a...)})
+}
+
+func (r *jsonlReporter) Warn(format string, a ...interface{}) {
+	r.enc.Encode(reportEvent{Type: "warning", Message: fmt.Sprintf(format, a...)})
+}
+
+func (r *jsonlReporter) Message(format string, a ...interface{}) {}
+
+func (r *jsonlReporter) Result(result *Result) {
+	r.enc.Encode(reportEvent{Type: "result", Result: result})
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// APIError is an error response from the API of a forge other than GitHub
+type APIError struct {
+	Method, URL string
+	StatusCode  int
+	Message     string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, redactURL(e.URL), e.StatusCode, e.Message)
+}
+
+// restClient sends requests to a JSON REST API, used by clients of forges which do not have their own libraries
+type restClient struct {
+	// baseURL is the URL of the API ending with a slash
+	baseURL string
+	client  *http.Client
+
+	// header is set to every request, e.g. the credential
+	header http.Header
+}
+
+// newRESTClient creates a restClient for the API at the baseURL, which retries and logs requests like GitHubClient
+func newRESTClient(baseURL string, header http.Header, logger *Logger) *restClient {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+
+	return &restClient{
+		baseURL: baseURL,
+		client:  &http.Client{Transport: newRetryTransport(newLoggingTransport(http.DefaultTransport, logger), logger)},
+		header:  header,
+	}
+}
+
+// do sends a request to the path relative to the base URL, which must be escaped already.
+// in is encoded to the request body and the response body is decoded to out unless they are nil
+func (c *restClient) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	base, err := url.Parse(c.baseURL)
+	if err != nil {
+		return errors.Wrapf(err, "invalid API URL: %s", c.baseURL)
+	}
+
+	u, err := base.Parse(path)
+	if err != nil {
+		return err
+	}
+
+	if len(query) != 0 {
+		u.RawQuery = query.Encode()
+	}
+
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusMultipleChoices {
+		b, _ := ioutil.ReadAll(res.Body)
+		return &APIError{Method: method, URL: u.String(), StatusCode: res.StatusCode, Message: errorMessage(b)}
+	}
+
+	if out == nil || res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	return errors.Wrapf(json.NewDecoder(res.Body).Decode(out), "failed to decode the response of %s %s", method, redactURL(u.String()))
+}
+
+// errorMessage extracts the message from an error response body, which is either a string or any JSON in "message" or "error"
+func errorMessage(body []byte) string {
+	var e struct {
+		Message json.RawMessage `json:"message"`
+		Error   json.RawMessage `json:"error"`
+	}
+
+	if err := json.Unmarshal(body, &e); err != nil {
+		return strings.TrimSpace(string(body))
+	}
+
+	for _, raw := range []json.RawMessage{e.Message, e.Error} {
+		if len(raw) == 0 {
+			continue
+		}
+
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return s
+		}
+
+		return string(raw)
+	}
+
+	return strings.TrimSpace(string(body))
+}
+
+// retryMerge calls merge up to retries times at the interval while it fails with one of the statuses,
+// which forges respond with until they finish checking whether a Pull Request can be merged
+func retryMerge(ctx context.Context, retries int, interval time.Duration, merge func() error, statuses ...int) error {
+	var err error
+	for i := 0; i < retries; i++ {
+		if err = merge(); err == nil {
+			return nil
+		}
+
+		e, ok := err.(*APIError)
+		if !ok || !containsStatus(statuses, e.StatusCode) {
+			return err
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return err
+}
+
+func containsStatus(statuses []int, status int) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
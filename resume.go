@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type operationOptions struct {
+	token, org, owner, repo, tap string
+}
+
+var operationOpts operationOptions
+
+func NewResumeCmd(generator GhbrGenerator) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resume",
+		Short: "Continue the last incomplete operation on your tap",
+		Long: "Continue the last operation on your tap which ghbr was killed in the middle of, e.g. merge the Pull Request it has opened.\n" +
+			"Operations are saved under $XDG_STATE_HOME/ghbr, or ~/.local/state/ghbr if XDG_STATE_HOME is not set.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(globalOpts.timeout)
+			defer cancel()
+
+			return canceledError(ctx, runResume(ctx, generator))
+		},
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	setOperationPreRunE(cmd)
+
+	return cmd
+}
+
+func NewAbortCmd(generator GhbrGenerator) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "abort",
+		Short: "Roll back the last incomplete operation on your tap",
+		Long: "Roll back the last operation on your tap which ghbr was killed in the middle of,\n" +
+			"i.e. close the Pull Request and delete the feature branch it has created.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(globalOpts.timeout)
+			defer cancel()
+
+			return canceledError(ctx, runAbort(ctx, generator))
+		},
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	setOperationPreRunE(cmd)
+
+	return cmd
+}
+
+func setOperationPreRunE(cmd *cobra.Command) {
+	setOperationFlags(cmd)
+
+	// Validate flags after they are parsed
+	setProjectPreRunE(cmd, validateOperationFlags)
+}
+
+func runResume(ctx context.Context, generator GhbrGenerator) error {
+	g := generator(operationOpts.token)
+
+	return g.Resume(ctx, parseTap(operationOpts.org, operationOpts.tap, ""), operationOpts.owner, operationOpts.repo)
+}
+
+func runAbort(ctx context.Context, generator GhbrGenerator) error {
+	g := generator(operationOpts.token)
+
+	return g.Abort(ctx, parseTap(operationOpts.org, operationOpts.tap, ""), operationOpts.owner, operationOpts.repo)
+}
+
+func setOperationFlags(cmd *cobra.Command) {
+	// Set token flag
+	setTokenFlag(cmd, &operationOpts.token)
+
+	// Set org flag
+	cmd.Flags().StringVarP(&operationOpts.org, "org", "g", "", "GitHub organization hosting a formula on")
+
+	// Set owner flag
+	setOwnerFlag(cmd, &operationOpts.owner)
+
+	// Set repository flag
+	setRepositoryFlag(cmd, &operationOpts.repo)
+
+	// Set tap flag
+	setTapFlag(cmd, &operationOpts.tap)
+}
+
+func validateOperationFlags() error {
+	// Token
+	if err := validateToken(operationOpts.token); err != nil {
+		return err
+	}
+
+	// Tap
+	if err := validateTap(operationOpts.tap); err != nil {
+		return err
+	}
+
+	// Owner is not necessary if the tap has its owner
+	if len(operationOpts.org) == 0 && !strings.Contains(operationOpts.tap, "/") {
+		if err := validateOwner(operationOpts.owner); err != nil {
+			return err
+		}
+	}
+
+	// Repository is not necessary if the tap is given
+	if len(operationOpts.tap) == 0 {
+		return validateRepository(operationOpts.repo)
+	}
+
+	return nil
+}
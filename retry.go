@@ -0,0 +1,182 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Retry policy of API requests and downloads
+const (
+	maxRetries     = 3
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 30 * time.Second
+
+	// maxRateLimitWait is the longest time ghbr waits for a rate limit to reset, the response is returned as is if it takes longer
+	maxRateLimitWait = 2 * time.Minute
+)
+
+// retryTransport retries idempotent requests failed with a network error or a 5xx status with jittered exponential backoff,
+// and any request rejected by a rate limit once the limit resets
+type retryTransport struct {
+	base   http.RoundTripper
+	logger *Logger
+
+	maxRetries                            int
+	baseDelay, maxDelay, maxRateLimitWait time.Duration
+}
+
+// newRetryTransport wraps the base with a retryTransport with the default retry policy
+func newRetryTransport(base http.RoundTripper, logger *Logger) http.RoundTripper {
+	return &retryTransport{
+		base:             base,
+		logger:           logger,
+		maxRetries:       maxRetries,
+		baseDelay:        retryBaseDelay,
+		maxDelay:         retryMaxDelay,
+		maxRateLimitWait: maxRateLimitWait,
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req
+
+	for attempt := 0; ; attempt++ {
+		res, err := t.base.RoundTrip(r)
+
+		wait, reason, ok := t.retryAfter(req, attempt, res, err)
+		if !ok {
+			return res, err
+		}
+
+		if res != nil {
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		t.logger.Verbosef("%s %s: %s, retrying in %s (%d/%d)", req.Method, redactURL(req.URL.String()), reason, wait.Round(time.Millisecond), attempt+1, t.maxRetries)
+
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+
+		// Rewind the body since the previous attempt consumed it
+		if req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+
+			r = new(http.Request)
+			*r = *req
+			r.Body = body
+		}
+	}
+}
+
+// retryAfter returns how long to wait before retrying the request and why, ok is false if it should not be retried
+func (t *retryTransport) retryAfter(req *http.Request, attempt int, res *http.Response, err error) (wait time.Duration, reason string, ok bool) {
+	if attempt >= t.maxRetries || req.Context().Err() != nil {
+		return 0, "", false
+	}
+
+	if req.Body != nil && req.GetBody == nil {
+		return 0, "", false
+	}
+
+	if err != nil {
+		if !idempotent(req.Method) {
+			return 0, "", false
+		}
+
+		return t.backoff(attempt), err.Error(), true
+	}
+
+	// Requests rejected by a rate limit are not processed, so they are retried regardless of the method
+	if res.StatusCode == http.StatusForbidden || res.StatusCode == http.StatusTooManyRequests {
+		wait, ok := rateLimitWait(res.Header)
+		if !ok {
+			if res.StatusCode == http.StatusForbidden {
+				return 0, "", false
+			}
+
+			wait = t.backoff(attempt)
+		}
+
+		if wait > t.maxRateLimitWait {
+			t.logger.Verbosef("%s %s: rate limited until %s, giving up since it is too long to wait", req.Method, redactURL(req.URL.String()), time.Now().Add(wait).Round(time.Second).Format(time.RFC3339))
+			return 0, "", false
+		}
+
+		return wait, "rate limited", true
+	}
+
+	if res.StatusCode >= http.StatusInternalServerError && idempotent(req.Method) {
+		if wait, ok := rateLimitWait(res.Header); ok && wait <= t.maxRateLimitWait {
+			return wait, res.Status, true
+		}
+
+		return t.backoff(attempt), res.Status, true
+	}
+
+	return 0, "", false
+}
+
+// backoff returns an exponentially growing delay for the attempt, half of which is randomized to spread retries
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	d := t.baseDelay << uint(attempt)
+	if d > t.maxDelay || d <= 0 {
+		d = t.maxDelay
+	}
+
+	half := int64(d / 2)
+
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// rateLimitWait returns how long the headers ask to wait from Retry-After, or X-RateLimit-Reset if the quota is used up.
+// ok is false if neither of them is present
+func rateLimitWait(h http.Header) (wait time.Duration, ok bool) {
+	if ra := h.Get("Retry-After"); len(ra) != 0 {
+		if s, err := strconv.Atoi(ra); err == nil {
+			return time.Duration(s) * time.Second, true
+		}
+
+		if at, err := http.ParseTime(ra); err == nil {
+			return nonNegative(time.Until(at)), true
+		}
+	}
+
+	if h.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			// Wait a second more since the reset time is truncated to seconds
+			return nonNegative(time.Until(time.Unix(reset, 0)) + time.Second), true
+		}
+	}
+
+	return 0, false
+}
+
+// idempotent returns true if requests of the method can be retried safely after they reach the server.
+// PUT is not, since updating a file in a branch fails or commits twice if the first request is applied
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+
+	return d
+}
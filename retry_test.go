@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testRetryClient() *http.Client {
+	return &http.Client{Transport: &retryTransport{
+		base:             http.DefaultTransport,
+		maxRetries:       3,
+		baseDelay:        time.Millisecond,
+		maxDelay:         10 * time.Millisecond,
+		maxRateLimitWait: 2 * time.Second,
+	}}
+}
+
+func TestRetryTransport(t *testing.T) {
+	cases := []struct {
+		method   string
+		statuses []int
+		header   http.Header
+		want     int
+		requests int
+	}{
+		{method: http.MethodGet, statuses: []int{http.StatusOK}, want: http.StatusOK, requests: 1},
+		{method: http.MethodGet, statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK}, want: http.StatusOK, requests: 3},
+		{method: http.MethodGet, statuses: []int{http.StatusBadGateway}, want: http.StatusBadGateway, requests: 4},
+		{method: http.MethodGet, statuses: []int{http.StatusNotFound}, want: http.StatusNotFound, requests: 1},
+		{method: http.MethodPut, statuses: []int{http.StatusBadGateway, http.StatusOK}, want: http.StatusBadGateway, requests: 1},
+		{method: http.MethodPost, statuses: []int{http.StatusBadGateway, http.StatusOK}, want: http.StatusBadGateway, requests: 1},
+
+		// Rate limited requests are retried regardless of the method
+		{method: http.MethodPost, statuses: []int{http.StatusForbidden, http.StatusCreated}, header: http.Header{"Retry-After": {"0"}}, want: http.StatusCreated, requests: 2},
+		{method: http.MethodGet, statuses: []int{http.StatusTooManyRequests, http.StatusOK}, want: http.StatusOK, requests: 2},
+		{method: http.MethodGet, statuses: []int{http.StatusForbidden, http.StatusOK}, want: http.StatusForbidden, requests: 1},
+		{
+			method:   http.MethodGet,
+			statuses: []int{http.StatusForbidden, http.StatusOK},
+			header:   http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {strconv.FormatInt(time.Now().Unix()-1, 10)}},
+			want:     http.StatusOK,
+			requests: 2,
+		},
+		{
+			method:   http.MethodGet,
+			statuses: []int{http.StatusForbidden, http.StatusOK},
+			header:   http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)}},
+			want:     http.StatusForbidden,
+			requests: 1,
+		},
+	}
+
+	for i, tc := range cases {
+		var requests int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if b, _ := ioutil.ReadAll(r.Body); r.Method != http.MethodGet && string(b) != "body" {
+				t.Errorf("#%d request %d has body %q, want %q", i, requests, b, "body")
+			}
+
+			status := tc.statuses[len(tc.statuses)-1]
+			if requests < len(tc.statuses) {
+				status = tc.statuses[requests]
+			}
+			requests++
+
+			if status != http.StatusOK && status != http.StatusCreated {
+				for k, v := range tc.header {
+					w.Header()[k] = v
+				}
+			}
+
+			w.WriteHeader(status)
+		}))
+
+		var body io.Reader
+		if tc.method != http.MethodGet {
+			body = strings.NewReader("body")
+		}
+
+		req, _ := http.NewRequest(tc.method, server.URL, body)
+
+		res, err := testRetryClient().Do(req)
+		server.Close()
+
+		if err != nil {
+			t.Errorf("#%d RoundTrip returned unexpected error: %s", i, err)
+			continue
+		}
+
+		if res.StatusCode != tc.want {
+			t.Errorf("#%d RoundTrip returned %d, want %d", i, res.StatusCode, tc.want)
+		}
+
+		if requests != tc.requests {
+			t.Errorf("#%d RoundTrip sent %d requests, want %d", i, requests, tc.requests)
+		}
+	}
+}
+
+func TestRetryTransport_Logging(t *testing.T) {
+	w := new(bytes.Buffer)
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	c := testRetryClient()
+	c.Transport.(*retryTransport).logger = NewLogger(LogVerbose, w)
+
+	if _, err := c.Get(server.URL); err != nil {
+		t.Fatalf("#RoundTrip returned unexpected error: %s", err)
+	}
+
+	want := fmt.Sprintf("[ghbr] verbose: GET %s: 502 Bad Gateway, retrying in ", server.URL)
+	if got := w.String(); !strings.HasPrefix(got, want) || !strings.HasSuffix(got, "(1/3)\n") {
+		t.Errorf("#RoundTrip logged %q, want %q", got, want+"... (1/3)\n")
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	cases := []struct {
+		header   http.Header
+		min, max time.Duration
+		ok       bool
+	}{
+		{header: http.Header{}, ok: false},
+		{header: http.Header{"Retry-After": {"30"}}, min: 30 * time.Second, max: 30 * time.Second, ok: true},
+		{header: http.Header{"Retry-After": {time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)}}, min: 58 * time.Second, max: time.Minute, ok: true},
+		{header: http.Header{"X-Ratelimit-Remaining": {"10"}, "X-Ratelimit-Reset": {strconv.FormatInt(time.Now().Unix(), 10)}}, ok: false},
+		{header: http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)}}, min: time.Minute - time.Second, max: time.Minute + time.Second, ok: true},
+	}
+
+	for i, tc := range cases {
+		wait, ok := rateLimitWait(tc.header)
+		if ok != tc.ok {
+			t.Errorf("#%d rateLimitWait returned ok %t, want %t", i, ok, tc.ok)
+			continue
+		}
+
+		if wait < tc.min || wait > tc.max {
+			t.Errorf("#%d rateLimitWait returned %s, want between %s and %s", i, wait, tc.min, tc.max)
+		}
+	}
+}
+
+func TestRetryTransport_Backoff(t *testing.T) {
+	tr := &retryTransport{baseDelay: time.Second, maxDelay: 30 * time.Second}
+
+	for attempt, max := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second} {
+		if d := tr.backoff(attempt); d < max/2 || d > max {
+			t.Errorf("#backoff(%d) returned %s, want between %s and %s", attempt, d, max/2, max)
+		}
+	}
+}
@@ -1,7 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -12,10 +18,14 @@ const (
 	// Error Starts from 10
 	ExitCodeError = 10 + iota
 	ExitCodeParseFlagsError
+	ExitCodeOutdated
 )
 
 const EnvGitHubToken = "GITHUB_TOKEN"
 
+// EnvTapToken is the environment variable holding the token of the forge passed via --tap-forge or --tap-forge-url
+const EnvTapToken = "GHBR_TAP_TOKEN"
+
 type cmdError struct {
 	error
 	exitCode int
@@ -25,15 +35,247 @@ func (cr cmdError) Error() string {
 	return cr.error.Error()
 }
 
+type globalOptions struct {
+	quiet, verbose, debug bool
+	timeout               time.Duration
+	forge, forgeURL       string
+
+	// tapPath is the path of a local tap repository, which is committed to as the author and pushed to the push remote
+	tapPath, push, author string
+
+	// tapForge hosts the tap instead of the forge unless it is empty, it is the forge at tapForgeURL if only the URL is passed
+	tapForge, tapForgeURL, tapToken string
+
+	// releaseURL is an index or a bucket the latest release is found in instead of the forge
+	releaseURL string
+
+	// app is the GitHub App loaded from appID, appInstallationID and appPrivateKey, GitHub is authenticated as it unless it is nil
+	appID, appInstallationID int64
+	appPrivateKey            string
+	app                      *GitHubApp
+}
+
+var globalOpts globalOptions
+
+// tapTokenOr returns the token of the tap forge, which is --tap-token, GHBR_TAP_TOKEN, the token of the tap forge
+// read from its own environment variable if it is not the forge, or the token of the forge in this order.
+// The token of the forge is used only if the tap is on the same host, so that it is not sent to another host
+func (o globalOptions) tapTokenOr(token string) string {
+	if t := o.ownTapToken(); len(t) != 0 {
+		return t
+	}
+
+	if o.sameTapHost() {
+		return token
+	}
+
+	return ""
+}
+
+// ownTapToken returns the token passed only for the tap forge, or an empty string if there is none
+func (o globalOptions) ownTapToken() string {
+	if len(o.tapToken) != 0 {
+		return o.tapToken
+	}
+
+	if t := os.Getenv(EnvTapToken); len(t) != 0 {
+		return t
+	}
+
+	if o.tapForge != o.forge {
+		return os.Getenv(forgeTokenEnv(o.tapForge))
+	}
+
+	return ""
+}
+
+// sameTapHost reports whether the tap is hosted on the same host as the application
+func (o globalOptions) sameTapHost() bool {
+	return len(o.tapForge) == 0 || (o.tapForge == o.forge && forgeHost(o.tapForge, o.tapForgeURL) == forgeHost(o.forge, o.forgeURL))
+}
+
+// validateTapToken validates a token of the tap forge is passed if the tap is on another host than the application,
+// GitHub Apps authenticate on any GitHub host without tokens
+func (o globalOptions) validateTapToken() error {
+	if o.sameTapHost() || len(o.ownTapToken()) != 0 || (o.app != nil && o.tapForge == ForgeGitHub) {
+		return nil
+	}
+
+	return fmt.Errorf("missing --tap-token / %s\n\n"+
+		"The token of your application is not sent to %s hosting the tap.\n"+
+		"Please set a token of it via `--tap-token` option or %s environment variable\n", EnvTapToken, forgeHost(o.tapForge, o.tapForgeURL), EnvTapToken)
+}
+
+// level returns the log level set via the flags
+func (o globalOptions) level() int {
+	switch {
+	case o.debug:
+		return LogDebug
+	case o.verbose:
+		return LogVerbose
+	case o.quiet:
+		return LogQuiet
+	default:
+		return LogNormal
+	}
+}
+
 var RootCmd = &cobra.Command{
 	Use:   "ghbr",
 	Short: "GHBR is a simple CLI tool to create and update your Homebrew formula",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if globalOpts.quiet && (globalOpts.verbose || globalOpts.debug) {
+			return cmdError{error: errors.New("--quiet cannot be used together with --verbose or --debug"), exitCode: ExitCodeParseFlagsError}
+		}
+
+		if err := validateForge("forge", globalOpts.forge, globalOpts.forgeURL); err != nil {
+			return cmdError{error: err, exitCode: ExitCodeParseFlagsError}
+		}
+
+		// The tap is on the same kind of forge as the application if only --tap-forge-url is passed
+		if len(globalOpts.tapForge) == 0 && len(globalOpts.tapForgeURL) != 0 {
+			globalOpts.tapForge = globalOpts.forge
+		}
+
+		if len(globalOpts.tapForge) != 0 {
+			if len(globalOpts.tapPath) != 0 {
+				return cmdError{error: errors.New("--tap-path cannot be used together with --tap-forge or --tap-forge-url"), exitCode: ExitCodeParseFlagsError}
+			}
+
+			if err := validateForge("tap-forge", globalOpts.tapForge, globalOpts.tapForgeURL); err != nil {
+				return cmdError{error: err, exitCode: ExitCodeParseFlagsError}
+			}
+		}
+
+		if err := validateLocalTapFlags(globalOpts.tapPath, globalOpts.push, globalOpts.author); err != nil {
+			return cmdError{error: err, exitCode: ExitCodeParseFlagsError}
+		}
+
+		if err := loadGitHubApp(cmd); err != nil {
+			return cmdError{error: err, exitCode: ExitCodeParseFlagsError}
+		}
+
+		if err := globalOpts.validateTapToken(); err != nil {
+			return cmdError{error: err, exitCode: ExitCodeParseFlagsError}
+		}
+
+		// Forges other than GitHub have their own tokens, which take precedence over the one of GitHub unless -t is passed
+		if t := os.Getenv(forgeTokenEnv(globalOpts.forge)); globalOpts.forge != ForgeGitHub && len(t) != 0 {
+			if f := cmd.Flags().Lookup("token"); f != nil && !f.Changed {
+				return cmd.Flags().Set("token", t)
+			}
+		}
+
+		return nil
+	},
 }
 
 func init() {
+	// Set global flags
+	RootCmd.PersistentFlags().BoolVarP(&globalOpts.quiet, "quiet", "q", false, "Print only warnings, errors and results")
+	RootCmd.PersistentFlags().BoolVar(&globalOpts.verbose, "verbose", false, "Log details of what ghbr does and every API request to stderr")
+	RootCmd.PersistentFlags().BoolVar(&globalOpts.debug, "debug", false, "Log every API request along with its status, rate limit and error response to stderr, the token is redacted")
+	RootCmd.PersistentFlags().DurationVar(&globalOpts.timeout, "timeout", 0, "Give up after the `duration` like 5m, changes made so far are rolled back, 0 means no timeout")
+	RootCmd.PersistentFlags().StringVar(&globalOpts.forge, "forge", ForgeGitHub, "Forge hosting your application and tap, one of `github`, `gitlab` or `gitea`")
+	RootCmd.PersistentFlags().StringVar(&globalOpts.forgeURL, "forge-url", "", "URL of your self-hosted GitHub Enterprise, GitLab or Gitea instance like https://codeberg.org")
+
+	RootCmd.PersistentFlags().StringVar(&globalOpts.releaseURL, "release-url", "", "Find the latest release in the JSON or HTML index at the `URL`, or under the prefix of an S3 bucket like s3://bucket/prefix/, instead of the forge")
+	RootCmd.PersistentFlags().StringVar(&globalOpts.tapForge, "tap-forge", "", "Forge hosting your tap if it differs from the one hosting your application, one of `github`, `gitlab` or `gitea`")
+	RootCmd.PersistentFlags().StringVar(&globalOpts.tapForgeURL, "tap-forge-url", "", "URL of the self-hosted instance hosting your tap like https://github.example.com")
+	RootCmd.PersistentFlags().StringVar(&globalOpts.tapToken, "tap-token", "", "Token of the forge hosting your tap, the token of your application is used by default only if the tap is on the same host")
+	RootCmd.PersistentFlags().StringVar(&globalOpts.tapPath, "tap-path", "", "Update the tap checked out at the `path`, or a bare repository, via git instead of the API of the forge")
+	RootCmd.PersistentFlags().StringVar(&globalOpts.push, "push", "", "Push branches updated in the local tap to the `remote` like origin, nothing is pushed by default")
+	RootCmd.PersistentFlags().StringVar(&globalOpts.author, "author", "", "Commit to the local tap as the `author` like \"Name <email>\", user.name and user.email in .gitconfig are used by default")
+
+	RootCmd.PersistentFlags().Int64Var(&globalOpts.appID, "app-id", 0, "Authenticate to GitHub as the GitHub App with the `ID` instead of a personal access token")
+	RootCmd.PersistentFlags().Int64Var(&globalOpts.appInstallationID, "app-installation-id", 0, "`ID` of the installation of the GitHub App, the one on the owner of each repository is used by default")
+	RootCmd.PersistentFlags().StringVar(&globalOpts.appPrivateKey, "app-private-key", "", "`Path` of the private key of the GitHub App in PEM")
+
 	RootCmd.AddCommand(NewVersionCmd())
 	RootCmd.AddCommand(NewReleaseCmd(GenerateGhbr))
 	RootCmd.AddCommand(NewCreateCmd(GenerateGhbr))
+	RootCmd.AddCommand(NewConfigCmd())
+	RootCmd.AddCommand(NewTapCmd(GenerateGhbr))
+	RootCmd.AddCommand(NewStatusCmd(GenerateGhbr))
+	RootCmd.AddCommand(NewVerifyCmd(GenerateGhbr))
+	RootCmd.AddCommand(NewLintCmd())
+	RootCmd.AddCommand(NewResumeCmd(GenerateGhbr))
+	RootCmd.AddCommand(NewAbortCmd(GenerateGhbr))
+}
+
+// loadGitHubApp loads the GitHub App set via the flags or their environment variables
+func loadGitHubApp(cmd *cobra.Command) error {
+	for flag, env := range map[string]string{"app-id": EnvGitHubAppID, "app-installation-id": EnvGitHubAppInstallationID, "app-private-key": EnvGitHubAppPrivateKey} {
+		f := cmd.Flags().Lookup(flag)
+		if v := os.Getenv(env); f != nil && !f.Changed && len(v) != 0 {
+			if err := cmd.Flags().Set(flag, v); err != nil {
+				return fmt.Errorf("invalid %s: %s", env, err)
+			}
+		}
+	}
+
+	tapForge := globalOpts.tapForge
+	if len(tapForge) == 0 {
+		tapForge = globalOpts.forge
+	}
+
+	if err := validateGitHubApp(globalOpts.appID, globalOpts.appInstallationID, globalOpts.appPrivateKey, globalOpts.forge, tapForge); err != nil || globalOpts.appID == 0 {
+		return err
+	}
+
+	app, err := LoadGitHubApp(globalOpts.appID, globalOpts.appInstallationID, globalOpts.appPrivateKey)
+	if err != nil {
+		return err
+	}
+
+	globalOpts.app = app
+
+	return nil
+}
+
+// commandContext returns a context for a command, which is canceled on SIGINT or SIGTERM, or after the timeout unless it is 0.
+// A second signal is not caught so that it kills ghbr even while rolling back
+func commandContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		cancelParent := cancel
+		cancel = func() {
+			cancelTimeout()
+			cancelParent()
+		}
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-sig:
+			cancel()
+		case <-ctx.Done():
+		}
+
+		signal.Stop(sig)
+	}()
+
+	return ctx, cancel
+}
+
+// canceledError returns a HandledError telling why the ctx is canceled if the err is caused by the cancellation,
+// otherwise the err itself is returned
+func canceledError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return &HandledError{Message: fmt.Sprintf("ghbr timed out after %s: %s", globalOpts.timeout, err)}
+	default:
+		return &HandledError{Message: fmt.Sprintf("ghbr was interrupted: %s", err)}
+	}
 }
 
 func Execute() int {
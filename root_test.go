@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGlobalOptions_TapTokenOr(t *testing.T) {
+	for _, env := range []string{EnvTapToken, EnvGitLabToken, EnvGiteaToken} {
+		defer os.Setenv(env, os.Getenv(env))
+		os.Unsetenv(env)
+	}
+
+	cases := []struct {
+		opts globalOptions
+		env  map[string]string
+		want string
+	}{
+		// The tap is on the forge of the application
+		{opts: globalOptions{forge: ForgeGitHub}, want: "source"},
+		{opts: globalOptions{forge: ForgeGitHub, tapForge: ForgeGitHub, tapForgeURL: "https://github.com/"}, want: "source"},
+		{opts: globalOptions{forge: ForgeGitea, forgeURL: "https://codeberg.org", tapForge: ForgeGitea, tapForgeURL: "https://Codeberg.org/"}, want: "source"},
+
+		// The token of the application is not sent to another host
+		{opts: globalOptions{forge: ForgeGitHub, tapForge: ForgeGitHub, tapForgeURL: "https://github.example.com"}, want: ""},
+		{opts: globalOptions{forge: ForgeGitLab, tapForge: ForgeGitLab, tapForgeURL: "https://gitlab.example.com"}, env: map[string]string{EnvGitLabToken: "gitlab"}, want: ""},
+		{opts: globalOptions{forge: ForgeGitHub, tapForge: ForgeGitea, tapForgeURL: "https://codeberg.org"}, want: ""},
+
+		// Tokens for the tap take precedence
+		{opts: globalOptions{forge: ForgeGitHub, tapForge: ForgeGitLab, tapToken: "flag"}, env: map[string]string{EnvTapToken: "env"}, want: "flag"},
+		{opts: globalOptions{forge: ForgeGitHub, tapForge: ForgeGitHub, tapForgeURL: "https://github.example.com"}, env: map[string]string{EnvTapToken: "env"}, want: "env"},
+		{opts: globalOptions{forge: ForgeGitHub, tapForge: ForgeGitLab}, env: map[string]string{EnvGitLabToken: "gitlab"}, want: "gitlab"},
+	}
+
+	for i, tc := range cases {
+		for k, v := range tc.env {
+			os.Setenv(k, v)
+		}
+
+		if got := tc.opts.tapTokenOr("source"); got != tc.want {
+			t.Errorf("#%d #tapTokenOr returned %q, want %q", i, got, tc.want)
+		}
+
+		for k := range tc.env {
+			os.Unsetenv(k)
+		}
+	}
+}
+
+func TestGlobalOptions_ValidateTapToken(t *testing.T) {
+	for _, env := range []string{EnvTapToken, EnvGitLabToken, EnvGiteaToken} {
+		defer os.Setenv(env, os.Getenv(env))
+		os.Unsetenv(env)
+	}
+
+	cases := []struct {
+		opts    globalOptions
+		wantErr bool
+	}{
+		{opts: globalOptions{forge: ForgeGitHub}, wantErr: false},
+		{opts: globalOptions{forge: ForgeGitLab, tapForge: ForgeGitLab}, wantErr: false},
+		{opts: globalOptions{forge: ForgeGitHub, tapForge: ForgeGitHub, tapForgeURL: "https://github.example.com"}, wantErr: true},
+		{opts: globalOptions{forge: ForgeGitHub, tapForge: ForgeGitLab}, wantErr: true},
+		{opts: globalOptions{forge: ForgeGitHub, tapForge: ForgeGitLab, tapToken: "flag"}, wantErr: false},
+		{opts: globalOptions{forge: ForgeGitLab, tapForge: ForgeGitHub, app: &GitHubApp{ID: 42}}, wantErr: false},
+	}
+
+	for i, tc := range cases {
+		err := tc.opts.validateTapToken()
+		if tc.wantErr != (err != nil) {
+			t.Errorf("#%d #validateTapToken returned %v, want error: %t", i, err, tc.wantErr)
+		}
+
+		if err != nil && !strings.HasPrefix(err.Error(), "missing --tap-token / GHBR_TAP_TOKEN") {
+			t.Errorf("#%d #validateTapToken returned %q", i, err)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// defaultS3Region is the region of AWS used unless AWS_REGION or AWS_DEFAULT_REGION is set
+const defaultS3Region = "us-east-1"
+
+// emptySHA256 is the SHA-256 of an empty payload, which S3 requires in x-amz-content-sha256 of signed GET requests
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Credentials is an access key of AWS or an S3-compatible storage, requests are not signed if AccessKeyID is empty
+type S3Credentials struct {
+	AccessKeyID, SecretAccessKey, SessionToken string
+}
+
+// S3Source finds releases by listing objects under a prefix of a bucket on AWS S3 or an S3-compatible storage like MinIO.
+// Versions are read from the keys of the objects, e.g. testApp/v1.2.0/testApp_darwin_amd64.zip or testApp/testApp_v1.2.0_darwin_amd64.zip.
+// Objects are expected to be readable without credentials since Homebrew downloads them as they are
+type S3Source struct {
+	// endpoint is the URL of the storage like https://s3.us-east-1.amazonaws.com, buckets are addressed by path
+	endpoint       string
+	region         string
+	bucket, prefix string
+	credentials    S3Credentials
+
+	client *http.Client
+
+	// now returns the time requests are signed at
+	now func() time.Time
+}
+
+// s3ListResult is a response of ListObjectsV2
+type s3ListResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// s3Error is an error response of S3
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// NewS3Source creates a new S3Source listing objects under the prefix of the bucket at the endpoint in the region,
+// which retries and logs requests like GitHubClient
+func NewS3Source(endpoint, region, bucket, prefix string, credentials S3Credentials, logger *Logger) *S3Source {
+	return &S3Source{
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		region:      region,
+		bucket:      bucket,
+		prefix:      prefix,
+		credentials: credentials,
+		client:      &http.Client{Transport: newRetryTransport(newLoggingTransport(http.DefaultTransport, logger), logger)},
+		now:         time.Now,
+	}
+}
+
+// GetLatestRelease returns the latest version found in the keys under the prefix along with every object of the version,
+// the owner and the repo are not used since the prefix belongs to an application
+func (s *S3Source) GetLatestRelease(ctx context.Context, owner, repo string) (*github.RepositoryRelease, error) {
+	keys, err := s.listObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]releaseFile, len(keys))
+	for i, k := range keys {
+		files[i] = releaseFile{name: strings.TrimPrefix(k, s.prefix), url: s.objectURL(k)}
+	}
+
+	version := latestVersion(files)
+	if len(version) == 0 {
+		return nil, &HandledError{Message: fmt.Sprintf("No object whose key contains a version is found in s3://%s/%s", s.bucket, s.prefix)}
+	}
+
+	var assets []releaseFile
+	for _, f := range files {
+		if f.version() == version && !f.isDir() {
+			assets = append(assets, f)
+		}
+	}
+
+	return newRelease(version, assets), nil
+}
+
+// listObjects lists keys of the objects under the prefix, following continuation tokens
+func (s *S3Source) listObjects(ctx context.Context) ([]string, error) {
+	var keys []string
+	token := ""
+
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.prefix}}
+		if len(token) != 0 {
+			query.Set("continuation-token", token)
+		}
+
+		var result s3ListResult
+		if err := s.get(ctx, "/"+s.bucket, query, &result); err != nil {
+			return nil, err
+		}
+
+		for _, c := range result.Contents {
+			keys = append(keys, c.Key)
+		}
+
+		if !result.IsTruncated || len(result.NextContinuationToken) == 0 {
+			return keys, nil
+		}
+
+		token = result.NextContinuationToken
+	}
+}
+
+// objectURL returns the URL of the object with the key
+func (s *S3Source) objectURL(key string) string {
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+
+	return s.endpoint + "/" + s.bucket + "/" + strings.Join(segments, "/")
+}
+
+// get sends a GET request to the path and decodes the XML response into out
+func (s *S3Source) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, s.endpoint+path+"?"+canonicalQuery(query), nil)
+	if err != nil {
+		return err
+	}
+
+	if len(s.credentials.AccessKeyID) != 0 {
+		s.sign(req, s.now().UTC())
+	}
+
+	res, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read objects of s3://%s/%s", s.bucket, s.prefix)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		var e s3Error
+		msg := strings.TrimSpace(string(b))
+		if xml.Unmarshal(b, &e) == nil && len(e.Code) != 0 {
+			msg = fmt.Sprintf("%s: %s", e.Code, e.Message)
+		}
+
+		return &APIError{Method: req.Method, URL: req.URL.String(), StatusCode: res.StatusCode, Message: msg}
+	}
+
+	return errors.Wrapf(xml.Unmarshal(b, out), "failed to parse objects of s3://%s/%s", s.bucket, s.prefix)
+}
+
+// sign signs the request with AWS Signature Version 4, see https://docs.aws.amazon.com/general/latest/gr/sigv4_signing.html
+func (s *S3Source) sign(req *http.Request, t time.Time) {
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+	if len(s.credentials.SessionToken) != 0 {
+		req.Header.Set("X-Amz-Security-Token", s.credentials.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		if k := strings.ToLower(k); strings.HasPrefix(k, "x-amz-") {
+			headers[k] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", k, headers[k])
+	}
+
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptySHA256,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s.region)
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hashHex(canonicalRequest)}, "\n")
+
+	key := []byte("AWS4" + s.credentials.SecretAccessKey)
+	for _, v := range []string{date, s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, v)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.credentials.AccessKeyID, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))))
+}
+
+// canonicalQuery encodes the query sorted by keys, escaping everything other than unreserved characters as S3 requires
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		vs := append([]string(nil), query[k]...)
+		sort.Strings(vs)
+
+		for _, v := range vs {
+			pairs = append(pairs, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+func awsEscape(s string) string {
+	return strings.Replace(strings.Replace(url.QueryEscape(s), "+", "%20", -1), "%7E", "~", -1)
+}
+
+func hashHex(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// ReleaseSource is where ghbr finds the latest release of an application, which is the forge unless --release-url is passed
+type ReleaseSource interface {
+	// GetLatestRelease returns the latest release of the repository
+	GetLatestRelease(ctx context.Context, owner, repo string) (*github.RepositoryRelease, error)
+}
+
+// Environment variables configuring S3 release sources, named after those of AWS CLI
+const (
+	EnvAWSEndpointURL     = "AWS_ENDPOINT_URL"
+	EnvAWSRegion          = "AWS_REGION"
+	EnvAWSDefaultRegion   = "AWS_DEFAULT_REGION"
+	EnvAWSAccessKeyID     = "AWS_ACCESS_KEY_ID"
+	EnvAWSSecretAccessKey = "AWS_SECRET_ACCESS_KEY"
+	EnvAWSSessionToken    = "AWS_SESSION_TOKEN"
+)
+
+// releasedVersionRegex matches a version in the name of a released file or directory like testApp_v1.2.0_darwin_amd64.zip,
+// the second group is a pre-release suffix like -rc1
+var releasedVersionRegex = regexp.MustCompile(`(?i)(?:^|[^0-9a-z])(v?[0-9]+(?:\.[0-9]+)+)([-.+_]?(?:alpha|beta|rc|pre|dev)[0-9.]*)?`)
+
+// NewReleaseSource creates a release source reading the index at the releaseURL, which is expected to be validated by validateReleaseURL.
+// s3://bucket/prefix lists objects in the bucket, which is on AWS unless AWS_ENDPOINT_URL points to an S3-compatible storage.
+// Requests are logged through the logger unless it is nil
+func NewReleaseSource(releaseURL string, logger *Logger) ReleaseSource {
+	u, _ := url.Parse(releaseURL)
+	if u.Scheme != "s3" {
+		return NewIndexSource(releaseURL, logger)
+	}
+
+	region := os.Getenv(EnvAWSRegion)
+	if len(region) == 0 {
+		region = os.Getenv(EnvAWSDefaultRegion)
+	}
+
+	if len(region) == 0 {
+		region = defaultS3Region
+	}
+
+	endpoint := os.Getenv(EnvAWSEndpointURL)
+	if len(endpoint) == 0 {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+
+	credentials := S3Credentials{
+		AccessKeyID:     os.Getenv(EnvAWSAccessKeyID),
+		SecretAccessKey: os.Getenv(EnvAWSSecretAccessKey),
+		SessionToken:    os.Getenv(EnvAWSSessionToken),
+	}
+
+	return NewS3Source(endpoint, region, u.Host, strings.TrimPrefix(u.Path, "/"), credentials, logger)
+}
+
+// validateReleaseURL validates the URL passed via --release-url, which is empty to use the forge
+func validateReleaseURL(releaseURL string) error {
+	if len(releaseURL) == 0 {
+		return nil
+	}
+
+	if u, err := url.Parse(releaseURL); err == nil && len(u.Host) != 0 && (u.Scheme == "http" || u.Scheme == "https" || u.Scheme == "s3") {
+		return nil
+	}
+
+	return fmt.Errorf("invalid release URL: %s\n\n"+
+		"Please set the URL of an index like https://example.com/releases/ or a bucket like s3://bucket/prefix/ via `--release-url` option\n", releaseURL)
+}
+
+// releaseFile is a file or a directory found in a release source
+type releaseFile struct {
+	// name is the path of the file relative to the source, which ends with a slash if it is a directory
+	name string
+	url  string
+}
+
+// version returns the version in the name of the file, or an empty string if it does not have one or it is a pre-release
+func (f releaseFile) version() string {
+	ms := releasedVersionRegex.FindStringSubmatch(f.name)
+	if ms == nil || len(ms[2]) != 0 {
+		return ""
+	}
+
+	return ms[1]
+}
+
+func (f releaseFile) isDir() bool {
+	return strings.HasSuffix(f.name, "/")
+}
+
+// latestVersion returns the latest version found in the names of the files, or an empty string if none of them has a version
+func latestVersion(files []releaseFile) string {
+	var latest string
+	for _, f := range files {
+		if v := f.version(); len(v) != 0 && (len(latest) == 0 || compareVersions(v, latest) > 0) {
+			latest = v
+		}
+	}
+
+	return latest
+}
+
+// newRelease converts the files released as the version into a release, assets are named after the base names of the files
+func newRelease(version string, files []releaseFile) *github.RepositoryRelease {
+	release := &github.RepositoryRelease{TagName: github.String(version)}
+	for _, f := range files {
+		name := path.Base(f.name)
+		if n, err := url.PathUnescape(name); err == nil {
+			name = n
+		}
+
+		release.Assets = append(release.Assets, github.ReleaseAsset{Name: github.String(name), BrowserDownloadURL: github.String(f.url)})
+	}
+
+	return release
+}
+
+// hrefRegex matches links in an HTML index like a directory listing of nginx or Apache
+var hrefRegex = regexp.MustCompile(`(?i)href\s*=\s*["']([^"'#?]+)["']`)
+
+// IndexSource finds releases in an index served over HTTP, which is either a JSON listing releases
+// or an HTML page linking to released files or directories like a directory listing
+type IndexSource struct {
+	indexURL string
+	client   *http.Client
+}
+
+// indexRelease is a release listed in a JSON index, its assets are URLs relative to the index
+type indexRelease struct {
+	Version    string   `json:"version"`
+	Prerelease bool     `json:"prerelease"`
+	Assets     []string `json:"assets"`
+}
+
+// NewIndexSource creates a new IndexSource reading the index at the indexURL, which retries and logs requests like GitHubClient
+func NewIndexSource(indexURL string, logger *Logger) *IndexSource {
+	return &IndexSource{
+		indexURL: indexURL,
+		client:   &http.Client{Transport: newRetryTransport(newLoggingTransport(http.DefaultTransport, logger), logger)},
+	}
+}
+
+// GetLatestRelease returns the latest release in the index, the owner and the repo are not used since the index belongs to an application.
+// Versions are read from a JSON index, or from the names of files and directories linked from an HTML index,
+// in which case a directory named after the latest version is read as well to list its files
+func (s *IndexSource) GetLatestRelease(ctx context.Context, owner, repo string) (*github.RepositoryRelease, error) {
+	body, base, isJSON, err := s.get(ctx, s.indexURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if isJSON {
+		return s.latestJSONRelease(body, base)
+	}
+
+	files := htmlLinks(body, base)
+
+	version := latestVersion(files)
+	if len(version) == 0 {
+		return nil, &HandledError{Message: fmt.Sprintf("No released file whose name contains a version is found in %s", s.indexURL)}
+	}
+
+	var assets []releaseFile
+	for _, f := range files {
+		if f.version() != version {
+			continue
+		}
+
+		if !f.isDir() {
+			assets = append(assets, f)
+			continue
+		}
+
+		dir, dirBase, _, err := s.get(ctx, f.url)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range htmlLinks(dir, dirBase) {
+			if !a.isDir() {
+				assets = append(assets, a)
+			}
+		}
+	}
+
+	return newRelease(version, assets), nil
+}
+
+func (s *IndexSource) latestJSONRelease(body []byte, base *url.URL) (*github.RepositoryRelease, error) {
+	var releases []indexRelease
+	if err := json.Unmarshal(body, &releases); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the index %s", s.indexURL)
+	}
+
+	var latest *indexRelease
+	for i, r := range releases {
+		if !r.Prerelease && len(r.Version) != 0 && (latest == nil || compareVersions(r.Version, latest.Version) > 0) {
+			latest = &releases[i]
+		}
+	}
+
+	if latest == nil {
+		return nil, &HandledError{Message: fmt.Sprintf("No release is found in %s", s.indexURL)}
+	}
+
+	files := make([]releaseFile, 0, len(latest.Assets))
+	for _, a := range latest.Assets {
+		u, err := base.Parse(a)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid asset %s of %s in %s", a, latest.Version, s.indexURL)
+		}
+
+		files = append(files, releaseFile{name: u.Path, url: u.String()})
+	}
+
+	return newRelease(latest.Version, files), nil
+}
+
+// get reads the index at the rawURL, it returns the body along with the URL which links in the body are relative to
+// and whether the body is a JSON
+func (s *IndexSource) get(ctx context.Context, rawURL string) ([]byte, *url.URL, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	req.Header.Set("Accept", "application/json, text/html")
+
+	res, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, false, errors.Wrapf(err, "failed to read the index %s", rawURL)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, nil, false, &APIError{Method: http.MethodGet, URL: rawURL, StatusCode: res.StatusCode, Message: errorMessage(b)}
+	}
+
+	// Redirects like the one to add a trailing slash to a directory change the base of relative links
+	base := res.Request.URL
+	isJSON := strings.Contains(res.Header.Get("Content-Type"), "json") || strings.HasSuffix(base.Path, ".json")
+
+	return b, base, isJSON, nil
+}
+
+// htmlLinks returns files and directories linked from the HTML page at the base, links outside of the base like ../ are ignored
+func htmlLinks(body []byte, base *url.URL) []releaseFile {
+	dir := *base
+	dir.Path = dir.Path[:strings.LastIndex(dir.Path, "/")+1]
+	dir.RawPath = ""
+	prefix := dir.String()
+
+	var files []releaseFile
+	for _, ms := range hrefRegex.FindAllSubmatch(body, -1) {
+		u, err := base.Parse(string(ms[1]))
+		if err != nil {
+			continue
+		}
+
+		if name := strings.TrimPrefix(u.String(), prefix); len(name) != 0 && name != u.String() {
+			files = append(files, releaseFile{name: name, url: u.String()})
+		}
+	}
+
+	return files
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+type statusOptions struct {
+	token, org, owner, repo, branch string
+	tap, formulaPath, output        string
+}
+
+var statusOpts statusOptions
+
+func NewStatusCmd(generator GhbrGenerator) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show whether your Homebrew formula points to the latest release",
+		Long: "Show whether your Homebrew formula points to the latest release without changing anything.\n" +
+			fmt.Sprintf("It exits with %d if the formula is outdated, so that it can be used to gate CI.", ExitCodeOutdated),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(globalOpts.timeout)
+			defer cancel()
+
+			return canceledError(ctx, runStatus(ctx, generator))
+		},
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	setStatusFlags(cmd)
+
+	// Validate flags after they are parsed
+	setProjectPreRunE(cmd, validateStatusFlags)
+
+	return cmd
+}
+
+func runStatus(ctx context.Context, generator GhbrGenerator) error {
+	g := generator(statusOpts.token)
+
+	s, err := g.GetStatus(ctx, parseTap(statusOpts.org, statusOpts.tap, statusOpts.formulaPath), statusOpts.owner, statusOpts.repo, statusOpts.branch)
+	if err != nil {
+		return err
+	}
+
+	if err := g.PrintStatus(s, statusOpts.output); err != nil {
+		return err
+	}
+
+	if s.Status == StatusOutdated {
+		return cmdError{error: fmt.Errorf("the formula is outdated, the latest release is %s", s.LatestVersion), exitCode: ExitCodeOutdated}
+	}
+
+	return nil
+}
+
+func setStatusFlags(cmd *cobra.Command) {
+	// Set token flag
+	setTokenFlag(cmd, &statusOpts.token)
+
+	// Set org flag
+	cmd.Flags().StringVarP(&statusOpts.org, "org", "g", "", "GitHub organization hosting a formula on")
+
+	// Set owner flag
+	setOwnerFlag(cmd, &statusOpts.owner)
+
+	// Set repository flag
+	setRepositoryFlag(cmd, &statusOpts.repo)
+
+	// Set tap flag
+	setTapFlag(cmd, &statusOpts.tap)
+
+	// Set formula-path flag
+	setFormulaPathFlag(cmd, &statusOpts.formulaPath)
+
+	// Set branch flag
+	cmd.Flags().StringVarP(&statusOpts.branch, "branch", "b", "master", "GitHub branch")
+
+	// Set output flag
+	cmd.Flags().StringVar(&statusOpts.output, "output", "text", "Output format, one of text or json")
+}
+
+func validateStatusFlags() error {
+	// Token
+	if err := validateToken(statusOpts.token); err != nil {
+		return err
+	}
+
+	// Owner
+	if err := validateOwner(statusOpts.owner); err != nil {
+		return err
+	}
+
+	// Repository
+	if err := validateRepository(statusOpts.repo); err != nil {
+		return err
+	}
+
+	// Tap
+	if err := validateTap(statusOpts.tap); err != nil {
+		return err
+	}
+
+	// Release URL
+	if err := validateReleaseURL(globalOpts.releaseURL); err != nil {
+		return err
+	}
+
+	// Output
+	if statusOpts.output != "text" && statusOpts.output != "json" {
+		return fmt.Errorf("invalid output: %s\n\n"+
+			"Please set one of `text` or `json` via `--output` option\n", statusOpts.output)
+	}
+
+	return nil
+}
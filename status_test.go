@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStatus(t *testing.T) {
+	cases := []struct {
+		latest   string
+		exitCode int
+		want     FormulaStatus
+	}{
+		{
+			latest:   "v0.0.2",
+			exitCode: ExitCodeOutdated,
+			want: FormulaStatus{
+				Path:          "testApp.rb",
+				Version:       "v0.0.1",
+				URL:           "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip",
+				SHA256:        "0001123456789012345678901234567890123456789012345678901234567890",
+				LatestVersion: "v0.0.2",
+				Status:        StatusOutdated,
+				PullRequests:  []string{"https://github.com/shuheiktgw/homebrew-testApp/pull/1"},
+			},
+		},
+		{
+			latest: "v0.0.1",
+			want: FormulaStatus{
+				Path:          "testApp.rb",
+				Version:       "v0.0.1",
+				URL:           "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip",
+				SHA256:        "0001123456789012345678901234567890123456789012345678901234567890",
+				LatestVersion: "v0.0.1",
+				Status:        StatusCurrent,
+				PullRequests:  []string{"https://github.com/shuheiktgw/homebrew-testApp/pull/1"},
+			},
+		},
+	}
+
+	content := base64.StdEncoding.EncodeToString([]byte(`
+version "v0.0.1"
+url "https://github.com/shuheiktgw/testApp/releases/download/v0.0.1/testApp_v0.0.1_darwin_amd64.zip"
+sha256 "0001123456789012345678901234567890123456789012345678901234567890"
+`))
+
+	for i, tc := range cases {
+		generator, _, outStream, mux, tearDown := ghbrMockGenerator()
+
+		cmd := NewStatusCmd(generator)
+		args := strings.Split("ghbr status -t test -o shuheiktgw -r testApp --output json", " ")
+		cmd.SetArgs(args[1:])
+
+		// Mock GetFile request
+		mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/contents/testApp.rb", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+			testMethod(t, r, http.MethodGet)
+			fmt.Fprintf(w, `{"path":"testApp.rb","sha":"formulaV0.0.1","encoding":"base64","content":"%s"}`, content)
+		})
+
+		// Mock GetLatestRelease request
+		mux.HandleFunc(fmt.Sprintf("/repos/%s/testApp/releases/latest", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"tag_name":"%s"}`, tc.latest)
+		})
+
+		// Mock ListPullRequests request
+		mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-testApp/pulls", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+			testMethod(t, r, http.MethodGet)
+			fmt.Fprint(w, `[{"html_url":"https://github.com/shuheiktgw/homebrew-testApp/pull/1","head":{"ref":"bumps_up_to_v0.0.2"}},`+
+				`{"html_url":"https://github.com/shuheiktgw/homebrew-testApp/pull/2","head":{"ref":"feature"}}]`)
+		})
+
+		err := cmd.Execute()
+		if e, ok := err.(cmdError); tc.exitCode != 0 && (!ok || e.exitCode != tc.exitCode) {
+			t.Errorf("#%d status returned %v, want exit code %d", i, err, tc.exitCode)
+		}
+
+		if tc.exitCode == 0 && err != nil {
+			t.Errorf("#%d status returned unexpected error: %s", i, err)
+		}
+
+		var got FormulaStatus
+		if err := json.Unmarshal(outStream.Bytes(), &got); err != nil {
+			t.Fatalf("#%d status outputed invalid json %s: %s", i, outStream, err)
+		}
+
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("#%d status outputed %+v, want %+v", i, got, tc.want)
+		}
+
+		tearDown()
+	}
+}
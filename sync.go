@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// TapManifestFileName is a name of the manifest file placed at the root of a shared tap
+const TapManifestFileName = ".ghbr-tap.yml"
+
+// syncConcurrency is the number of formulae checked at the same time
+const syncConcurrency = 8
+
+// Statuses of a formula synced by SyncTap
+const (
+	SyncUpToDate = "up-to-date"
+	SyncOutdated = "outdated"
+	SyncBumped   = "bumped"
+	SyncFailed   = "failed"
+)
+
+// tapManifest lists formulae managed in a shared tap
+type tapManifest struct {
+	Formulae []tapFormula `yaml:"formulae"`
+}
+
+// tapFormula is a formula in a shared tap along with the repository releasing it
+type tapFormula struct {
+	// Name is a name of the formula
+	Name string `yaml:"name"`
+
+	// Repository is a repository releasing the application, in owner/repo form
+	Repository string `yaml:"repository"`
+
+	// Asset is a glob pattern matching the name of the released asset, the Darwin AMD64 one is used if it is empty
+	Asset string `yaml:"asset"`
+
+	// Path is a path of the formula file in the tap, it is detected from the layout of the tap if it is empty
+	Path string `yaml:"path"`
+}
+
+// parseTapManifest parses the manifest of a shared tap
+func parseTapManifest(b []byte) (*tapManifest, error) {
+	var m tapManifest
+	if err := yaml.UnmarshalStrict(b, &m); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", TapManifestFileName)
+	}
+
+	for _, f := range m.Formulae {
+		if len(f.Name) == 0 {
+			return nil, errors.Errorf("invalid %s: missing name of a formula", TapManifestFileName)
+		}
+
+		if ss := strings.Split(f.Repository, "/"); len(ss) != 2 || len(ss[0]) == 0 || len(ss[1]) == 0 {
+			return nil, errors.Errorf("invalid %s: invalid repository of formula %s: %q, it must be in owner/repo form", TapManifestFileName, f.Name, f.Repository)
+		}
+	}
+
+	return &m, nil
+}
+
+// syncResult is a result of syncing a formula
+type syncResult struct {
+	formula                       tapFormula
+	path, current, latest, status string
+
+	// update is an update of the formula file, set only if the formula is outdated
+	update *fileUpdate
+
+	// pr is a URL of the Pull Request bumping up the formula
+	pr string
+
+	err error
+}
+
+// SyncTap checks the latest releases of the formulae listed in the manifest of the tap concurrently, and bumps up outdated
+// ones through a single Pull Request, or a Pull Request per formula if perFormula is true. It returns a HandledError if any of them fails
+func (g *Ghbr) SyncTap(ctx context.Context, tap Tap, owner, branch string, perFormula, merge bool) error {
+	tapOwner := tap.owner(owner)
+
+	// Get the manifest
+	g.report().Step("read_manifest", "Reading the manifest of the tap")
+	rc, err := g.tapForge().GetFile(ctx, tapOwner, tap.Name, branch, TapManifestFileName)
+	if err != nil {
+		return err
+	}
+
+	content, err := decodeContent(rc)
+	if err != nil {
+		return err
+	}
+
+	m, err := parseTapManifest([]byte(content))
+	if err != nil {
+		return err
+	}
+
+	// Check the formulae
+	g.report().Step("check_formulae", "Checking the latest releases of %d formulae", len(m.Formulae))
+	results := g.checkFormulae(ctx, tapOwner, tap.Name, branch, m.Formulae)
+
+	var outdated []*syncResult
+	for _, r := range results {
+		if r.status == SyncOutdated {
+			outdated = append(outdated, r)
+		}
+	}
+
+	switch {
+	case len(outdated) == 0:
+	case perFormula:
+		for _, r := range outdated {
+			message := fmt.Sprintf("Bumps up %s to %s", r.formula.Name, r.latest)
+			newBranch := fmt.Sprintf("bumps_up_%s_to_%s", r.formula.Name, r.latest)
+
+			pr, err := g.pullRequest(ctx, tapOwner, tap.Name, branch, newBranch, message, message, []fileUpdate{*r.update}, merge)
+			r.bumped(pr.GetHTMLURL(), err)
+		}
+	default:
+		title := fmt.Sprintf("Bumps up %d formulae", len(outdated))
+		if len(outdated) == 1 {
+			title = fmt.Sprintf("Bumps up %s to %s", outdated[0].formula.Name, outdated[0].latest)
+		}
+
+		var updates []fileUpdate
+		var body string
+		for _, r := range outdated {
+			updates = append(updates, *r.update)
+			body += fmt.Sprintf("- %s: %s -> %s\n", r.formula.Name, r.current, r.latest)
+		}
+
+		// Name the branch after the updates so that the same updates are not proposed twice
+		sum := sha256.Sum256([]byte(body))
+		newBranch := fmt.Sprintf("bumps_up_formulae_%x", sum[:4])
+
+		pr, err := g.pullRequest(ctx, tapOwner, tap.Name, branch, newBranch, title, body, updates, merge)
+		for _, r := range outdated {
+			r.bumped(pr.GetHTMLURL(), err)
+		}
+	}
+
+	g.report().Message("\n\n")
+	failed := g.printSyncSummary(results)
+
+	if failed != 0 {
+		return &HandledError{Message: fmt.Sprintf("%d of %d formulae failed to sync", failed, len(results))}
+	}
+
+	return nil
+}
+
+// bumped records a result of the Pull Request bumping up the formula
+func (r *syncResult) bumped(pr string, err error) {
+	if err != nil {
+		r.status, r.err = SyncFailed, err
+		return
+	}
+
+	r.status, r.pr = SyncBumped, pr
+}
+
+// checkFormulae checks the formulae concurrently, the results are in the same order as the formulae
+func (g *Ghbr) checkFormulae(ctx context.Context, owner, repo, branch string, formulae []tapFormula) []*syncResult {
+	results := make([]*syncResult, len(formulae))
+	sem := make(chan struct{}, syncConcurrency)
+
+	var wg sync.WaitGroup
+	for i, f := range formulae {
+		wg.Add(1)
+
+		go func(i int, f tapFormula) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = g.checkFormula(ctx, owner, repo, branch, f)
+		}(i, f)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// checkFormula compares the formula with the latest release of its repository,
+// and prepares an update of the formula file if it is outdated
+func (g *Ghbr) checkFormula(ctx context.Context, owner, repo, branch string, f tapFormula) *syncResult {
+	r := &syncResult{formula: f, path: f.Path}
+
+	fail := func(err error) *syncResult {
+		r.status, r.err = SyncFailed, err
+		return r
+	}
+
+	if len(r.path) == 0 {
+		path, err := g.formulaPath(ctx, owner, repo, branch, f.Name)
+		if err != nil {
+			return fail(err)
+		}
+
+		r.path = path
+	}
+
+	rc, err := g.tapForge().GetFile(ctx, owner, repo, branch, r.path)
+	if err != nil {
+		return fail(err)
+	}
+
+	content, err := decodeContent(rc)
+	if err != nil {
+		return fail(err)
+	}
+
+	ms := versionRegex.FindStringSubmatch(content)
+	if ms == nil {
+		return fail(errors.New("could not find version in a formula file"))
+	}
+
+	r.current = ms[1]
+
+	ownerRepo := strings.Split(f.Repository, "/")
+	release, err := g.Forge.GetLatestRelease(ctx, ownerRepo[0], ownerRepo[1])
+	if err != nil {
+		return fail(err)
+	}
+
+	r.latest = release.GetTagName()
+	// The formula may point to a version ahead of the latest release, which must not be bumped backwards
+	if compareVersions(r.latest, r.current) <= 0 {
+		r.status = SyncUpToDate
+		return r
+	}
+
+	var url string
+	if len(f.Asset) != 0 {
+		url, err = findAssetURL(release, f.Asset)
+	} else {
+		url, err = findMacAssetURL(release)
+	}
+
+	if err != nil {
+		return fail(err)
+	}
+
+	body, err := g.downloadFile(ctx, url)
+	if err != nil {
+		return fail(err)
+	}
+
+	defer body.Close()
+
+	hash, err := calculateSha256(body)
+	if err != nil {
+		return fail(err)
+	}
+
+	c, err := bumpsUpFormula(content, &LatestRelease{version: r.latest, url: url, hash: hash})
+	if err != nil {
+		return fail(err)
+	}
+
+	r.status = SyncOutdated
+	r.update = &fileUpdate{label: r.path, path: r.path, sha: rc.GetSHA(), content: c}
+
+	return r
+}
+
+// printSyncSummary prints the results in a table followed by the counts of each status, and returns the count of failed formulae
+func (g *Ghbr) printSyncSummary(results []*syncResult) int {
+	counts := make(map[string]int)
+
+	tw := tabwriter.NewWriter(g.outStream, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FORMULA\tCURRENT\tLATEST\tSTATUS\tDETAIL")
+
+	for _, r := range results {
+		counts[r.status]++
+
+		detail := r.pr
+		if r.err != nil {
+			detail = strings.SplitN(r.err.Error(), "\n", 2)[0]
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.formula.Name, orDash(r.current), orDash(r.latest), r.status, orDash(detail))
+	}
+
+	tw.Flush()
+
+	fmt.Fprintf(g.outStream, "\nchecked: %d, up-to-date: %d, bumped: %d, failed: %d\n\n", len(results), counts[SyncUpToDate], counts[SyncBumped], counts[SyncFailed])
+
+	return counts[SyncFailed]
+}
+
+// orDash returns "-" if the s is empty
+func orDash(s string) string {
+	if len(s) == 0 {
+		return "-"
+	}
+
+	return s
+}
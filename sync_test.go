@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTapManifest(t *testing.T) {
+	got, err := parseTapManifest([]byte("formulae:\n- name: testApp\n  repository: shuheiktgw/testApp\n  asset: '*.zip'\n  path: Formula/testApp.rb\n"))
+	if err != nil {
+		t.Fatalf("#parseTapManifest returns unexpected error: %s", err)
+	}
+
+	want := &tapManifest{Formulae: []tapFormula{{Name: "testApp", Repository: "shuheiktgw/testApp", Asset: "*.zip", Path: "Formula/testApp.rb"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("#parseTapManifest returned %+v, want %+v", got, want)
+	}
+
+	for _, c := range []string{
+		"unknown: true\n",
+		"formulae:\n- repository: shuheiktgw/testApp\n",
+		"formulae:\n- name: testApp\n  repository: testApp\n",
+	} {
+		if _, err := parseTapManifest([]byte(c)); err == nil {
+			t.Errorf("#parseTapManifest did not return error for %q", c)
+		}
+	}
+}
+
+func TestValidateTapSyncFlags_ReleaseURL(t *testing.T) {
+	defer func(g globalOptions, o tapOptions) { globalOpts, tapOpts = g, o }(globalOpts, tapOpts)
+
+	tapOpts = tapOptions{token: "test", owner: TestOwner, tap: "homebrew-tools"}
+	if err := validateTapSyncFlags(); err != nil {
+		t.Fatalf("#validateTapSyncFlags returns unexpected error: %s", err)
+	}
+
+	// Every formula of the tap would be bumped up to the release found at the URL
+	globalOpts.releaseURL = "https://artifacts.example.com/testApp/"
+	if err := validateTapSyncFlags(); err == nil || !strings.Contains(err.Error(), "--release-url cannot be used") {
+		t.Errorf("#validateTapSyncFlags returned %v, want an error rejecting --release-url", err)
+	}
+}
+
+func TestGhbr_SyncTap(t *testing.T) {
+	client, mux, _, tearDown := setup()
+	defer tearDown()
+
+	outStream := new(bytes.Buffer)
+	ghbr := Ghbr{Forge: client, outStream: outStream}
+
+	manifest := base64.StdEncoding.EncodeToString([]byte(`formulae:
+- name: appA
+  repository: shuheiktgw/appA
+- name: appB
+  repository: shuheiktgw/appB
+- name: appC
+  repository: shuheiktgw/appC
+`))
+
+	// Mock GetFile request for the manifest
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-tools/contents/%s", TestOwner, TapManifestFileName), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"path":".ghbr-tap.yml","sha":"manifest","encoding":"base64","content":"%s"}`, manifest)
+	})
+
+	assetPath := fmt.Sprintf("/%s/appA/releases/download/v0.0.2/appA_v0.0.2_darwin_amd64.zip", TestOwner)
+	assetURL := fmt.Sprintf("%s%s", strings.TrimSuffix(client.Client.BaseURL.String(), "/"), assetPath)
+
+	formulaA := base64.StdEncoding.EncodeToString([]byte(`
+version 'v0.0.1'
+url 'https://github.com/shuheiktgw/appA/releases/download/v0.0.1/appA_v0.0.1_darwin_amd64.zip'
+sha256 '0001123456789012345678901234567890123456789012345678901234567890'
+`))
+
+	expectedContent, _ := json.Marshal([]byte(fmt.Sprintf(`
+version 'v0.0.2'
+url '%s'
+sha256 '%s'
+`, assetURL, "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08")))
+
+	// Mock GetFile and UpdateFile request for the outdated formula
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-tools/contents/appA.rb", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprintf(w, `{"path":"appA.rb","sha":"appAV0.0.1","encoding":"base64","content":"%s"}`, formulaA)
+		case http.MethodPut:
+			testBody(t, r, fmt.Sprintf(`{"message":"Bumps up appA to v0.0.2","content":%s,"sha":"appAV0.0.1","branch":"bumps_up_formulae_27ff7b60"}`+"\n", expectedContent))
+		}
+	})
+
+	// Mock GetFile request for the up-to-date formula, which is ahead of the latest release without the v prefix
+	formulaB := base64.StdEncoding.EncodeToString([]byte("version '1.1.0'\n"))
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-tools/contents/appB.rb", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"path":"appB.rb","sha":"appBV1.0.0","encoding":"base64","content":"%s"}`, formulaB)
+	})
+
+	// Mock GetLatestRelease requests
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/appA/releases/latest", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"tag_name":"v0.0.2","assets":[{"name":"appA_v0.0.2_darwin_amd64.zip", "browser_download_url":"%s"}]}`, assetURL)
+	})
+
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/appB/releases/latest", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"tag_name":"v1.0.0"}`)
+	})
+
+	// Mock downloadFile request
+	mux.HandleFunc(assetPath, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "test")
+	})
+
+	// Mock CreateBranch request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-tools/git/refs/heads/master", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"object":{"sha":"abcdefg"}}`)
+	})
+
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-tools/git/refs", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		testBody(t, r, `{"ref":"refs/heads/bumps_up_formulae_27ff7b60","sha":"abcdefg"}`+"\n")
+		fmt.Fprint(w, `{"object":{"sha":"abcdefg"}}`)
+	})
+
+	// Mock CreatePullRequest request
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/homebrew-tools/pulls", TestOwner), func(w http.ResponseWriter, r *http.Request) {
+		testBody(t, r, `{"title":"Bumps up appA to v0.0.2","head":"bumps_up_formulae_27ff7b60","base":"master","body":"- appA: v0.0.1 -> v0.0.2\n"}`+"\n")
+		fmt.Fprint(w, `{"number":100, "html_url":"https://github.com/shuheiktgw/homebrew-tools/pull/100"}`)
+	})
+
+	err := ghbr.SyncTap(context.Background(), Tap{Name: "homebrew-tools"}, TestOwner, "master", false, false)
+	if e, ok := err.(*HandledError); !ok || e.Message != "1 of 3 formulae failed to sync" {
+		t.Fatalf("#SyncTap returned %v, want the failure of appC", err)
+	}
+
+	got := outStream.String()
+	for _, want := range [][]string{
+		{"appA", "v0.0.1", "v0.0.2", SyncBumped, "https://github.com/shuheiktgw/homebrew-tools/pull/100"},
+		{"appB", "1.1.0", "v1.0.0", SyncUpToDate, "-"},
+	} {
+		var found bool
+		for _, l := range strings.Split(got, "\n") {
+			if reflect.DeepEqual(strings.Fields(l), want) {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Errorf("#SyncTap outputed %s, want a line of %v", got, want)
+		}
+	}
+
+	for _, want := range []string{"appC  ", "\nchecked: 3, up-to-date: 1, bumped: 1, failed: 1\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("#SyncTap outputed %s, want %q", got, want)
+		}
+	}
+}
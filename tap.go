@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+type tapOptions struct {
+	token, org, owner, tap, branch string
+	perFormula, merge              bool
+}
+
+var tapOpts tapOptions
+
+func NewTapCmd(generator GhbrGenerator) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tap",
+		Short: "Manage a shared tap hosting formulae of many repositories",
+	}
+
+	cmd.AddCommand(newTapSyncCmd(generator))
+
+	return cmd
+}
+
+func newTapSyncCmd(generator GhbrGenerator) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Bump up all outdated formulae listed in " + TapManifestFileName + " of a shared tap",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(globalOpts.timeout)
+			defer cancel()
+
+			return canceledError(ctx, runTapSync(ctx, generator))
+		},
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	setTapSyncFlags(cmd)
+
+	// Validate flags after they are parsed
+	setProjectPreRunE(cmd, validateTapSyncFlags)
+
+	return cmd
+}
+
+func runTapSync(ctx context.Context, generator GhbrGenerator) error {
+	g := generator(tapOpts.token)
+
+	return g.SyncTap(ctx, parseTap(tapOpts.org, tapOpts.tap, ""), tapOpts.owner, tapOpts.branch, tapOpts.perFormula, tapOpts.merge)
+}
+
+func setTapSyncFlags(cmd *cobra.Command) {
+	// Set token flag
+	setTokenFlag(cmd, &tapOpts.token)
+
+	// Set org flag
+	cmd.Flags().StringVarP(&tapOpts.org, "org", "g", "", "GitHub organization hosting the tap")
+
+	// Set owner flag
+	setOwnerFlag(cmd, &tapOpts.owner)
+
+	// Set tap flag
+	cmd.Flags().StringVar(&tapOpts.tap, "tap", "", "Shared tap repository in owner/name or name form")
+
+	// Set branch flag
+	cmd.Flags().StringVarP(&tapOpts.branch, "branch", "b", "master", "GitHub branch")
+
+	// Set per-formula flag
+	cmd.Flags().BoolVar(&tapOpts.perFormula, "per-formula", false, "Open a Pull Request per formula instead of a single one bumping up all of them")
+
+	// Set merge flag
+	cmd.Flags().BoolVarP(&tapOpts.merge, "merge", "m", false, "Merge Pull Requests or not")
+}
+
+func validateTapSyncFlags() error {
+	// Token
+	if err := validateToken(tapOpts.token); err != nil {
+		return err
+	}
+
+	// Owner
+	if err := validateOwner(tapOpts.owner); err != nil {
+		return err
+	}
+
+	// Release URL, which is where a single application is released while the formulae of a shared tap are released separately
+	if len(globalOpts.releaseURL) != 0 {
+		return errors.New("--release-url cannot be used with `tap sync`\n\n" +
+			"Please unset it along with `release-url` in " + ConfigFileName + " and GHBR_RELEASE_URL environment variable\n")
+	}
+
+	// Tap
+	if len(tapOpts.tap) == 0 {
+		return errors.New("missing tap\n\n" +
+			"Please set it via `--tap` option, e.g. `--tap shuheiktgw/homebrew-tools`\n")
+	}
+
+	return validateTap(tapOpts.tap)
+}
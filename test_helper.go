@@ -1,13 +1,18 @@
 package main
 
 import (
+	"archive/zip"
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"sort"
 	"testing"
+	"time"
 )
 
 // setup sets up a test HTTP server along with a GitHubClient that is
@@ -25,13 +30,43 @@ func setup() (client *GitHubClient, mux *http.ServeMux, serverURL string, tearDo
 
 	// client is the GitHub client being tested and is
 	// configured to use test server.
-	client = NewGitHubClient("abcdefg")
+	client = NewGitHubClient("abcdefg", nil)
 	u, _ := url.Parse(server.URL + "/")
 	client.Client.BaseURL = u
 
 	return client, mux, server.URL, server.Close
 }
 
+// setupGitLab sets up a test HTTP server standing in for GitLab API along with a GitLabClient
+// configured to talk to that test server like setup
+func setupGitLab() (client *GitLabClient, mux *http.ServeMux, serverURL string, tearDown func()) {
+	mux = http.NewServeMux()
+
+	// Project paths are escaped like owner%2Frepo, route them by the unescaped path so handlers
+	// can be registered like /api/v4/projects/owner/repo/releases
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.RawPath = ""
+		mux.ServeHTTP(w, r)
+	}))
+
+	client = NewGitLabClient(server.URL+"/api/v4", "abcdefg", nil)
+	client.mergeRetryInterval = time.Millisecond
+
+	return client, mux, server.URL, server.Close
+}
+
+// setupGitea sets up a test HTTP server standing in for a Gitea instance along with a GiteaClient
+// configured to talk to that test server like setup
+func setupGitea() (client *GiteaClient, mux *http.ServeMux, serverURL string, tearDown func()) {
+	mux = http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	client = NewGiteaClient(server.URL, "abcdefg", nil)
+	client.mergeRetryInterval = time.Millisecond
+
+	return client, mux, server.URL, server.Close
+}
+
 func testHeader(t *testing.T, r *http.Request, header string, want string) {
 	if got := r.Header.Get(header); got != want {
 		t.Errorf("Header.Get(%q) returned %q, want %q", header, got, want)
@@ -68,11 +103,58 @@ func testBody(t *testing.T, r *http.Request, want string) {
 	}
 }
 
+// testFileContent returns the decoded content of a file sent to the contents API
+func testFileContent(t *testing.T, r *http.Request) string {
+	var opt struct {
+		Content []byte `json:"content"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		t.Errorf("Error decoding request body: %v", err)
+	}
+
+	return string(opt.Content)
+}
+
+// testZip creates a zip archive containing the files, the value of the files indicates the file is executable or not
+func testZip(t *testing.T, files map[string]bool) []byte {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+
+	for _, name := range names {
+		h := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		if files[name] {
+			h.SetMode(0755)
+		} else {
+			h.SetMode(0644)
+		}
+
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			t.Fatalf("Error creating a zip archive: %v", err)
+		}
+
+		fmt.Fprint(w, name)
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Error creating a zip archive: %v", err)
+	}
+
+	return b.Bytes()
+}
+
 func ghbrMockGenerator() (GhbrGenerator, *GitHubClient, *bytes.Buffer, *http.ServeMux, func()) {
 	outStream := new(bytes.Buffer)
 	client, mux, _, teardown := setup()
 
 	return func(token string) *Ghbr {
-		return &Ghbr{GitHub: client, outStream: outStream}
+		return &Ghbr{Forge: client, outStream: outStream}
 	}, client, outStream, mux, teardown
 }
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+)
+
+type verifyOptions struct {
+	token, org, owner, repo, branch string
+	tap, formulaPath                string
+	fix, merge                      bool
+}
+
+var verifyOpts verifyOptions
+
+func NewVerifyCmd(generator GhbrGenerator) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify checksums of every url your Homebrew formula references",
+		Long: "Download every url your Homebrew formula references, including per-platform ones and resources,\n" +
+			"and compare their checksums with sha256 in the formula. With --fix, it opens a Pull Request fixing mismatched ones.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(globalOpts.timeout)
+			defer cancel()
+
+			return canceledError(ctx, runVerify(ctx, generator))
+		},
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	setVerifyFlags(cmd)
+
+	// Validate flags after they are parsed
+	setProjectPreRunE(cmd, validateVerifyFlags)
+
+	return cmd
+}
+
+func runVerify(ctx context.Context, generator GhbrGenerator) error {
+	g := generator(verifyOpts.token)
+
+	return g.Verify(ctx, parseTap(verifyOpts.org, verifyOpts.tap, verifyOpts.formulaPath), verifyOpts.owner, verifyOpts.repo, verifyOpts.branch, verifyOpts.fix, verifyOpts.merge)
+}
+
+func setVerifyFlags(cmd *cobra.Command) {
+	// Set token flag
+	setTokenFlag(cmd, &verifyOpts.token)
+
+	// Set org flag
+	cmd.Flags().StringVarP(&verifyOpts.org, "org", "g", "", "GitHub organization hosting a formula on")
+
+	// Set owner flag
+	setOwnerFlag(cmd, &verifyOpts.owner)
+
+	// Set repository flag
+	setRepositoryFlag(cmd, &verifyOpts.repo)
+
+	// Set tap flag
+	setTapFlag(cmd, &verifyOpts.tap)
+
+	// Set formula-path flag
+	setFormulaPathFlag(cmd, &verifyOpts.formulaPath)
+
+	// Set branch flag
+	cmd.Flags().StringVarP(&verifyOpts.branch, "branch", "b", "master", "GitHub branch")
+
+	// Set fix flag
+	cmd.Flags().BoolVar(&verifyOpts.fix, "fix", false, "Open a Pull Request fixing mismatched checksums")
+
+	// Set merge flag
+	cmd.Flags().BoolVarP(&verifyOpts.merge, "merge", "m", false, "Merge a Pull Request or not, used with --fix")
+}
+
+func validateVerifyFlags() error {
+	// Token
+	if err := validateToken(verifyOpts.token); err != nil {
+		return err
+	}
+
+	// Owner
+	if err := validateOwner(verifyOpts.owner); err != nil {
+		return err
+	}
+
+	// Repository
+	if err := validateRepository(verifyOpts.repo); err != nil {
+		return err
+	}
+
+	// Tap
+	return validateTap(verifyOpts.tap)
+}